@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// APIKeyPrefix starts every API key, so that a leaked key is easy to recognise
+const APIKeyPrefix = "emp_"
+
+// apiKeyDisplayLength is how much of a key is kept in clear to tell keys apart
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey returns a new random API key, the start of it shown to tell keys apart, and the
+// hash of the key, which is all that is stored of it
+func GenerateAPIKey() (string, string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hash an API key is stored and looked up by. The keys are 256 bit random
+// values, so a fast hash is enough to keep a stolen table from revealing them.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
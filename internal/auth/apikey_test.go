@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(key, prefix))
+	assert.Len(t, prefix, len(APIKeyPrefix)+8)
+	assert.Equal(t, HashAPIKey(key), hash)
+	assert.NotContains(t, hash, key)
+
+	other, _, otherHash, _ := GenerateAPIKey()
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, hash, otherHash)
+}
@@ -0,0 +1,51 @@
+// Package auth verifies the credentials requests are made with.
+package auth
+
+import (
+	"assignment/internal/config"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NewVerifier returns the Verifier of the tokens accepted by the authentication configuration.
+// The keys of a JWKS file or URL are loaded straight away, so that a misconfiguration is found
+// at startup.
+func NewVerifier(cfg config.Auth) (*Verifier, error) {
+	verifier := &Verifier{
+		Algorithms: cfg.Algorithms,
+		Secret:     []byte(cfg.HMACSecret),
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		Leeway:     time.Duration(cfg.ClockSkewSeconds) * time.Second,
+	}
+	if len(verifier.Algorithms) == 0 {
+		return nil, errors.New("auth.algorithms is empty")
+	}
+	for _, alg := range verifier.Algorithms {
+		if alg != AlgHS256 && alg != AlgRS256 && alg != AlgES256 {
+			return nil, fmt.Errorf("auth.algorithms holds %q, expected %s, %s or %s", alg, AlgHS256, AlgRS256, AlgES256)
+		}
+	}
+	if cfg.JWKSFile != "" && cfg.JWKSURL != "" {
+		return nil, errors.New("auth.jwks_file and auth.jwks_url cannot both be set")
+	}
+
+	var err error
+	switch {
+	case cfg.JWKSFile != "":
+		verifier.Keys, err = LoadKeySetFile(cfg.JWKSFile)
+	case cfg.JWKSURL != "":
+		verifier.Keys, err = NewRemoteKeySet(cfg.JWKSURL, time.Duration(cfg.JWKSRefreshMinutes)*time.Minute)
+	case cfg.HMACSecret == "":
+		err = errors.New("auth is enabled but none of auth.hmac_secret, auth.jwks_file and auth.jwks_url is set")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A short secret can be brute forced from a single token
+	if cfg.HMACSecret != "" && len(cfg.HMACSecret) < minSecretLength {
+		return nil, fmt.Errorf("auth.hmac_secret must be at least %d bytes long", minSecretLength)
+	}
+	return verifier, nil
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"assignment/internal/utils"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// KeySet holds the keys tokens are verified with, read from a JWKS file or fetched from a JWKS
+// URL. The keys of a URL are fetched again every refresh interval, and when a token names a key
+// that is not known yet.
+type KeySet struct {
+	storage jwkset.Storage
+	keyfunc keyfunc.Keyfunc
+}
+
+const (
+	// minimum time between two fetches of a JWKS URL for tokens naming unknown keys, so that they
+	// cannot make the API hammer the identity provider
+	minJWKSFetchInterval = time.Minute
+	// how long a fetch of a JWKS URL may take
+	jwksFetchTimeout = 10 * time.Second
+	// minimum length of a symmetric key, the same as the one of auth.hmac_secret
+	minSecretLength = 32
+)
+
+// keys of a JWKS used for signatures, a key without a use may be used for anything
+var signatureUses = []jwkset.USE{jwkset.UseSig, ""}
+
+// LoadKeySetFile reads the keys of a local JWKS file
+func LoadKeySetFile(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read JWKS file: %w", err)
+	}
+	var document jwkset.JWKSMarshal
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %w", path, err)
+	}
+	storage, err := document.ToStorage()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %w", path, err)
+	}
+	keySet, err := newKeySet(storage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %w", path, err)
+	}
+	return keySet, nil
+}
+
+// NewRemoteKeySet fetches the keys of a JWKS URL, failing when they cannot be fetched. The keys
+// are fetched again every refresh, never when it is zero.
+func NewRemoteKeySet(url string, refresh time.Duration) (*KeySet, error) {
+	remote, err := jwkset.NewStorageFromHTTP(url, jwkset.HTTPClientStorageOptions{
+		Client:          &http.Client{Timeout: jwksFetchTimeout},
+		HTTPTimeout:     jwksFetchTimeout,
+		RefreshInterval: refresh,
+		RefreshErrorHandler: func(_ context.Context, err error) {
+			utils.Logger.Warn("unable to fetch JWKS, the keys known so far are kept", zap.String("url", url), zap.Error(err))
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch JWKS: %w", err)
+	}
+	// A fetch for an unknown key is only waited for when it is allowed right away, the limiter
+	// would otherwise hold the request for up to minJWKSFetchInterval
+	storage, err := jwkset.NewHTTPClient(jwkset.HTTPClientOptions{
+		HTTPURLs:          map[string]jwkset.Storage{url: remote},
+		RateLimitWaitMax:  jwksFetchTimeout,
+		RefreshUnknownKID: rate.NewLimiter(rate.Every(minJWKSFetchInterval), 1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch JWKS: %w", err)
+	}
+	keySet, err := newKeySet(storage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS at %s: %w", url, err)
+	}
+	return keySet, nil
+}
+
+// newKeySet checks that the keys of storage hold a signing key the API verifies tokens with
+func newKeySet(storage jwkset.Storage) (*KeySet, error) {
+	jwks, err := storage.KeyReadAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, jwk := range jwks {
+		if secret, ok := jwk.Key().([]byte); ok && len(secret) < minSecretLength {
+			return nil, fmt.Errorf("key %q: a symmetric key must be at least %d bytes long", jwk.Marshal().KID, minSecretLength)
+		}
+		if signingKey(jwk, "") != nil {
+			found = true
+		}
+	}
+	if !found {
+		return nil, errors.New("no RS256, ES256 or HS256 signing key")
+	}
+
+	verifying, err := keyfunc.New(keyfunc.Options{Storage: storage, UseWhitelist: signatureUses})
+	if err != nil {
+		return nil, err
+	}
+	return &KeySet{storage: storage, keyfunc: verifying}, nil
+}
+
+// candidates returns the keys a token may be verified with: the key its kid names, or every key
+// of its algorithm when it has none
+func (keySet *KeySet) candidates(token *jwt.Token) []jwt.VerificationKey {
+	if keySet == nil {
+		return nil
+	}
+	alg, _ := token.Header["alg"].(string)
+	if kid, _ := token.Header[jwkset.HeaderKID].(string); kid != "" {
+		key, err := keySet.keyfunc.Keyfunc(token)
+		if err != nil || !usableKey(key, alg) {
+			return nil
+		}
+		return []jwt.VerificationKey{key}
+	}
+
+	jwks, err := keySet.storage.KeyReadAll(context.Background())
+	if err != nil {
+		return nil
+	}
+	var keys []jwt.VerificationKey
+	for _, jwk := range jwks {
+		if key := signingKey(jwk, alg); key != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// signingKey returns the public key of a JWKS key tokens signed with alg are verified with, nil
+// when it is not one. Any of the algorithms the API verifies tokens with matches an empty alg.
+func signingKey(jwk jwkset.JWK, alg string) jwt.VerificationKey {
+	marshal := jwk.Marshal()
+	if marshal.USE != jwkset.UseSig && marshal.USE != "" {
+		return nil
+	}
+	if alg == "" {
+		alg = marshal.ALG.String()
+	} else if marshal.ALG != "" && marshal.ALG.String() != alg {
+		return nil
+	}
+
+	key := jwk.Key()
+	if private, ok := key.(interface{ Public() crypto.PublicKey }); ok {
+		key = private.Public()
+	}
+	if alg == "" {
+		for _, candidate := range []string{AlgHS256, AlgRS256, AlgES256} {
+			if usableKey(key, candidate) {
+				return key
+			}
+		}
+		return nil
+	}
+	if !usableKey(key, alg) {
+		return nil
+	}
+	return key
+}
+
+// usableKey reports whether a key can verify tokens signed with alg. A public key is never used
+// as an HMAC secret, and a symmetric key must be at least minSecretLength bytes long.
+func usableKey(key interface{}, alg string) bool {
+	switch key := key.(type) {
+	case []byte:
+		return alg == AlgHS256 && len(key) >= minSecretLength
+	case *rsa.PublicKey:
+		return alg == AlgRS256
+	case *ecdsa.PublicKey:
+		return alg == AlgES256 && key.Curve.Params().Name == "P-256"
+	}
+	return false
+}
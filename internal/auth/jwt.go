@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signature algorithms a token may be signed with
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// Claims are the claims of a verified token, numbers are kept as json.Number
+type Claims map[string]interface{}
+
+// Subject returns the sub claim, the principal the token was issued to
+func (claims Claims) Subject() string {
+	subject, _ := claims["sub"].(string)
+	return subject
+}
+
+// String returns a string claim, "" when it is missing or not a string
+func (claims Claims) String(name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// Strings returns a claim holding a string or an array of strings, such as aud
+func (claims Claims) Strings(name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, element := range value {
+			if s, ok := element.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Verifier checks the signature and the registered claims of JSON Web Tokens (RFC 7519)
+type Verifier struct {
+	// Algorithms are the signature algorithms accepted, of AlgHS256, AlgRS256 and AlgES256
+	Algorithms []string
+	// Secret is the shared secret of HS256 tokens
+	Secret []byte
+	// Keys are the keys of RS256 and ES256 tokens, and of HS256 tokens naming a JWKS key
+	Keys *KeySet
+	// Issuer is the iss a token must carry, any when empty
+	Issuer string
+	// Audience is a value the aud of a token must hold, any when empty
+	Audience string
+	// Leeway is the clock skew allowed when checking exp and nbf
+	Leeway time.Duration
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+}
+
+// Verify returns the claims of a token, or why it is not valid. A token must be signed with one
+// of the accepted algorithms and carry an exp.
+func (verifier *Verifier) Verify(token string) (Claims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(verifier.Algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(verifier.Leeway),
+		jwt.WithJSONNumber(),
+	}
+	if verifier.Issuer != "" {
+		options = append(options, jwt.WithIssuer(verifier.Issuer))
+	}
+	if verifier.Audience != "" {
+		options = append(options, jwt.WithAudience(verifier.Audience))
+	}
+	if verifier.Now != nil {
+		options = append(options, jwt.WithTimeFunc(verifier.Now))
+	}
+
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.NewParser(options...).ParseWithClaims(token, claims, verifier.keys)
+	if err != nil {
+		return nil, verifier.invalid(parsed, err)
+	}
+	return Claims(claims), nil
+}
+
+// keys returns the keys a token may be verified with, each of them is tried
+func (verifier *Verifier) keys(token *jwt.Token) (interface{}, error) {
+	keys := verifier.Keys.candidates(token)
+	if token.Method.Alg() == AlgHS256 && len(verifier.Secret) > 0 {
+		keys = append(keys, verifier.Secret)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no key verifies the token")
+	}
+	return jwt.VerificationKeySet{Keys: keys}, nil
+}
+
+// invalid tells why a token is not valid from the error it was parsed with
+func (verifier *Verifier) invalid(token *jwt.Token, err error) error {
+	if errors.Is(err, jwt.ErrTokenMalformed) || token == nil {
+		return errors.New("the token is malformed")
+	}
+	if alg, _ := token.Header["alg"].(string); !slices.Contains(verifier.Algorithms, alg) {
+		return fmt.Errorf("the token algorithm %q is not accepted", alg)
+	}
+	switch {
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return errors.New("the token signature is invalid")
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return errors.New("the token has no exp")
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return errors.New("the token is expired")
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return errors.New("the token is not valid yet")
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return errors.New("the token issuer is not accepted")
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return errors.New("the token is not meant for this audience")
+	}
+	return errors.New("the token claims are malformed")
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx carrying the claims of the token a request was made with
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by WithClaims, nil when there are none
+func ClaimsFromContext(ctx context.Context) Claims {
+	claims, _ := ctx.Value(claimsKey{}).(Claims)
+	return claims
+}
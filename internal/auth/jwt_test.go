@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// sign returns a token of the claims signed with key, an HMAC secret, an RSA or an EC private key
+func sign(t *testing.T, alg string, kid string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		assert.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func validClaims(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"sub": "alice",
+		"iss": "https://issuer.example",
+		"aud": []string{"employee-api", "other"},
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	now := time.Now()
+	verifier := &Verifier{Algorithms: []string{AlgHS256}, Secret: testSecret, Issuer: "https://issuer.example", Audience: "employee-api"}
+
+	claims, err := verifier.Verify(sign(t, AlgHS256, "", testSecret, validClaims(now)))
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject())
+
+	_, err = verifier.Verify(sign(t, AlgHS256, "", []byte("another secret, as long as the first"), validClaims(now)))
+	assert.EqualError(t, err, "the token signature is invalid")
+
+	// Each registered claim is checked
+	for claim, tc := range map[string]struct {
+		value interface{}
+		err   string
+	}{
+		"exp": {now.Add(-2 * time.Minute).Unix(), "the token is expired"},
+		"nbf": {now.Add(2 * time.Minute).Unix(), "the token is not valid yet"},
+		"iss": {"https://other.example", "the token issuer is not accepted"},
+		"aud": {"other", "the token is not meant for this audience"},
+	} {
+		claims := validClaims(now)
+		claims[claim] = tc.value
+		_, err := verifier.Verify(sign(t, AlgHS256, "", testSecret, claims))
+		assert.EqualError(t, err, tc.err, claim)
+	}
+
+	claims = validClaims(now)
+	delete(claims, "exp")
+	_, err = verifier.Verify(sign(t, AlgHS256, "", testSecret, claims))
+	assert.EqualError(t, err, "the token has no exp")
+
+	// The leeway allows for clock skew
+	verifier.Leeway = time.Minute
+	claims = validClaims(now)
+	claims["exp"] = now.Add(-30 * time.Second).Unix()
+	_, err = verifier.Verify(sign(t, AlgHS256, "", testSecret, claims))
+	assert.NoError(t, err)
+}
+
+func TestVerifier_RejectsOtherAlgorithms(t *testing.T) {
+	verifier := &Verifier{Algorithms: []string{AlgRS256}, Secret: testSecret}
+	claims := validClaims(time.Now())
+
+	_, err := verifier.Verify(sign(t, AlgHS256, "", testSecret, claims))
+	assert.EqualError(t, err, `the token algorithm "HS256" is not accepted`)
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload, _ := json.Marshal(claims)
+	_, err = verifier.Verify(header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".")
+	assert.EqualError(t, err, `the token algorithm "none" is not accepted`)
+
+	_, err = verifier.Verify("not a token")
+	assert.EqualError(t, err, "the token is malformed")
+}
+
+func TestVerifier_JWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "alg": "RS256", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encodeBigInt(ecKey.X), "y": encodeBigInt(ecKey.Y)},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": encodeBigInt(rsaKey.N), "e": "AQAB"},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	keys, err := LoadKeySetFile(path)
+	assert.NoError(t, err)
+	verifier := &Verifier{Algorithms: []string{AlgRS256, AlgES256, AlgHS256}, Keys: keys}
+	claims := validClaims(time.Now())
+
+	_, err = verifier.Verify(sign(t, AlgRS256, "rsa-1", rsaKey, claims))
+	assert.NoError(t, err)
+	_, err = verifier.Verify(sign(t, AlgES256, "ec-1", ecKey, claims))
+	assert.NoError(t, err)
+	// Without a kid every key of the algorithm is tried
+	_, err = verifier.Verify(sign(t, AlgES256, "", ecKey, claims))
+	assert.NoError(t, err)
+
+	_, err = verifier.Verify(sign(t, AlgRS256, "ec-1", rsaKey, claims))
+	assert.EqualError(t, err, "the token signature is invalid")
+	// The public key of an RSA key cannot be used as an HMAC secret
+	_, err = verifier.Verify(sign(t, AlgHS256, "rsa-1", rsaKey.PublicKey.N.Bytes(), claims))
+	assert.EqualError(t, err, "the token signature is invalid")
+}
+
+func TestVerifier_HS256WithOnlyJWKSKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encodeBigInt(ecKey.X), "y": encodeBigInt(ecKey.Y)},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwks, 0o600))
+	keys, err := LoadKeySetFile(path)
+	assert.NoError(t, err)
+
+	// HS256 is accepted but there is no secret, so no key of the JWKS may verify it
+	verifier := &Verifier{Algorithms: []string{AlgRS256, AlgES256, AlgHS256}, Keys: keys}
+	for _, kid := range []string{"", "rsa-1"} {
+		token := jwt.New(jwt.SigningMethodHS256)
+		token.Header["kid"] = kid
+		assert.Empty(t, keys.candidates(token), kid)
+	}
+
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.NoError(t, err)
+	ecDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.NoError(t, err)
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaDER})
+	// A forger signs with what is public: the JWKS, or a public key in any encoding
+	for name, secret := range map[string][]byte{
+		"jwks":    jwks,
+		"rsa n":   rsaKey.N.Bytes(),
+		"rsa der": rsaDER,
+		"rsa pem": rsaPEM,
+		"ec der":  ecDER,
+	} {
+		for _, kid := range []string{"", "rsa-1", "ec-1"} {
+			_, err := verifier.Verify(sign(t, AlgHS256, kid, secret, validClaims(time.Now())))
+			assert.EqualError(t, err, "the token signature is invalid", "%s signed with kid %q", name, kid)
+		}
+	}
+}
+
+func TestLoadKeySetFile_SymmetricKeys(t *testing.T) {
+	claims := validClaims(time.Now())
+	for name, tc := range map[string]struct {
+		secret []byte
+		err    string
+	}{
+		"long enough": {secret: testSecret},
+		"too short":   {secret: testSecret[:31], err: `key "oct-1": a symmetric key must be at least 32 bytes long`},
+	} {
+		jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "oct", "kid": "oct-1", "alg": "HS256", "k": base64.RawURLEncoding.EncodeToString(tc.secret)},
+		}})
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		assert.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+		keys, err := LoadKeySetFile(path)
+		if tc.err != "" {
+			assert.ErrorContains(t, err, tc.err, name)
+			continue
+		}
+		assert.NoError(t, err, name)
+		verifier := &Verifier{Algorithms: []string{AlgHS256}, Keys: keys}
+		_, err = verifier.Verify(sign(t, AlgHS256, "oct-1", tc.secret, claims))
+		assert.NoError(t, err, name)
+	}
+}
@@ -1,9 +1,7 @@
 package config
 
 import (
-	"log"
-
-	"github.com/pelletier/go-toml"
+	"os"
 )
 
 var (
@@ -12,17 +10,103 @@ var (
 
 // Global Configuration
 type GlobalConfig struct {
-	Database Database `toml:"database"`
-	Server   Server   `toml:"server"`
+	Database     Database     `toml:"database"`
+	Retention    Retention    `toml:"retention"`
+	Compensation Compensation `toml:"compensation"`
+	Idempotency  Idempotency  `toml:"idempotency"`
+	Auth         Auth         `toml:"auth"`
+	RBAC         RBAC         `toml:"rbac"`
+	Tenancy      Tenancy      `toml:"tenancy"`
+	Metrics      Metrics      `toml:"metrics"`
+	Tracing      Tracing      `toml:"tracing"`
+	Health       Health       `toml:"health"`
+	Logging      Logging      `toml:"logging"`
+	Server       Server       `toml:"server"`
 }
 
 // DB configuration
 type Database struct {
-	Host     string `toml:"host"`
-	Port     int    `toml:"port"`
-	DBname   string `toml:"dbname"`
-	User     string `toml:"user"`
-	Password string `toml:"password"`
+	Host        string `toml:"host"`
+	Port        int    `toml:"port"`
+	DBname      string `toml:"dbname"`
+	User        string `toml:"user"`
+	Password    string `toml:"password"`
+	InMemory    bool   `toml:"in_memory"`
+	AutoMigrate bool   `toml:"auto_migrate"`
+}
+
+// retention of soft-deleted records
+type Retention struct {
+	DeletedEmployeeDays  int `toml:"deleted_employee_days"`
+	PurgeIntervalMinutes int `toml:"purge_interval_minutes"`
+}
+
+// scheduled salary changes
+type Compensation struct {
+	ApplyIntervalMinutes int `toml:"apply_interval_minutes"`
+}
+
+// responses kept for the requests made with an Idempotency-Key
+type Idempotency struct {
+	TTLMinutes           int `toml:"ttl_minutes"`
+	PurgeIntervalMinutes int `toml:"purge_interval_minutes"`
+}
+
+// authentication of requests with JWT bearer tokens
+type Auth struct {
+	Enabled            bool     `toml:"enabled"`
+	Issuer             string   `toml:"issuer"`
+	Audience           string   `toml:"audience"`
+	Algorithms         []string `toml:"algorithms"`
+	HMACSecret         string   `toml:"hmac_secret"`
+	JWKSFile           string   `toml:"jwks_file"`
+	JWKSURL            string   `toml:"jwks_url"`
+	JWKSRefreshMinutes int      `toml:"jwks_refresh_minutes"`
+	ClockSkewSeconds   int      `toml:"clock_skew_seconds"`
+}
+
+// permissions granted to the roles of authenticated callers
+type RBAC struct {
+	RolesClaim      string              `toml:"roles_claim"`
+	EmployeeIDClaim string              `toml:"employee_id_claim"`
+	Roles           map[string][]string `toml:"roles"`
+}
+
+// isolation of the records of the organisations sharing the deployment
+type Tenancy struct {
+	Claim            string `toml:"claim"`
+	Header           bool   `toml:"header"`
+	DefaultTenant    string `toml:"default_tenant"`
+	RowLevelSecurity bool   `toml:"row_level_security"`
+}
+
+// Prometheus metrics served on /metrics
+type Metrics struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// OpenTelemetry spans of the requests, the service methods and the SQL statements
+type Tracing struct {
+	Enabled      bool    `toml:"enabled"`
+	ServiceName  string  `toml:"service_name"`
+	Exporter     string  `toml:"exporter"`
+	OTLPEndpoint string  `toml:"otlp_endpoint"`
+	File         string  `toml:"file"`
+	SampleRatio  float64 `toml:"sample_ratio"`
+}
+
+// readiness of the instance to serve requests
+type Health struct {
+	DBTimeoutMillis      int `toml:"db_timeout_millis"`
+	ShutdownDelaySeconds int `toml:"shutdown_delay_seconds"`
+}
+
+// logs of the application
+type Logging struct {
+	Level        string   `toml:"level"`
+	Encoding     string   `toml:"encoding"`
+	AccessLog    bool     `toml:"access_log"`
+	RedactFields []string `toml:"redact_fields"`
 }
 
 // server configuration
@@ -42,21 +126,15 @@ func GetConfig() GlobalConfig {
 	return globalConfig
 }
 
-// Loading the values from default.toml and assigning them as part of GlobalConfig struct
-func InitGlobalConfig() error {
-	config, err := toml.LoadFile("./config/defaults.toml")
-	if err != nil {
-		log.Printf("Error while loading defaults.toml file : %v ", err)
-		return err
-	}
-
-	var appConfig GlobalConfig
-	err = config.Unmarshal(&appConfig)
+// InitGlobalConfig loads the configuration from defaults, the content of config/defaults.toml,
+// overridden by the configuration files, the environment and the flags in args, see Load. It
+// returns the arguments left after the flags.
+func InitGlobalConfig(defaults []byte, args []string) ([]string, error) {
+	appConfig, rest, err := Load(defaults, args, os.Environ())
 	if err != nil {
-		log.Printf("Error while unmarshalling config : %v", err)
-		return err
+		return nil, err
 	}
 
 	SetConfig(appConfig)
-	return nil
+	return rest, nil
 }
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+const (
+	// EnvPrefix prefixes the environment variables overriding a setting, e.g. EMPDB_DATABASE_PASSWORD
+	// for database.password
+	EnvPrefix = "EMPDB_"
+	// EnvConfig lists the configuration files read when no --config flag is given
+	EnvConfig = EnvPrefix + "CONFIG"
+	// fileSuffix names the environment variable holding the path of a file which holds the
+	// value, e.g. EMPDB_DATABASE_PASSWORD_FILE=/run/secrets/db_password
+	fileSuffix = "_FILE"
+)
+
+// legacyConfigFile is read when no configuration file is named, as it was the only one before
+const legacyConfigFile = "./config/defaults.toml"
+
+// InvalidConfigError lists every invalid setting of the configuration
+type InvalidConfigError struct {
+	Problems []string
+}
+
+func (e *InvalidConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration, %d problem(s): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// setting is a key of the configuration, e.g. database.password, and where its value is held
+type setting struct {
+	key   string
+	index []int
+}
+
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+// settings returns the keys of the configuration, from the toml tags of GlobalConfig
+func settings() []setting {
+	var all []setting
+	global := reflect.TypeOf(GlobalConfig{})
+	for i := 0; i < global.NumField(); i++ {
+		section := global.Field(i)
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			all = append(all, setting{key: section.Tag.Get("toml") + "." + field.Tag.Get("toml"), index: []int{i, j}})
+		}
+	}
+	return all
+}
+
+// override is a value given to a setting by a flag
+type override struct {
+	setting setting
+	value   string
+}
+
+// settingFlag records the values of a --<section>.<key> flag, applied once the flags are parsed
+type settingFlag struct {
+	setting   setting
+	overrides *[]override
+	isBool    bool
+}
+
+func (f *settingFlag) String() string { return "" }
+
+func (f *settingFlag) Set(value string) error {
+	*f.overrides = append(*f.overrides, override{setting: f.setting, value: value})
+	return nil
+}
+
+func (f *settingFlag) IsBoolFlag() bool { return f.isBool }
+
+// fileList collects the paths of the repeated --config flag
+type fileList []string
+
+func (l *fileList) String() string { return strings.Join(*l, ",") }
+
+func (l *fileList) Set(path string) error {
+	*l = append(*l, path)
+	return nil
+}
+
+// Load builds the configuration from the layers below, each overriding the settings of the
+// previous ones:
+//
+//  1. defaults, the content of config/defaults.toml
+//  2. the files of the --config flags in order, or of EMPDB_CONFIG, a list of paths separated as
+//     in PATH, or ./config/defaults.toml when it exists
+//  3. the EMPDB_<SECTION>_<KEY> environment variables, or EMPDB_<SECTION>_<KEY>_FILE naming a
+//     file which holds the value, for the secrets mounted as files
+//  4. the --<section>.<key> flags
+//
+// Lists are written comma separated in the environment and the flags, and rbac.roles as a TOML
+// inline table. Load returns the arguments left after the flags, and an *InvalidConfigError
+// listing every invalid setting.
+func Load(defaults []byte, args []string, environ []string) (GlobalConfig, []string, error) {
+	all := settings()
+
+	var files fileList
+	var overrides []override
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	flags.Var(&files, "config", "configuration file overriding the defaults, may be repeated")
+	for _, s := range all {
+		kind := reflect.TypeOf(GlobalConfig{}).FieldByIndex(s.index).Type.Kind()
+		flags.Var(&settingFlag{setting: s, overrides: &overrides, isBool: kind == reflect.Bool}, s.key, "overrides "+s.key+", also set with "+s.env())
+	}
+	if err := flags.Parse(args); err != nil {
+		return GlobalConfig{}, nil, err
+	}
+
+	tree, err := toml.LoadBytes(defaults)
+	if err != nil {
+		return GlobalConfig{}, nil, fmt.Errorf("invalid defaults: %w", err)
+	}
+
+	env := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		if name, value, ok := strings.Cut(entry, "="); ok && strings.HasPrefix(name, EnvPrefix) {
+			env[name] = value
+		}
+	}
+	if len(files) == 0 {
+		if paths, ok := env[EnvConfig]; ok {
+			files = filepath.SplitList(paths)
+		} else if _, err := os.Stat(legacyConfigFile); err == nil {
+			files = fileList{legacyConfigFile}
+		}
+	}
+
+	var problems []string
+	for _, path := range files {
+		file, err := toml.LoadFile(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		for _, key := range unknownKeys(file, all) {
+			problems = append(problems, fmt.Sprintf("%s: unknown key %s", path, key))
+		}
+		merge(tree, file)
+	}
+
+	var cfg GlobalConfig
+	if err := tree.Unmarshal(&cfg); err != nil {
+		return GlobalConfig{}, nil, &InvalidConfigError{Problems: append(problems, err.Error())}
+	}
+
+	problems = append(problems, applyEnv(&cfg, all, env)...)
+	for _, o := range overrides {
+		if err := setValue(reflect.ValueOf(&cfg).Elem().FieldByIndex(o.setting.index), o.value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (--%s): %v", o.setting.key, o.setting.key, err))
+		}
+	}
+	problems = append(problems, Validate(cfg)...)
+
+	if len(problems) > 0 {
+		return GlobalConfig{}, nil, &InvalidConfigError{Problems: problems}
+	}
+	return cfg, flags.Args(), nil
+}
+
+// applyEnv sets the settings named by the EMPDB_ environment variables, returning the problems
+// with their values and the variables naming no setting
+func applyEnv(cfg *GlobalConfig, all []setting, env map[string]string) []string {
+	var problems []string
+	known := map[string]bool{EnvConfig: true}
+	for _, s := range all {
+		name := s.env()
+		known[name], known[name+fileSuffix] = true, true
+
+		value, set := env[name]
+		if path, ok := env[name+fileSuffix]; ok {
+			if set {
+				problems = append(problems, fmt.Sprintf("%s: both %s and %s are set", s.key, name, name+fileSuffix))
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s (%s): %v", s.key, name+fileSuffix, err))
+				continue
+			}
+			// Files written by hand or by echo usually end with a newline, which is not part of
+			// the secret
+			value, set = strings.TrimRight(string(data), "\r\n"), true
+			name += fileSuffix
+		}
+		if !set {
+			continue
+		}
+		if err := setValue(reflect.ValueOf(cfg).Elem().FieldByIndex(s.index), value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s): %v", s.key, name, err))
+		}
+	}
+
+	var unknown []string
+	for name := range env {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("%s: unknown setting", name))
+	}
+	return problems
+}
+
+// setValue parses value into field, by the type of the field
+func setValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		field.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		// An inline table, e.g. {admin = ["*"], viewer = ["employees:read"]}
+		holder := reflect.New(reflect.StructOf([]reflect.StructField{{Name: "Value", Type: field.Type(), Tag: `toml:"value"`}}))
+		tree, err := toml.Load("value = " + value)
+		if err == nil {
+			err = tree.Unmarshal(holder.Interface())
+		}
+		if err != nil {
+			return fmt.Errorf("%q is not a TOML inline table", value)
+		}
+		field.Set(holder.Elem().Field(0))
+	default:
+		return errors.New("cannot be overridden")
+	}
+	return nil
+}
+
+// unknownKeys returns the keys of a configuration file which are no setting. The keys within a
+// map, such as the roles of rbac.roles, are free.
+func unknownKeys(file *toml.Tree, all []setting) []string {
+	known := make(map[string]bool, len(all))
+	sections := make(map[string]bool)
+	for _, s := range all {
+		known[s.key] = true
+		sections[strings.SplitN(s.key, ".", 2)[0]] = true
+	}
+
+	var unknown []string
+	for _, section := range file.Keys() {
+		table, ok := file.GetPath([]string{section}).(*toml.Tree)
+		if !ok || !sections[section] {
+			unknown = append(unknown, section)
+			continue
+		}
+		for _, key := range table.Keys() {
+			if !known[section+"."+key] {
+				unknown = append(unknown, section+"."+key)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// merge sets the values of overlay on base, table by table, so that a file only overrides the
+// keys it sets
+func merge(base, overlay *toml.Tree) {
+	for _, key := range overlay.Keys() {
+		value := overlay.GetPath([]string{key})
+		if table, ok := value.(*toml.Tree); ok {
+			if baseTable, ok := base.GetPath([]string{key}).(*toml.Tree); ok {
+				merge(baseTable, table)
+				continue
+			}
+		}
+		base.SetPath([]string{key}, value)
+	}
+}
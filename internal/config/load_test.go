@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readDefaults(t *testing.T) []byte {
+	defaults, err := os.ReadFile("../../config/defaults.toml")
+	assert.NoError(t, err)
+	return defaults
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	base := writeFile(t, "base.toml", `
+[database]
+host = "db.internal"
+port = 5433
+[auth]
+algorithms = ["HS256"]
+[rbac.roles]
+auditor = ["audit:read"]
+`)
+	prod := writeFile(t, "prod.toml", `
+[database]
+port = 6432
+[server]
+address = "0.0.0.0:9090"
+`)
+	secret := writeFile(t, "db_password", "s3cret\n")
+	environ := []string{
+		"EMPDB_DATABASE_PORT=7432",
+		"EMPDB_DATABASE_PASSWORD_FILE=" + secret,
+		"EMPDB_AUTH_HMAC_SECRET=0123456789abcdef0123456789abcdef",
+		"EMPDB_LOGGING_REDACT_FIELDS=name, salary",
+		"EMPDB_TRACING_SAMPLE_RATIO=0.25",
+		"HOME=/root",
+	}
+
+	cfg, args, err := Load(readDefaults(t), []string{"--config", base, "--config=" + prod, "--server.address", ":8081", "--metrics.enabled=false", "migrate", "up"}, environ)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "up"}, args)
+
+	// Defaults are kept unless overridden
+	assert.Equal(t, "postgres", cfg.Database.DBname)
+	assert.Equal(t, "employee-api", cfg.Auth.Audience)
+	// Files override the defaults and each other in order, the environment overrides the files
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 7432, cfg.Database.Port)
+	assert.Equal(t, []string{"HS256"}, cfg.Auth.Algorithms)
+	assert.Equal(t, []string{"audit:read"}, cfg.RBAC.Roles["auditor"])
+	assert.Contains(t, cfg.RBAC.Roles, "admin")
+	// Secrets are read from files without their trailing newline
+	assert.Equal(t, "s3cret", cfg.Database.Password)
+	assert.Equal(t, []string{"name", "salary"}, cfg.Logging.RedactFields)
+	assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+	// Flags override everything
+	assert.Equal(t, ":8081", cfg.Server.Address)
+	assert.False(t, cfg.Metrics.Enabled)
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	// The shipped defaults start the API, in memory, without any other setting
+	cfg, _, err := Load(readDefaults(t), []string{"--database.in_memory"}, nil)
+	assert.NoError(t, err)
+	assert.False(t, cfg.Auth.Enabled)
+}
+
+func TestLoad_ConfigFromEnvironment(t *testing.T) {
+	path := writeFile(t, "env.toml", "[database]\nin_memory = true\n[auth]\nenabled = false\n")
+
+	cfg, _, err := Load(readDefaults(t), nil, []string{EnvConfig + "=" + path, `EMPDB_RBAC_ROLES={viewer = ["employees:read"]}`})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Database.InMemory)
+	assert.Equal(t, map[string][]string{"viewer": {"employees:read"}}, cfg.RBAC.Roles)
+}
+
+func TestLoad_ListsEveryProblem(t *testing.T) {
+	path := writeFile(t, "bad.toml", "[database]\nport = 0\nhots = \"db\"\n[cache]\nsize = 1\n")
+	secret := writeFile(t, "secret", "0123456789abcdef0123456789abcdef")
+
+	_, _, err := Load(readDefaults(t), []string{"--config", path, "--auth.enabled", "--logging.level=loud"}, []string{
+		"EMPDB_SERVER_READ_TIME_OUT=ten",
+		"EMPDB_AUTH_HMAC_SECRET=short",
+		"EMPDB_AUTH_HMAC_SECRET_FILE=" + secret,
+		"EMPDB_DATABSE_HOST=db",
+	})
+	invalid, ok := err.(*InvalidConfigError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		path + ": unknown key cache",
+		path + ": unknown key database.hots",
+		"auth.hmac_secret: both EMPDB_AUTH_HMAC_SECRET and EMPDB_AUTH_HMAC_SECRET_FILE are set",
+		`server.read_time_out (EMPDB_SERVER_READ_TIME_OUT): "ten" is not an integer`,
+		"EMPDB_DATABSE_HOST: unknown setting",
+		"database.port: must be between 1 and 65535, got 0",
+		"auth: one of auth.hmac_secret, auth.jwks_file and auth.jwks_url is required when auth is enabled",
+		`logging.level: must be one of debug, info, warn, error, got "loud"`,
+	}, invalid.Problems)
+	assert.NotContains(t, err.Error(), "0123456789abcdef")
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate returns a problem for every invalid setting of cfg, each naming its key
+func Validate(cfg GlobalConfig) []string {
+	var problems []string
+	invalid := func(key, format string, args ...interface{}) {
+		problems = append(problems, key+": "+fmt.Sprintf(format, args...))
+	}
+	notNegative := func(key string, value int) {
+		if value < 0 {
+			invalid(key, "must not be negative, got %d", value)
+		}
+	}
+	oneOf := func(key, value string, accepted ...string) {
+		for _, a := range accepted {
+			if value == a {
+				return
+			}
+		}
+		invalid(key, "must be one of %s, got %q", strings.Join(accepted, ", "), value)
+	}
+
+	if !cfg.Database.InMemory {
+		if cfg.Database.Host == "" {
+			invalid("database.host", "is required unless database.in_memory is set")
+		}
+		if cfg.Database.DBname == "" {
+			invalid("database.dbname", "is required unless database.in_memory is set")
+		}
+		if cfg.Database.Port < 1 || cfg.Database.Port > 65535 {
+			invalid("database.port", "must be between 1 and 65535, got %d", cfg.Database.Port)
+		}
+	}
+
+	notNegative("retention.deleted_employee_days", cfg.Retention.DeletedEmployeeDays)
+	notNegative("retention.purge_interval_minutes", cfg.Retention.PurgeIntervalMinutes)
+	notNegative("compensation.apply_interval_minutes", cfg.Compensation.ApplyIntervalMinutes)
+	notNegative("idempotency.ttl_minutes", cfg.Idempotency.TTLMinutes)
+	notNegative("idempotency.purge_interval_minutes", cfg.Idempotency.PurgeIntervalMinutes)
+
+	if cfg.Auth.Enabled {
+		if len(cfg.Auth.Algorithms) == 0 {
+			invalid("auth.algorithms", "must not be empty when auth is enabled")
+		}
+		for _, alg := range cfg.Auth.Algorithms {
+			oneOf("auth.algorithms", alg, "HS256", "RS256", "ES256")
+		}
+		if cfg.Auth.JWKSFile != "" && cfg.Auth.JWKSURL != "" {
+			invalid("auth.jwks_url", "cannot be set along with auth.jwks_file")
+		}
+		if cfg.Auth.HMACSecret == "" && cfg.Auth.JWKSFile == "" && cfg.Auth.JWKSURL == "" {
+			invalid("auth", "one of auth.hmac_secret, auth.jwks_file and auth.jwks_url is required when auth is enabled")
+		}
+		// The secret itself is never part of a message
+		if cfg.Auth.HMACSecret != "" && len(cfg.Auth.HMACSecret) < 32 {
+			invalid("auth.hmac_secret", "must be at least 32 bytes long, got %d", len(cfg.Auth.HMACSecret))
+		}
+		notNegative("auth.jwks_refresh_minutes", cfg.Auth.JWKSRefreshMinutes)
+		notNegative("auth.clock_skew_seconds", cfg.Auth.ClockSkewSeconds)
+	}
+
+	if cfg.Tracing.Enabled {
+		switch cfg.Tracing.Exporter {
+		case "", "otlp":
+			if cfg.Tracing.OTLPEndpoint == "" {
+				invalid("tracing.otlp_endpoint", "is required by the otlp exporter")
+			}
+		case "file":
+			if cfg.Tracing.File == "" {
+				invalid("tracing.file", "is required by the file exporter")
+			}
+		default:
+			oneOf("tracing.exporter", cfg.Tracing.Exporter, "otlp", "stdout", "file")
+		}
+		if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+			invalid("tracing.sample_ratio", "must be between 0 and 1, got %v", cfg.Tracing.SampleRatio)
+		}
+	}
+
+	notNegative("health.db_timeout_millis", cfg.Health.DBTimeoutMillis)
+	notNegative("health.shutdown_delay_seconds", cfg.Health.ShutdownDelaySeconds)
+
+	if cfg.Logging.Level != "" {
+		oneOf("logging.level", cfg.Logging.Level, "debug", "info", "warn", "error")
+	}
+	if cfg.Logging.Encoding != "" {
+		oneOf("logging.encoding", cfg.Logging.Encoding, "json", "console")
+	}
+
+	if cfg.Server.Address == "" {
+		invalid("server.address", "is required")
+	}
+	notNegative("server.read_time_out", cfg.Server.ReadTimeOut)
+	notNegative("server.write_time_out", cfg.Server.WriteTimeOut)
+	return problems
+}
@@ -4,16 +4,90 @@ const (
 	ForwardSlash = "/"
 	EmployeeAPI  = "employeeapi"
 	Employee     = "employees"
+	Department   = "departments"
+	Reports      = "reports"
+	Chain        = "chain"
+	OrgChart     = "orgchart"
+	Restore      = "restore"
+	History      = "history"
+	Audit        = "audit"
+	Compensation = "compensation"
+	APIKeys      = "api-keys"
+	Rotate       = "rotate"
+	Metrics      = "metrics"
+	Healthz      = "healthz"
+	Readyz       = "readyz"
+	Status       = "status"
+
+	// custom methods, routed as POST /v1/employees:<method>
+	PurgeAction       = ":purge"
+	ImportAction      = ":import"
+	ExportAction      = ":export"
+	BatchUpdateAction = ":batchUpdate"
+	BatchDeleteAction = ":batchDelete"
 
 	Version = "v1"
 
+	// currency of salaries recorded without one
+	DefaultCurrency = "USD"
+
+	// limits of a bulk import
+	MaxImportRows  = 1000
+	MaxImportBytes = 10 << 20
+
+	// employees a batch update or delete applies to at most
+	MaxBatchSize = 1000
+
+	// rows fetched at a time from the cursor of an export
+	ExportBatchSize = 1000
+
+	// tenant of the records created before tenancy, and of requests naming none by default
+	DefaultTenant = "default"
+	// longest tenant ID accepted
+	MaxTenantIDLength = 64
+
+	// longest Idempotency-Key accepted
+	MaxIdempotencyKeyLength = 255
+
+	// paging of list endpoints
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+
 	TransactionID = "transaction-id"
-	InvalidBody   = "invalid value for body"
-	Group         = "my-group"
+	// header naming who makes a request when requests are not authenticated
+	Actor        = "X-Actor"
+	ActorKey     = "actor"
+	SubjectKey   = "subject"
+	ClaimsKey    = "claims"
+	PrincipalKey = "principal"
+	TenantKey    = "tenant"
+	// request-scoped logger, holding the fields of the request
+	LoggerKey = "logger"
+	// actor of the requests made with an API key, followed by the ID of the key
+	APIKeyActorPrefix = "api-key:"
+	UnknownActor      = "anonymous"
+	SystemActor       = "system"
+	InvalidBody       = "invalid value for body"
+	Group             = "my-group"
 
 	//http
-	Accept          = "Accept"
-	ContentType     = "Content-Type"
-	Authorization   = "Authorization"
-	ApplicationJSON = "application/json"
+	Accept             = "Accept"
+	ContentType        = "Content-Type"
+	Authorization      = "Authorization"
+	WWWAuthenticate    = "WWW-Authenticate"
+	Bearer             = "Bearer"
+	ApplicationJSON    = "application/json"
+	TextCSV            = "text/csv"
+	ApplicationNDJSON  = "application/x-ndjson"
+	MergePatchJSON     = "application/merge-patch+json"
+	JSONPatchJSON      = "application/json-patch+json"
+	ApplicationXLSX    = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentDisposition = "Content-Disposition"
+	ETag               = "ETag"
+	IfMatch            = "If-Match"
+	IfNoneMatch        = "If-None-Match"
+	IdempotencyKey     = "Idempotency-Key"
+	APIKey             = "X-API-Key"
+	TenantID           = "X-Tenant-ID"
+	IdempotentReplayed = "Idempotent-Replayed"
 )
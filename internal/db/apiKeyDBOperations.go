@@ -0,0 +1,215 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// apiKeyColumns is the column list scanned by scanAPIKey
+const apiKeyColumns = `id, name, owner, prefix, scopes, expires_at, last_used_at, created_at, revoked_at`
+
+// lastUsedResolution is how stale the last use of a key may get before it is recorded again, so
+// that a busy key does not write its row on every request
+const lastUsedResolution = time.Minute
+
+// scanAPIKey scans a row selected with apiKeyColumns, followed by any extra columns
+func scanAPIKey(row rowScanner, key *models.APIKey, extra ...interface{}) error {
+	var id int
+	var scopes string
+	dest := []interface{}{&id, &key.Name, &key.Owner, &key.Prefix, &scopes, &key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	key.ID = strconv.Itoa(id)
+	key.Scopes = strings.Fields(scopes)
+	return nil
+}
+
+func apiKeyNotFoundError(txid string) *employeeerror.EmployeeError {
+	return &employeeerror.EmployeeError{
+		Code:    http.StatusNotFound,
+		Message: "API key not found",
+		Trace:   txid,
+	}
+}
+
+// CreateAPIKey stores a new key of the tenant of the request, of which only the hash is kept
+func (p postgres) CreateAPIKey(ctx *gin.Context, key models.APIKey) (models.APIKey, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	created := models.APIKey{Key: key.Key}
+	q, done, err := p.scope(ctx)
+	if err == nil {
+		err = scanAPIKey(q.QueryRowContext(ctx, `INSERT INTO api_keys (name, owner, prefix, key_hash, scopes, expires_at, created_at, tenant_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+apiKeyColumns,
+			key.Name, key.Owner, key.Prefix, key.KeyHash, strings.Join(key.Scopes, " "), key.ExpiresAt, time.Now(), tenantOf(ctx)), &created)
+		if doneErr := done(); err == nil {
+			err = doneErr
+		}
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error creating API key", zap.Error(err))
+		return models.APIKey{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to create API key",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("successfully added API key in db", zap.String("api_key_id", created.ID))
+	return created, nil
+}
+
+// ListAPIKeys returns every key of the tenant, revoked and expired ones included, oldest first
+func (p postgres) ListAPIKeys(ctx *gin.Context) ([]models.APIKey, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	listErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to retrieve API keys",
+		Trace:   txid,
+	}
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return nil, listErr
+	}
+	defer done()
+
+	rows, err := q.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE tenant_id=$1 ORDER BY id`, tenantOf(ctx))
+	if err != nil {
+		utils.Log(ctx).Error("Error listing API keys", zap.Error(err))
+		return nil, listErr
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			utils.Log(ctx).Error("Error scanning API key", zap.Error(err))
+			return nil, listErr
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		utils.Log(ctx).Error("Error listing API keys", zap.Error(err))
+		return nil, listErr
+	}
+	return keys, nil
+}
+
+// RotateAPIKey replaces the key of an active API key, the previous key stops working at once
+func (p postgres) RotateAPIKey(ctx *gin.Context, keyId string, rotated models.APIKey) (models.APIKey, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	id, _ := strconv.Atoi(keyId)
+	key := models.APIKey{Key: rotated.Key}
+	q, done, err := p.scope(ctx)
+	if err == nil {
+		err = scanAPIKey(q.QueryRowContext(ctx, `UPDATE api_keys SET prefix=$1, key_hash=$2, last_used_at=NULL WHERE id=$3 AND tenant_id=$4 AND revoked_at IS NULL RETURNING `+apiKeyColumns,
+			rotated.Prefix, rotated.KeyHash, id, tenantOf(ctx)), &key)
+		if doneErr := done(); err == nil {
+			err = doneErr
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.APIKey{}, apiKeyNotFoundError(txid)
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error rotating API key", zap.Error(err))
+		return models.APIKey{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to rotate API key",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("successfully rotated API key in db", zap.String("api_key_id", key.ID))
+	return key, nil
+}
+
+// RevokeAPIKey stops a key from working for good. Revoking a revoked key is not an error.
+func (p postgres) RevokeAPIKey(ctx *gin.Context, keyId string) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	id, _ := strconv.Atoi(keyId)
+	q, done, err := p.scope(ctx)
+	if err == nil {
+		var result sql.Result
+		result, err = q.ExecContext(ctx, `UPDATE api_keys SET revoked_at=COALESCE(revoked_at, $1) WHERE id=$2 AND tenant_id=$3`, time.Now(), id, tenantOf(ctx))
+		if doneErr := done(); err == nil {
+			err = doneErr
+		}
+		if err == nil {
+			var revoked int64
+			if revoked, err = result.RowsAffected(); err == nil && revoked == 0 {
+				return apiKeyNotFoundError(txid)
+			}
+		}
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error revoking API key", zap.Error(err))
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to revoke API key",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("successfully revoked API key in db", zap.String("api_key_id", keyId))
+	return nil
+}
+
+// UseAPIKey returns the active key with the given hash, reporting whether there is one, and
+// records that it was used. The key is looked up across tenants, as it is what the tenant of the
+// request is resolved from.
+func (p postgres) UseAPIKey(ctx *gin.Context, keyHash string) (models.APIKey, bool, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	useErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to check API key",
+		Trace:   txid,
+	}
+	q, done, err := p.scopeTo(ctx, allTenants)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.APIKey{}, false, useErr
+	}
+	defer done()
+
+	now := time.Now()
+	var key models.APIKey
+	err = scanAPIKey(q.QueryRowContext(ctx, `SELECT `+apiKeyColumns+`, tenant_id FROM api_keys WHERE key_hash=$1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)`,
+		keyHash, now), &key, &key.TenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.APIKey{}, false, nil
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error looking up API key", zap.Error(err))
+		return models.APIKey{}, false, useErr
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		// The request goes on when the use cannot be recorded
+		id, _ := strconv.Atoi(key.ID)
+		if _, err := q.ExecContext(ctx, `UPDATE api_keys SET last_used_at=$1 WHERE id=$2`, now, id); err != nil {
+			utils.Log(ctx).Error("Error recording API key use", zap.Error(err))
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, true, nil
+}
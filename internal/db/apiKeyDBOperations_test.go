@@ -0,0 +1,176 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiKeyColumnNames = []string{"id", "name", "owner", "prefix", "scopes", "expires_at", "last_used_at", "created_at", "revoked_at"}
+
+func TestCreateAPIKey(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+	now := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO api_keys \(name, owner, prefix, key_hash, scopes, expires_at, created_at, tenant_id\)`).
+		WithArgs("payroll", "alice", "emp_abcdefgh", "hash", "employees:read salaries:read", nil, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumnNames).
+			AddRow(3, "payroll", "alice", "emp_abcdefgh", "employees:read salaries:read", nil, nil, now, nil))
+
+	created, employeeErr := p.CreateAPIKey(newTestContext(), models.APIKey{
+		Name: "payroll", Owner: "alice", Prefix: "emp_abcdefgh", KeyHash: "hash", Key: "emp_secret",
+		Scopes: []string{"employees:read", "salaries:read"},
+	})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, "3", created.ID)
+	assert.Equal(t, []string{"employees:read", "salaries:read"}, created.Scopes)
+	// The key is returned once, its hash never is
+	assert.Equal(t, "emp_secret", created.Key)
+	assert.Empty(t, created.KeyHash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateAPIKey_Revoked(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectQuery(`UPDATE api_keys SET prefix=\$1, key_hash=\$2, last_used_at=NULL WHERE id=\$3 AND tenant_id=\$4 AND revoked_at IS NULL`).
+		WithArgs("emp_ijklmnop", "new-hash", 3, "default").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumnNames))
+
+	_, employeeErr := p.RotateAPIKey(newTestContext(), "3", models.APIKey{Prefix: "emp_ijklmnop", KeyHash: "new-hash"})
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// A key of another tenant is not found either
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at=COALESCE\(revoked_at, \$1\) WHERE id=\$2 AND tenant_id=\$3`).
+		WithArgs(sqlmock.AnyArg(), 9, "default").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	employeeErr := p.RevokeAPIKey(newTestContext(), "9")
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseAPIKey(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+	recently := time.Now().Add(-10 * time.Second)
+
+	// A key used long ago has its use recorded
+	mock.ExpectQuery(`SELECT .* FROM api_keys WHERE key_hash=\$1 AND revoked_at IS NULL AND \(expires_at IS NULL OR expires_at > \$2\)`).
+		WithArgs("hash", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(append(apiKeyColumnNames, "tenant_id")).
+			AddRow(3, "payroll", "alice", "emp_abcdefgh", "employees:read", nil, nil, time.Now(), nil, "acme"))
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at=\$1 WHERE id=\$2`).
+		WithArgs(sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	key, ok, employeeErr := p.UseAPIKey(newTestContext(), "hash")
+	assert.Nil(t, employeeErr)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"employees:read"}, key.Scopes)
+	assert.Equal(t, "acme", key.TenantID)
+	assert.NotNil(t, key.LastUsedAt)
+
+	// A key used a moment ago is not written again
+	mock.ExpectQuery(`SELECT .* FROM api_keys WHERE key_hash=\$1`).
+		WithArgs("hash", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(append(apiKeyColumnNames, "tenant_id")).
+			AddRow(3, "payroll", "alice", "emp_abcdefgh", "employees:read", nil, recently, time.Now(), nil, "acme"))
+
+	_, ok, employeeErr = p.UseAPIKey(newTestContext(), "hash")
+	assert.Nil(t, employeeErr)
+	assert.True(t, ok)
+
+	// Unknown, revoked and expired keys are not found
+	mock.ExpectQuery(`SELECT .* FROM api_keys WHERE key_hash=\$1`).
+		WithArgs("other", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(append(apiKeyColumnNames, "tenant_id")))
+
+	_, ok, employeeErr = p.UseAPIKey(newTestContext(), "other")
+	assert.Nil(t, employeeErr)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInMemory_APIKeys(t *testing.T) {
+	m := NewInMemory()
+	ctx := newTestContext()
+	past := time.Now().Add(-time.Hour)
+
+	first, _ := m.CreateAPIKey(ctx, models.APIKey{Name: "payroll", Prefix: "emp_1", KeyHash: "hash-1", Key: "emp_1secret", Scopes: []string{"employees:read"}})
+	assert.Equal(t, "1", first.ID)
+	assert.Equal(t, "emp_1secret", first.Key)
+	expired, _ := m.CreateAPIKey(ctx, models.APIKey{Name: "old", KeyHash: "hash-2", Scopes: []string{"employees:read"}, ExpiresAt: &past})
+
+	key, ok, _ := m.UseAPIKey(ctx, "hash-1")
+	assert.True(t, ok)
+	assert.Equal(t, "payroll", key.Name)
+	assert.NotNil(t, key.LastUsedAt)
+	_, ok, _ = m.UseAPIKey(ctx, "hash-2")
+	assert.False(t, ok)
+
+	// The keys listed never hold the key nor its hash
+	keys, _ := m.ListAPIKeys(ctx)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, expired.ID, keys[1].ID)
+	for _, listed := range keys {
+		assert.Empty(t, listed.Key)
+		assert.Empty(t, listed.KeyHash)
+	}
+
+	// Rotation replaces the key at once
+	rotated, err := m.RotateAPIKey(ctx, first.ID, models.APIKey{Prefix: "emp_3", KeyHash: "hash-3", Key: "emp_3secret"})
+	assert.Nil(t, err)
+	assert.Equal(t, "emp_3secret", rotated.Key)
+	assert.Nil(t, rotated.LastUsedAt)
+	_, ok, _ = m.UseAPIKey(ctx, "hash-1")
+	assert.False(t, ok)
+	_, ok, _ = m.UseAPIKey(ctx, "hash-3")
+	assert.True(t, ok)
+
+	// A revoked key stops working and cannot be rotated
+	assert.Nil(t, m.RevokeAPIKey(ctx, first.ID))
+	assert.Nil(t, m.RevokeAPIKey(ctx, first.ID))
+	_, ok, _ = m.UseAPIKey(ctx, "hash-3")
+	assert.False(t, ok)
+	_, err = m.RotateAPIKey(ctx, first.ID, models.APIKey{KeyHash: "hash-4"})
+	assert.Equal(t, http.StatusNotFound, err.Code)
+	assert.Equal(t, http.StatusNotFound, m.RevokeAPIKey(ctx, "42").Code)
+}
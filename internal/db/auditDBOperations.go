@@ -0,0 +1,149 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// newAuditEntry records a change of an employee, before is nil for a create and after is nil
+// for a purge
+func newAuditEntry(action string, before, after *models.Employee, actor string, transactionID string) models.AuditEntry {
+	entry := models.AuditEntry{
+		Action:        action,
+		Actor:         actor,
+		TransactionID: transactionID,
+	}
+	if before != nil {
+		entry.EmployeeID = before.ID
+		entry.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		entry.EmployeeID = after.ID
+		entry.After, _ = json.Marshal(after)
+	}
+	return entry
+}
+
+// requestAuditEntry records a change made by the request
+func requestAuditEntry(ctx *gin.Context, action string, before, after *models.Employee) models.AuditEntry {
+	entry := newAuditEntry(action, before, after, actorOf(ctx), ctx.Request.Header.Get(constants.TransactionID))
+	entry.TenantID = tenantOf(ctx)
+	return entry
+}
+
+// nullableJSON passes an empty document to the database as NULL
+func nullableJSON(document json.RawMessage) interface{} {
+	if len(document) == 0 {
+		return nil
+	}
+	return string(document)
+}
+
+// insertAudit writes an audit entry, q is the transaction of the change being recorded
+func insertAudit(ctx context.Context, q queryer, entry models.AuditEntry) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO employee_audit (employee_id, action, before, after, actor, transaction_id, tenant_id) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		entry.EmployeeID, entry.Action, nullableJSON(entry.Before), nullableJSON(entry.After), entry.Actor, entry.TransactionID, entry.TenantID)
+	return err
+}
+
+// buildAuditFilter returns the WHERE clause selecting the audit entries of the tenant matching
+// the query
+func buildAuditFilter(tenant string, query models.AuditQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	add("tenant_id = $%d", tenant)
+
+	if query.EmployeeID != nil {
+		add("employee_id = $%d", *query.EmployeeID)
+	}
+	if query.Actor != "" {
+		add("actor = $%d", query.Actor)
+	}
+	if query.From != nil {
+		add("created_at >= $%d", *query.From)
+	}
+	if query.To != nil {
+		add("created_at < $%d", *query.To)
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (p postgres) ListAudit(ctx *gin.Context, query models.AuditQuery) (models.AuditList, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	auditErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to retrieve audit records",
+		Trace:   txid,
+	}
+
+	where, args := buildAuditFilter(tenantOf(ctx), query)
+
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.AuditList{}, auditErr
+	}
+	defer done()
+
+	var totalCount int
+	if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM employee_audit `+where, args...).Scan(&totalCount); err != nil {
+		utils.Log(ctx).Error("Error executing audit count query", zap.Error(err))
+		return models.AuditList{}, auditErr
+	}
+
+	// The newest changes come first
+	offset := (query.Page - 1) * query.PageSize
+	listQuery := fmt.Sprintf(`SELECT id, employee_id, action, before, after, actor, transaction_id, created_at
+               FROM employee_audit %s
+               ORDER BY id DESC
+               LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	args = append(args, query.PageSize, offset)
+
+	rows, err := q.QueryContext(ctx, listQuery, args...)
+	if err != nil {
+		utils.Log(ctx).Error("Error executing audit query", zap.Error(err))
+		return models.AuditList{}, auditErr
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&entry.ID, &entry.EmployeeID, &entry.Action, &before, &after, &entry.Actor, &entry.TransactionID, &entry.CreatedAt); err != nil {
+			utils.Log(ctx).Error("Error scanning audit row", zap.Error(err))
+			return models.AuditList{}, auditErr
+		}
+		entry.Before, entry.After = before, after
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		utils.Log(ctx).Error("Error iterating over audit rows", zap.Error(err))
+		return models.AuditList{}, auditErr
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved audit records from db")
+	return models.AuditList{
+		Entries:    entries,
+		TotalCount: totalCount,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
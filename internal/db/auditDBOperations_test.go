@@ -0,0 +1,83 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectAudit expects the audit entry of a change made through newTestContext
+func expectAudit(mock sqlmock.Sqlmock, employeeID string, action string) {
+	mock.ExpectExec(`INSERT INTO employee_audit \(employee_id, action, before, after, actor, transaction_id, tenant_id\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\)`).
+		WithArgs(employeeID, action, sqlmock.AnyArg(), sqlmock.AnyArg(), "anonymous", sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestNewAuditEntry(t *testing.T) {
+	before := models.Employee{ID: "1", Name: "John Doe", Version: 1}
+	after := models.Employee{ID: "1", Name: "Jane Doe", Version: 2}
+
+	entry := newAuditEntry(models.AuditUpdate, &before, &after, "alice", "txid")
+	assert.Equal(t, "1", entry.EmployeeID)
+	assert.Equal(t, "alice", entry.Actor)
+	assert.Equal(t, "txid", entry.TransactionID)
+
+	var recorded models.Employee
+	assert.NoError(t, json.Unmarshal(entry.After, &recorded))
+	assert.Equal(t, "Jane Doe", recorded.Name)
+
+	// A create has nothing before it
+	entry = newAuditEntry(models.AuditCreate, nil, &after, "alice", "txid")
+	assert.Nil(t, entry.Before)
+	assert.Nil(t, nullableJSON(entry.Before))
+}
+
+func TestBuildAuditFilter(t *testing.T) {
+	employeeID := "7"
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	where, args := buildAuditFilter("acme", models.AuditQuery{EmployeeID: &employeeID, Actor: "alice", From: &from, To: &to})
+	assert.Equal(t, "WHERE tenant_id = $1 AND employee_id = $2 AND actor = $3 AND created_at >= $4 AND created_at < $5", where)
+	assert.Equal(t, []interface{}{"acme", "7", "alice", from, to}, args)
+
+	// The entries of other tenants are always left out
+	where, args = buildAuditFilter("acme", models.AuditQuery{})
+	assert.Equal(t, "WHERE tenant_id = $1", where)
+	assert.Equal(t, []interface{}{"acme"}, args)
+}
+
+func TestListAudit(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM employee_audit WHERE tenant_id = \$1 AND actor = \$2`).
+		WithArgs("default", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	mock.ExpectQuery(`SELECT id, employee_id, action, before, after, actor, transaction_id, created_at\s+FROM employee_audit WHERE tenant_id = \$1 AND actor = \$2\s+ORDER BY id DESC\s+LIMIT \$3 OFFSET \$4`).
+		WithArgs("default", "alice", 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "employee_id", "action", "before", "after", "actor", "transaction_id", "created_at"}).
+			AddRow(2, 1, "update", []byte(`{"id":"1","name":"John Doe"}`), []byte(`{"id":"1","name":"Jane Doe"}`), "alice", "txid", now).
+			AddRow(1, 1, "create", nil, []byte(`{"id":"1","name":"John Doe"}`), "alice", "txid", now))
+
+	list, employeeErr := p.ListAudit(newTestContext(), models.AuditQuery{Actor: "alice", Page: 2, PageSize: 10})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, 12, list.TotalCount)
+	assert.Len(t, list.Entries, 2)
+	assert.Equal(t, "1", list.Entries[0].EmployeeID)
+	assert.JSONEq(t, `{"id":"1","name":"Jane Doe"}`, string(list.Entries[0].After))
+	assert.Nil(t, list.Entries[1].Before)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
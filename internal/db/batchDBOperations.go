@@ -0,0 +1,231 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BatchUpdateEmployees applies the fields set in update to every selected employee in one
+// transaction. An employee that cannot be updated fails the whole batch. A dry run returns the
+// employees as they would be and rolls the transaction back.
+func (p postgres) BatchUpdateEmployees(ctx *gin.Context, selection models.BatchSelection, update models.Employee, dryRun bool) ([]models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	updateErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to update employee records",
+		Trace:   txid,
+	}
+
+	tx, err := p.beginTx(ctx, tenantOf(ctx), nil)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return nil, updateErr
+	}
+	defer tx.Rollback()
+
+	// A manager change takes the hierarchy lock before locking any employee, in the same order
+	// as UpdateEmployee, so that the two cannot deadlock
+	if update.ManagerID != nil {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, hierarchyLockID); err != nil {
+			utils.Log(ctx).Error("Error acquiring hierarchy lock", zap.Error(err))
+			return nil, updateErr
+		}
+	}
+
+	ids, selectErr := selectBatchEmployees(ctx, tx, selection)
+	if selectErr != nil {
+		return nil, selectErr
+	}
+	// Unlike a filter, a list of IDs must only name active employees
+	if missing := missingBatchIDs(selection.IDs, ids); len(missing) > 0 {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employees not found: " + strings.Join(missing, ", "),
+			Trace:   txid,
+		}
+	}
+
+	employees := make([]models.Employee, 0, len(ids))
+	for _, id := range ids {
+		employee := update
+		employee.ID = strconv.Itoa(id)
+		after, changeErr := updateEmployee(ctx, tx, employee, false)
+		if changeErr != nil {
+			return nil, batchRowError(employee.ID, changeErr)
+		}
+		employees = append(employees, after)
+	}
+
+	if dryRun {
+		utils.Log(ctx).Info("dry run of batch update rolled back", zap.Int("employees", len(employees)))
+		return employees, nil
+	}
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing batch update", zap.Error(err))
+		return nil, updateErr
+	}
+
+	utils.Log(ctx).Info("Successfully updated employee entries in db", zap.Int("employees", len(employees)))
+	return employees, nil
+}
+
+// BatchDeleteEmployees soft deletes every selected employee in one transaction, detaching their
+// reports as DeleteEmployee does. As with DeleteEmployee, an ID that does not name an active
+// employee is not an error. A dry run returns the employees as they would be and rolls the
+// transaction back.
+func (p postgres) BatchDeleteEmployees(ctx *gin.Context, selection models.BatchSelection, dryRun bool) ([]models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	deleteErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to delete employee records",
+		Trace:   txid,
+	}
+
+	tx, err := p.beginTx(ctx, tenantOf(ctx), nil)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return nil, deleteErr
+	}
+	defer tx.Rollback()
+
+	// Holding the hierarchy lock keeps a concurrent manager change from attaching a report
+	// to an employee after its reports were detached
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, hierarchyLockID); err != nil {
+		utils.Log(ctx).Error("Error acquiring hierarchy lock", zap.Error(err))
+		return nil, deleteErr
+	}
+
+	ids, selectErr := selectBatchEmployees(ctx, tx, selection)
+	if selectErr != nil {
+		return nil, selectErr
+	}
+
+	employees := make([]models.Employee, 0, len(ids))
+	for _, id := range ids {
+		// The employee is read again, deleting its manager earlier in the batch detached it
+		before, found, lockErr := lockActiveEmployee(ctx, tx, id)
+		if lockErr != nil {
+			return nil, lockErr
+		}
+		if !found {
+			continue
+		}
+		after, softDeleteErr := softDeleteEmployee(ctx, tx, before)
+		if softDeleteErr != nil {
+			return nil, softDeleteErr
+		}
+		employees = append(employees, after)
+	}
+
+	if dryRun {
+		utils.Log(ctx).Info("dry run of batch delete rolled back", zap.Int("employees", len(employees)))
+		return employees, nil
+	}
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing batch delete", zap.Error(err))
+		return nil, deleteErr
+	}
+
+	utils.Log(ctx).Info("Successfully deleted employee entries from db", zap.Int("employees", len(employees)))
+	return employees, nil
+}
+
+// selectBatchEmployees locks the active employees of a batch and returns their IDs in ascending
+// order, the order in which concurrent batches lock them. A filter matching more than
+// constants.MaxBatchSize employees is rejected.
+func selectBatchEmployees(ctx *gin.Context, tx *sql.Tx, selection models.BatchSelection) ([]int, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	var query string
+	var args []interface{}
+	if selection.Query != nil {
+		where, filterArgs := buildEmployeeFilter(tenantOf(ctx), *selection.Query)
+		query = fmt.Sprintf("SELECT id FROM employees %s ORDER BY id LIMIT %d FOR UPDATE", where, constants.MaxBatchSize+1)
+		args = filterArgs
+	} else {
+		// The IDs of another tenant are reported missing like those of no employee
+		args = append(args, tenantOf(ctx))
+		placeholders := make([]string, len(selection.IDs))
+		for i, id := range selection.IDs {
+			placeholders[i] = fmt.Sprintf("$%d", i+2)
+			empId, _ := strconv.Atoi(id)
+			args = append(args, empId)
+		}
+		query = fmt.Sprintf("SELECT id FROM employees WHERE tenant_id=$1 AND id IN (%s) AND deleted_at IS NULL ORDER BY id FOR UPDATE", strings.Join(placeholders, ", "))
+	}
+
+	selectErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to retrieve employee records",
+		Trace:   txid,
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		utils.Log(ctx).Error("Error selecting batch employees", zap.Error(err))
+		return nil, selectErr
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			utils.Log(ctx).Error("Error scanning batch employee", zap.Error(err))
+			return nil, selectErr
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		utils.Log(ctx).Error("Error selecting batch employees", zap.Error(err))
+		return nil, selectErr
+	}
+
+	if len(ids) > constants.MaxBatchSize {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("filter matches more than %d employees", constants.MaxBatchSize),
+			Trace:   txid,
+		}
+	}
+	return ids, nil
+}
+
+// missingBatchIDs returns the requested IDs that were not selected, in the order requested
+func missingBatchIDs(requested []string, selected []int) []string {
+	found := make(map[string]bool, len(selected))
+	for _, id := range selected {
+		found[strconv.Itoa(id)] = true
+	}
+	var missing []string
+	for _, id := range requested {
+		if !found[canonicalID(id)] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// batchRowError names the employee a rejected change of a batch was for, so that the client
+// can tell which one failed the batch
+func batchRowError(employeeID string, err *employeeerror.EmployeeError) *employeeerror.EmployeeError {
+	if err.Code >= http.StatusInternalServerError {
+		return err
+	}
+	return &employeeerror.EmployeeError{
+		Code:    err.Code,
+		Message: fmt.Sprintf("employee %s: %s", employeeID, err.Message),
+		Trace:   err.Trace,
+	}
+}
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const lockEmployeeQuery = `SELECT .+ FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`
+
+func TestBatchUpdateEmployees_DryRun(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// Both employees are locked up front and updated one by one, then the dry run rolls back
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM employees WHERE tenant_id=\$1 AND deleted_at IS NULL AND position=\$2 ORDER BY id LIMIT 1001 FOR UPDATE`).
+		WithArgs("default", "Engineer").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	for _, id := range []int{1, 2} {
+		mock.ExpectQuery(lockEmployeeQuery).
+			WithArgs(id, "default").
+			WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(id, "Name", "Engineer", "40000", "USD", nil, nil, now, now, 1, nil))
+		mock.ExpectQuery(`UPDATE employees SET position=\$1, last_updated_at=\$2 WHERE id=\$3 AND tenant_id=\$4 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+			WithArgs("Senior Engineer", sqlmock.AnyArg(), id, "default").
+			WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(id, "Name", "Senior Engineer", "40000", "USD", nil, nil, now, now, 2, nil))
+		expectAudit(mock, strconv.Itoa(id), models.AuditUpdate)
+	}
+	mock.ExpectRollback()
+
+	selection := models.BatchSelection{Query: &models.EmployeeQuery{Position: "Engineer"}}
+	employees, employeeErr := p.BatchUpdateEmployees(newTestContext(), selection, models.Employee{Position: "Senior Engineer"}, true)
+	assert.Nil(t, employeeErr)
+	assert.Len(t, employees, 2)
+	assert.Equal(t, "Senior Engineer", employees[1].Position)
+	assert.Equal(t, 2, employees[1].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchUpdateEmployees_NotFound(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM employees WHERE tenant_id=\$1 AND id IN \(\$2, \$3\) AND deleted_at IS NULL ORDER BY id FOR UPDATE`).
+		WithArgs("default", 1, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	selection := models.BatchSelection{IDs: []string{"1", "7"}}
+	_, employeeErr := p.BatchUpdateEmployees(newTestContext(), selection, models.Employee{Position: "Senior Engineer"}, false)
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	assert.Equal(t, "Employees not found: 7", employeeErr.Message)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchDeleteEmployees(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// Employee 2 reported to employee 1, deleting 1 detaches it before it is deleted in turn
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).WithArgs(hierarchyLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT id FROM employees WHERE tenant_id=\$1 AND id IN \(\$2, \$3\) AND deleted_at IS NULL ORDER BY id FOR UPDATE`).
+		WithArgs("default", 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery(lockEmployeeQuery).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Jane Doe", "Manager", "60000", "USD", nil, nil, now, now, 1, nil))
+	mock.ExpectQuery(`UPDATE employees SET deleted_at=\$2 WHERE id=\$1 AND tenant_id=\$3 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(1, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Jane Doe", "Manager", "60000", "USD", nil, nil, now, now, 2, now))
+	expectAudit(mock, "1", models.AuditDelete)
+	mock.ExpectQuery(`UPDATE employees SET manager_id=NULL WHERE manager_id=\$1 AND tenant_id=\$2 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(2, "John Doe", "Engineer", "50000", "USD", nil, nil, now, now, 2, nil))
+	expectAudit(mock, "2", models.AuditUpdate)
+	mock.ExpectQuery(lockEmployeeQuery).
+		WithArgs(2, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(2, "John Doe", "Engineer", "50000", "USD", nil, nil, now, now, 2, nil))
+	mock.ExpectQuery(`UPDATE employees SET deleted_at=\$2 WHERE id=\$1 AND tenant_id=\$3 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(2, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(2, "John Doe", "Engineer", "50000", "USD", nil, nil, now, now, 3, now))
+	expectAudit(mock, "2", models.AuditDelete)
+	mock.ExpectQuery(`UPDATE employees SET manager_id=NULL WHERE manager_id=\$1 AND tenant_id=\$2 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(2, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList))
+	mock.ExpectCommit()
+
+	selection := models.BatchSelection{IDs: []string{"1", "2"}}
+	employees, employeeErr := p.BatchDeleteEmployees(newTestContext(), selection, false)
+	assert.Nil(t, employeeErr)
+	assert.Len(t, employees, 2)
+	assert.NotNil(t, employees[0].DeletedAt)
+	assert.Equal(t, 3, employees[1].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInMemory_BatchUpdateEmployees(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	managerID, _ := m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Manager", Salary: usd("60000")})
+	employeeID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: usd("50000")})
+
+	// A dry run leaves the store, its audit trail and the salary history untouched
+	selection := models.BatchSelection{IDs: []string{managerID, employeeID}}
+	employees, employeeErr := m.BatchUpdateEmployees(ctx, selection, models.Employee{Salary: usd("70000")}, true)
+	assert.Nil(t, employeeErr)
+	assert.Len(t, employees, 2)
+	assert.Equal(t, usd("70000"), employees[0].Salary)
+	employee, _ := m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, usd("50000"), employee.Salary)
+	assert.Equal(t, 1, employee.Version)
+	audit, _ := m.ListAudit(ctx, models.AuditQuery{Page: 1, PageSize: 10})
+	assert.Equal(t, 2, audit.TotalCount)
+
+	// One employee that cannot be updated fails the whole batch
+	_, employeeErr = m.BatchUpdateEmployees(ctx, selection, models.Employee{ManagerID: &managerID}, false)
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+	employee, _ = m.GetEmployeeByID(ctx, employeeID)
+	assert.Nil(t, employee.ManagerID)
+
+	employees, employeeErr = m.BatchUpdateEmployees(ctx, models.BatchSelection{IDs: []string{employeeID}}, models.Employee{ManagerID: &managerID}, false)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, &managerID, employees[0].ManagerID)
+	employee, _ = m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, &managerID, employee.ManagerID)
+}
+
+func TestInMemory_BatchDeleteEmployees(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	managerID, _ := m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Manager", Salary: usd("60000")})
+	employeeID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: usd("50000"), ManagerID: &managerID})
+	m.CreateEmployee(ctx, models.Employee{Name: "Jim Doe", Position: "Engineer", Salary: usd("40000")})
+
+	salaryMin := decimal("45000")
+	selection := models.BatchSelection{Query: &models.EmployeeQuery{SalaryMin: &salaryMin}}
+	employees, employeeErr := m.BatchDeleteEmployees(ctx, selection, true)
+	assert.Nil(t, employeeErr)
+	assert.Len(t, employees, 2)
+	list, _ := m.ListEmployee(ctx, models.EmployeeQuery{Page: 1, PageSize: 10})
+	assert.Equal(t, 3, list.TotalCount)
+
+	employees, employeeErr = m.BatchDeleteEmployees(ctx, selection, false)
+	assert.Nil(t, employeeErr)
+	assert.Len(t, employees, 2)
+	assert.Nil(t, employees[1].ManagerID)
+	list, _ = m.ListEmployee(ctx, models.EmployeeQuery{Page: 1, PageSize: 10})
+	assert.Equal(t, 1, list.TotalCount)
+	_, employeeErr = m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+}
@@ -0,0 +1,244 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// salaryChangeColumns is the column list scanned by scanSalaryChange
+const salaryChangeColumns = "id, employee_id, amount, currency, effective_from, reason, created_at"
+
+// scanSalaryChange scans a row selected with salaryChangeColumns
+func scanSalaryChange(row rowScanner, change *models.SalaryChange) error {
+	return row.Scan(&change.ID, &change.EmployeeID, &change.Amount, &change.Currency, &change.EffectiveFrom, &change.Reason, &change.CreatedAt)
+}
+
+// insertSalaryChange appends a change to the salary history of the tenant, filling in its ID and
+// creation time
+func insertSalaryChange(ctx context.Context, q queryer, tenant string, change *models.SalaryChange) error {
+	return q.QueryRowContext(ctx, `INSERT INTO salary_history (employee_id, amount, currency, effective_from, reason, tenant_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		change.EmployeeID, change.Amount, change.Currency, change.EffectiveFrom, change.Reason, tenant).Scan(&change.ID, &change.CreatedAt)
+}
+
+// resolveCompensation splits the salary history of an employee, ordered newest first, into the
+// changes in effect at asOf and the scheduled ones
+func resolveCompensation(employeeID string, changes []models.SalaryChange, asOf time.Time) models.Compensation {
+	compensation := models.Compensation{
+		EmployeeID: employeeID,
+		AsOf:       asOf,
+		History:    []models.SalaryChange{},
+		Scheduled:  []models.SalaryChange{},
+	}
+	for _, change := range changes {
+		if change.EffectiveFrom.After(asOf) {
+			// Scheduled changes are listed soonest first
+			compensation.Scheduled = append([]models.SalaryChange{change}, compensation.Scheduled...)
+			continue
+		}
+		if compensation.Current == nil {
+			current := change
+			compensation.Current = &current
+		}
+		compensation.History = append(compensation.History, change)
+	}
+	return compensation
+}
+
+func (p postgres) GetCompensation(ctx *gin.Context, employeeId string, asOf time.Time) (models.Compensation, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	compensationErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to retrieve compensation records",
+		Trace:   txid,
+	}
+
+	empId, _ := strconv.Atoi(employeeId)
+	tenant := tenantOf(ctx)
+
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.Compensation{}, compensationErr
+	}
+	defer done()
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM employees WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL)`, empId, tenant).Scan(&exists); err != nil {
+		utils.Log(ctx).Error("Error executing query", zap.Error(err))
+		return models.Compensation{}, compensationErr
+	}
+	if !exists {
+		return models.Compensation{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT `+salaryChangeColumns+` FROM salary_history WHERE employee_id=$1 AND tenant_id=$2 ORDER BY effective_from DESC, id DESC`, empId, tenant)
+	if err != nil {
+		utils.Log(ctx).Error("Error executing salary history query", zap.Error(err))
+		return models.Compensation{}, compensationErr
+	}
+	defer rows.Close()
+
+	var changes []models.SalaryChange
+	for rows.Next() {
+		var change models.SalaryChange
+		if err := scanSalaryChange(rows, &change); err != nil {
+			utils.Log(ctx).Error("Error scanning salary history row", zap.Error(err))
+			return models.Compensation{}, compensationErr
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		utils.Log(ctx).Error("Error iterating over salary history rows", zap.Error(err))
+		return models.Compensation{}, compensationErr
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved compensation records from db")
+	return resolveCompensation(strconv.Itoa(empId), changes, asOf), nil
+}
+
+// ScheduleSalaryChange appends a change to the salary history of an employee. A change that is
+// already in effect updates the salary of the employee straight away, a later one is applied by
+// ApplySalaryChanges once it is due.
+func (p postgres) ScheduleSalaryChange(ctx *gin.Context, change models.SalaryChange) (models.SalaryChange, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	scheduleErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to record salary change",
+		Trace:   txid,
+	}
+
+	tenant := tenantOf(ctx)
+	tx, err := p.beginTx(ctx, tenant, nil)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.SalaryChange{}, scheduleErr
+	}
+	defer tx.Rollback()
+
+	empId, _ := strconv.Atoi(change.EmployeeID)
+	employee, found, lockErr := lockActiveEmployee(ctx, tx, empId)
+	if lockErr != nil {
+		return models.SalaryChange{}, lockErr
+	}
+	if !found {
+		return models.SalaryChange{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+
+	change.EmployeeID = employee.ID
+	if err := insertSalaryChange(ctx, tx, tenant, &change); err != nil {
+		utils.Log(ctx).Error("Error recording salary change", zap.Error(err))
+		return models.SalaryChange{}, scheduleErr
+	}
+
+	now := time.Now()
+	if !change.EffectiveFrom.After(now) {
+		var salary models.Money
+		err := tx.QueryRowContext(ctx, `SELECT amount, currency FROM salary_history WHERE employee_id=$1 AND tenant_id=$3 AND effective_from <= $2 ORDER BY effective_from DESC, id DESC LIMIT 1`, empId, now, tenant).Scan(&salary.Amount, &salary.Currency)
+		if err != nil {
+			utils.Log(ctx).Error("Error resolving current salary", zap.Error(err))
+			return models.SalaryChange{}, scheduleErr
+		}
+		// A backdated change does not replace a later one that is already in effect
+		if employee.Salary == nil || !employee.Salary.Equal(salary) {
+			if err := applySalary(ctx, tx, tenant, employee, salary, now, actorOf(ctx), txid); err != nil {
+				utils.Log(ctx).Error("Error applying salary change", zap.Error(err))
+				return models.SalaryChange{}, scheduleErr
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing salary change", zap.Error(err))
+		return models.SalaryChange{}, scheduleErr
+	}
+
+	utils.Log(ctx).Info("Successfully recorded salary change in db")
+	return change, nil
+}
+
+// applySalary sets the salary of an employee of the tenant locked by the transaction q and audits
+// the change
+func applySalary(ctx context.Context, q queryer, tenant string, before models.Employee, salary models.Money, now time.Time, actor string, transactionID string) error {
+	var after models.Employee
+	err := scanEmployee(q.QueryRowContext(ctx, `UPDATE employees SET salary=$2, salary_currency=$3, last_updated_at=$4 WHERE id=$1 AND tenant_id=$5 RETURNING `+employeeColumns, before.ID, salary.Amount, salary.Currency, now, tenant), &after)
+	if err != nil {
+		return err
+	}
+	entry := newAuditEntry(models.AuditUpdate, &before, &after, actor, transactionID)
+	entry.TenantID = tenant
+	return insertAudit(ctx, q, entry)
+}
+
+// ApplySalaryChanges brings the salary of every employee in line with the latest change of
+// their salary history in effect at asOf, whatever their tenant, and returns how many employees
+// were updated. Each update is audited under the given actor and transaction.
+func (p postgres) ApplySalaryChanges(ctx context.Context, asOf time.Time, actor string, transactionID string) (int64, error) {
+	tx, err := p.beginTx(ctx, allTenants, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+employeeColumnsOf("e")+`, s.amount, s.currency, e.tenant_id
+               FROM employees e
+               JOIN (SELECT DISTINCT ON (employee_id) employee_id, amount, currency
+                     FROM salary_history
+                     WHERE effective_from <= $1
+                     ORDER BY employee_id, effective_from DESC, id DESC) s ON s.employee_id = e.id
+               WHERE e.deleted_at IS NULL AND (e.salary <> s.amount OR e.salary_currency <> s.currency)
+               ORDER BY e.id
+               FOR UPDATE OF e`, asOf)
+	if err != nil {
+		return 0, err
+	}
+	var due []models.Employee
+	var salaries []models.Money
+	var tenants []string
+	for rows.Next() {
+		var employee models.Employee
+		var salary models.Money
+		var tenant string
+		if err := scanEmployee(rows, &employee, &salary.Amount, &salary.Currency, &tenant); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, employee)
+		salaries = append(salaries, salary)
+		tenants = append(tenants, tenant)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	for i, employee := range due {
+		if err := applySalary(ctx, tx, tenants[i], employee, salaries[i], now, actor, transactionID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(due)), nil
+}
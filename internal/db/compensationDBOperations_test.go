@@ -0,0 +1,170 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectSalaryChange expects a change to be appended to the salary history
+func expectSalaryChange(mock sqlmock.Sqlmock, employeeID string, reason string) {
+	mock.ExpectQuery(`INSERT INTO salary_history \(employee_id, amount, currency, effective_from, reason, tenant_id\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id, created_at`).
+		WithArgs(employeeID, sqlmock.AnyArg(), "USD", sqlmock.AnyArg(), reason, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+}
+
+func TestResolveCompensation(t *testing.T) {
+	now := time.Now()
+	amount := func(value string) *models.Decimal {
+		d := decimal(value)
+		return &d
+	}
+
+	// Ordered newest first, as returned by the salary history query
+	changes := []models.SalaryChange{
+		{ID: 4, Amount: amount("70000"), EffectiveFrom: now.AddDate(1, 0, 0)},
+		{ID: 3, Amount: amount("60000"), EffectiveFrom: now.AddDate(0, 1, 0)},
+		{ID: 2, Amount: amount("55000"), EffectiveFrom: now.AddDate(0, -1, 0)},
+		{ID: 1, Amount: amount("50000"), EffectiveFrom: now.AddDate(-1, 0, 0)},
+	}
+
+	compensation := resolveCompensation("1", changes, now)
+	assert.Equal(t, int64(2), compensation.Current.ID)
+	assert.Equal(t, []int64{2, 1}, []int64{compensation.History[0].ID, compensation.History[1].ID})
+	assert.Equal(t, []int64{3, 4}, []int64{compensation.Scheduled[0].ID, compensation.Scheduled[1].ID})
+
+	// What the employee earned a year ago
+	compensation = resolveCompensation("1", changes, now.AddDate(0, -6, 0))
+	assert.Equal(t, "50000", compensation.Current.Amount.String())
+	assert.Len(t, compensation.Scheduled, 3)
+
+	compensation = resolveCompensation("1", changes, now.AddDate(-2, 0, 0))
+	assert.Nil(t, compensation.Current)
+	assert.Empty(t, compensation.History)
+}
+
+func TestGetCompensation_NotFound(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL\)`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	_, employeeErr := p.GetCompensation(newTestContext(), "1", time.Now())
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScheduleSalaryChange_Future(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// A raise taking effect next month is only recorded, the salary is left alone
+	now := time.Now()
+	raise := decimal("60000")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 50000.0, "USD", nil, nil, now, now, 1, nil))
+	expectSalaryChange(mock, "1", "promotion")
+	mock.ExpectCommit()
+
+	change, employeeErr := p.ScheduleSalaryChange(newTestContext(), models.SalaryChange{
+		EmployeeID:    "1",
+		Amount:        &raise,
+		Currency:      "USD",
+		EffectiveFrom: now.AddDate(0, 1, 0),
+		Reason:        "promotion",
+	})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, int64(1), change.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScheduleSalaryChange_Immediate(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// A change already in effect updates the salary and audits it
+	now := time.Now()
+	raise := decimal("60000")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 50000.0, "USD", nil, nil, now, now, 1, nil))
+	expectSalaryChange(mock, "1", "promotion")
+	mock.ExpectQuery(`SELECT amount, currency FROM salary_history WHERE employee_id=\$1 AND tenant_id=\$3 AND effective_from <= \$2 ORDER BY effective_from DESC, id DESC LIMIT 1`).
+		WithArgs(1, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "currency"}).AddRow("60000.0000", "USD"))
+	mock.ExpectQuery(`UPDATE employees SET salary=\$2, salary_currency=\$3, last_updated_at=\$4 WHERE id=\$1 AND tenant_id=\$5 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs("1", "60000", "USD", sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 60000.0, "USD", nil, nil, now, now, 2, nil))
+	expectAudit(mock, "1", models.AuditUpdate)
+	mock.ExpectCommit()
+
+	_, employeeErr := p.ScheduleSalaryChange(newTestContext(), models.SalaryChange{
+		EmployeeID:    "1",
+		Amount:        &raise,
+		Currency:      "USD",
+		EffectiveFrom: now.Add(-time.Minute),
+		Reason:        "promotion",
+	})
+	assert.Nil(t, employeeErr)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplySalaryChanges(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT ` + regexp.QuoteMeta(employeeColumnsOf("e")) + `, s.amount, s.currency, e.tenant_id\s+FROM employees e\s+JOIN \(SELECT DISTINCT ON \(employee_id\)`).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(append(employeeColumnList, "amount", "currency", "tenant_id")).
+			AddRow(3, "Name", "Position", 50000.0, "USD", nil, nil, now, now, 1, nil, "60000.0000", "USD", "acme"))
+	mock.ExpectQuery(`UPDATE employees SET salary=\$2, salary_currency=\$3, last_updated_at=\$4 WHERE id=\$1 AND tenant_id=\$5`).
+		WithArgs("3", "60000", "USD", sqlmock.AnyArg(), "acme").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(3, "Name", "Position", 60000.0, "USD", nil, nil, now, now, 2, nil))
+	mock.ExpectExec(`INSERT INTO employee_audit`).
+		WithArgs("3", models.AuditUpdate, sqlmock.AnyArg(), sqlmock.AnyArg(), "system", "txid", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	applied, err := p.ApplySalaryChanges(context.Background(), now, "system", "txid")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -4,43 +4,202 @@ import (
 	"assignment/internal/config"
 	employeeerror "assignment/internal/errors"
 	"assignment/internal/models"
+	"assignment/internal/utils"
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-type postgres struct{ db *sql.DB }
+type postgres struct {
+	db *sql.DB
+	// rowLevelSecurity sets app.tenant_id for the row level security policies of the tables
+	rowLevelSecurity bool
+}
+
+// allTenants is the app.tenant_id of the background jobs, which work on the rows of every tenant
+const allTenants = "*"
+
+// beginTx starts a transaction limited by row level security, when it is on, to the rows of the
+// tenant
+func (p postgres) beginTx(ctx context.Context, tenant string, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil || !p.rowLevelSecurity {
+		return tx, err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenant); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// scope returns what the queries of a request made outside of a transaction run on: the
+// database, or a transaction limited to the tenant of the request when row level security is
+// on. done ends that transaction, committing the writes made in it.
+func (p postgres) scope(ctx *gin.Context) (q queryer, done func() error, err error) {
+	return p.scopeTo(ctx, tenantOf(ctx))
+}
+
+// scopeTo is scope for the given tenant, allTenants for a background job
+func (p postgres) scopeTo(ctx context.Context, tenant string) (q queryer, done func() error, err error) {
+	if !p.rowLevelSecurity {
+		return p.db, func() error { return nil }, nil
+	}
+	tx, err := p.beginTx(ctx, tenant, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, tx.Commit, nil
+}
 
 type EmployeeDBService interface {
 	// EmployeeDBService
 	CreateEmployee(*gin.Context, models.Employee) (string, *employeeerror.EmployeeError)
-	DeleteEmployee(*gin.Context, string) *employeeerror.EmployeeError
+	DeleteEmployee(*gin.Context, string, int) *employeeerror.EmployeeError
 	GetEmployeeByID(*gin.Context, string) (models.Employee, *employeeerror.EmployeeError)
 	UpdateEmployee(*gin.Context, models.Employee) (models.Employee, *employeeerror.EmployeeError)
-	ListEmployee(*gin.Context, int, int) ([]models.Employee, *employeeerror.EmployeeError)
+	ReplaceEmployee(*gin.Context, models.Employee) (models.Employee, *employeeerror.EmployeeError)
+	ListEmployee(*gin.Context, models.EmployeeQuery) (models.EmployeeList, *employeeerror.EmployeeError)
+	RestoreEmployee(*gin.Context, string) (models.Employee, *employeeerror.EmployeeError)
+	PurgeEmployees(context.Context, time.Time, string, string) (int64, error)
+	PurgeTenantEmployees(*gin.Context, time.Time) (int64, *employeeerror.EmployeeError)
+	ImportEmployees(*gin.Context, []models.ImportRow, bool) ([]models.ImportResult, *employeeerror.EmployeeError)
+	ExportEmployees(*gin.Context, models.EmployeeQuery, func(models.Employee) error) *employeeerror.EmployeeError
+	BatchUpdateEmployees(*gin.Context, models.BatchSelection, models.Employee, bool) ([]models.Employee, *employeeerror.EmployeeError)
+	BatchDeleteEmployees(*gin.Context, models.BatchSelection, bool) ([]models.Employee, *employeeerror.EmployeeError)
+	CountActiveEmployees(context.Context) (map[string]int64, error)
+
+	DepartmentDBService
+	HierarchyDBService
+	AuditDBService
+	CompensationDBService
+	IdempotencyDBService
+	APIKeyDBService
+	HealthDBService
+}
+
+type DepartmentDBService interface {
+	CreateDepartment(*gin.Context, models.Department) (string, *employeeerror.EmployeeError)
+	GetDepartmentByID(*gin.Context, string) (models.Department, *employeeerror.EmployeeError)
+	UpdateDepartment(*gin.Context, models.Department) (models.Department, *employeeerror.EmployeeError)
+	DeleteDepartment(*gin.Context, string) *employeeerror.EmployeeError
+	ListDepartments(*gin.Context, int, int) ([]models.Department, *employeeerror.EmployeeError)
+}
+
+type HierarchyDBService interface {
+	ListReports(*gin.Context, string, int) ([]models.EmployeeReport, *employeeerror.EmployeeError)
+	GetManagementChain(*gin.Context, string) ([]models.Employee, *employeeerror.EmployeeError)
+	GetOrgChart(*gin.Context) ([]*models.OrgChartNode, *employeeerror.EmployeeError)
+}
+
+type AuditDBService interface {
+	ListAudit(*gin.Context, models.AuditQuery) (models.AuditList, *employeeerror.EmployeeError)
+}
+
+type CompensationDBService interface {
+	GetCompensation(*gin.Context, string, time.Time) (models.Compensation, *employeeerror.EmployeeError)
+	ScheduleSalaryChange(*gin.Context, models.SalaryChange) (models.SalaryChange, *employeeerror.EmployeeError)
+	ApplySalaryChanges(context.Context, time.Time, string, string) (int64, error)
 }
 
-func New() (postgres, error) {
+type IdempotencyDBService interface {
+	ReserveIdempotencyKey(*gin.Context, models.IdempotentResponse) (models.IdempotentResponse, bool, *employeeerror.EmployeeError)
+	SaveIdempotentResponse(*gin.Context, models.IdempotentResponse) *employeeerror.EmployeeError
+	ReleaseIdempotencyKey(*gin.Context, string) *employeeerror.EmployeeError
+	PurgeIdempotencyKeys(context.Context, time.Time) (int64, error)
+}
+
+type APIKeyDBService interface {
+	CreateAPIKey(*gin.Context, models.APIKey) (models.APIKey, *employeeerror.EmployeeError)
+	ListAPIKeys(*gin.Context) ([]models.APIKey, *employeeerror.EmployeeError)
+	RotateAPIKey(*gin.Context, string, models.APIKey) (models.APIKey, *employeeerror.EmployeeError)
+	RevokeAPIKey(*gin.Context, string) *employeeerror.EmployeeError
+	UseAPIKey(*gin.Context, string) (models.APIKey, bool, *employeeerror.EmployeeError)
+}
+
+// HealthDBService reports whether the database can serve requests
+type HealthDBService interface {
+	Ping(context.Context) error
+	PendingMigrations(context.Context) (int, error)
+	PoolStats() (models.PoolStats, bool)
+}
+
+// New returns the EmployeeDBService selected by the database configuration,
+// either the in-memory store or a connection to PostgreSQL.
+// Pending schema migrations are applied before returning when auto_migrate is set,
+// and the calls to the database are measured when metrics are enabled.
+func New() (EmployeeDBService, error) {
 	cfg := config.GetConfig()
-	connString := "host=" + cfg.Database.Host + " " + "dbname=" + cfg.Database.DBname + " " + "password=" +
-		cfg.Database.Password + " " + "user=" + cfg.Database.User + " " + "port=" + fmt.Sprint(cfg.Database.Port)
+	if cfg.Database.InMemory {
+		utils.Logger.Info("Using in-memory database")
+		if cfg.Metrics.Enabled {
+			return instrument(newInMemoryTenants()), nil
+		}
+		return newInMemoryTenants(), nil
+	}
 
-	conn, err := sql.Open("pgx", connString)
+	conn, err := Connect()
 	if err != nil {
-		log.Fatalf(fmt.Sprintf("Unable to connect: %v\n", err))
 		return postgres{}, err
 	}
 
-	log.Println("Connected to database")
+	if cfg.Database.AutoMigrate {
+		migrator, err := NewMigrator(conn)
+		if err != nil {
+			return postgres{}, err
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			utils.Logger.Error("Unable to migrate the database", zap.Error(err))
+			return postgres{}, err
+		}
+		utils.Logger.Info("database schema is up to date", zap.Int("applied", applied))
+	}
 
-	err = conn.Ping()
+	repo := postgres{db: conn, rowLevelSecurity: cfg.Tenancy.RowLevelSecurity}
+	if cfg.Metrics.Enabled {
+		registerPoolMetrics(conn)
+		return instrument(repo), nil
+	}
+	return repo, nil
+}
+
+// Connect opens and pings the PostgreSQL database from the global configuration
+func Connect() (*sql.DB, error) {
+	cfg := config.GetConfig()
+	connString := "host=" + cfg.Database.Host + " " + "dbname=" + cfg.Database.DBname + " " + "password=" +
+		cfg.Database.Password + " " + "user=" + cfg.Database.User + " " + "port=" + fmt.Sprint(cfg.Database.Port)
+
+	var conn *sql.DB
+	if cfg.Tracing.Enabled {
+		// Every statement run within a trace is traced
+		connector, err := newTracedConnector(connString)
+		if err != nil {
+			utils.Logger.Fatal("Unable to connect", zap.Error(err))
+			return nil, err
+		}
+		conn = sql.OpenDB(connector)
+	} else {
+		var err error
+		conn, err = sql.Open("pgx", connString)
+		if err != nil {
+			utils.Logger.Fatal("Unable to connect", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	utils.Logger.Info("Connected to database")
+
+	err := conn.Ping()
 	if err != nil {
-		log.Fatal("Cannot Ping the database")
-		return postgres{}, err
+		utils.Logger.Fatal("Cannot Ping the database", zap.Error(err))
+		return nil, err
 	}
-	log.Println("pinged database")
+	utils.Logger.Info("pinged database")
 
-	return postgres{db: conn}, nil
+	return conn, nil
 }
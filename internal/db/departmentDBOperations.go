@@ -0,0 +1,274 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// departmentColumns is the column list scanned by scanDepartment
+const departmentColumns = `id, name, description, created_at, last_updated_at`
+
+// scanDepartment scans a row selected with departmentColumns
+func scanDepartment(row rowScanner, department *models.Department) error {
+	return row.Scan(&department.ID, &department.Name, &department.Description, &department.CreatedAt, &department.LastUpdatedAt)
+}
+
+// CreateDepartment function
+func (p postgres) CreateDepartment(ctx *gin.Context, department models.Department) (string, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	createErr := &employeeerror.EmployeeError{
+		Trace:   txid,
+		Code:    http.StatusInternalServerError,
+		Message: "unable to add department",
+	}
+
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return "", createErr
+	}
+	defer done()
+
+	query := `INSERT INTO departments (name, description, tenant_id) VALUES ($1, $2, $3) RETURNING id`
+	var departmentID int
+
+	err = q.QueryRowContext(ctx, query, department.Name, department.Description, tenantOf(ctx)).Scan(&departmentID)
+	if err == nil {
+		err = done()
+	}
+	if err != nil {
+		utils.Log(ctx).Error("error while running insert query", zap.Error(err))
+		if isPgError(err, pgUniqueViolation) {
+			return "", &employeeerror.EmployeeError{
+				Trace:   txid,
+				Code:    http.StatusConflict,
+				Message: "Department already exists",
+			}
+		}
+		return "", createErr
+	}
+
+	utils.Log(ctx).Info("successfully added department entry in db")
+	return strconv.Itoa(departmentID), nil
+}
+
+func (p postgres) GetDepartmentByID(ctx *gin.Context, departmentId string) (models.Department, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	deptId, err := strconv.Atoi(departmentId)
+	if err != nil {
+		return models.Department{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid department ID",
+			Trace:   txid,
+		}
+	}
+
+	// A department of another tenant is not found
+	query := `SELECT ` + departmentColumns + ` FROM departments WHERE id=$1 AND tenant_id=$2`
+
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.Department{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve department record",
+			Trace:   txid,
+		}
+	}
+	defer done()
+
+	var department models.Department
+	err = scanDepartment(q.QueryRowContext(ctx, query, deptId, tenantOf(ctx)), &department)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Department{}, &employeeerror.EmployeeError{
+				Code:    http.StatusNotFound,
+				Message: "Department not found",
+				Trace:   txid,
+			}
+		}
+		utils.Log(ctx).Error("Error executing query", zap.Int("department_id", deptId), zap.Error(err))
+		return models.Department{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve department record",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved department entry from db")
+	return department, nil
+}
+
+func (p postgres) UpdateDepartment(ctx *gin.Context, department models.Department) (models.Department, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	updateErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to update department record",
+		Trace:   txid,
+	}
+
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.Department{}, updateErr
+	}
+	defer done()
+
+	query := `UPDATE departments SET name=$1, description=$2, last_updated_at=$3 WHERE id=$4 AND tenant_id=$5 RETURNING ` + departmentColumns
+
+	var updated models.Department
+	err = scanDepartment(q.QueryRowContext(ctx, query, department.Name, department.Description, time.Now(), department.ID, tenantOf(ctx)), &updated)
+	if err == nil {
+		err = done()
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Department{}, &employeeerror.EmployeeError{
+				Code:    http.StatusNotFound,
+				Message: "Department not found",
+				Trace:   txid,
+			}
+		}
+		utils.Log(ctx).Error("Error executing update query", zap.Error(err))
+		if isPgError(err, pgUniqueViolation) {
+			return models.Department{}, &employeeerror.EmployeeError{
+				Code:    http.StatusConflict,
+				Message: "Department already exists",
+				Trace:   txid,
+			}
+		}
+		return models.Department{}, updateErr
+	}
+
+	utils.Log(ctx).Info("Successfully updated department entry in db")
+	return updated, nil
+}
+
+func (p postgres) DeleteDepartment(ctx *gin.Context, departmentId string) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	deptId, _ := strconv.Atoi(departmentId)
+
+	deleteErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to delete department record",
+		Trace:   txid,
+	}
+
+	tenant := tenantOf(ctx)
+	tx, err := p.beginTx(ctx, tenant, nil)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return deleteErr
+	}
+	defer tx.Rollback()
+
+	// Refuse to orphan employees, the foreign key would reject the delete anyway
+	var members int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM employees WHERE department_id=$1 AND tenant_id=$2 AND deleted_at IS NULL`, deptId, tenant).Scan(&members)
+	if err != nil {
+		utils.Log(ctx).Error("Error counting department members", zap.Int("department_id", deptId), zap.Error(err))
+		return deleteErr
+	}
+	if members > 0 {
+		return departmentNotEmptyError(txid, members)
+	}
+
+	// Soft-deleted employees do not keep a department alive, they lose it instead
+	if _, err := tx.ExecContext(ctx, `UPDATE employees SET department_id=NULL WHERE department_id=$1 AND tenant_id=$2 AND deleted_at IS NOT NULL`, deptId, tenant); err != nil {
+		utils.Log(ctx).Error("Error detaching deleted employees", zap.Int("department_id", deptId), zap.Error(err))
+		return deleteErr
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM departments WHERE id=$1 AND tenant_id=$2`, deptId, tenant); err != nil {
+		utils.Log(ctx).Error("Error executing delete query", zap.Int("department_id", deptId), zap.Error(err))
+		if isPgError(err, pgForeignKeyViolation) {
+			// An employee joined between the count and the delete
+			return departmentNotEmptyError(txid, 1)
+		}
+		return deleteErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing delete", zap.Error(err))
+		return deleteErr
+	}
+
+	utils.Log(ctx).Info("Successfully deleted department entry from db")
+	return nil
+}
+
+func departmentNotEmptyError(txid string, members int) *employeeerror.EmployeeError {
+	return &employeeerror.EmployeeError{
+		Code:    http.StatusConflict,
+		Message: fmt.Sprintf("Department still has %d employee(s), move them to another department before deleting it", members),
+		Trace:   txid,
+	}
+}
+
+func (p postgres) ListDepartments(ctx *gin.Context, page int, pageSize int) ([]models.Department, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	offset := (page - 1) * pageSize
+	query := `SELECT ` + departmentColumns + ` FROM departments WHERE tenant_id=$1 ORDER BY id LIMIT $2 OFFSET $3`
+
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve department records",
+			Trace:   txid,
+		}
+	}
+	defer done()
+
+	rows, err := q.QueryContext(ctx, query, tenantOf(ctx), pageSize, offset)
+	if err != nil {
+		utils.Log(ctx).Error("Error executing query", zap.Error(err))
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve department records",
+			Trace:   txid,
+		}
+	}
+	defer rows.Close()
+
+	var departments []models.Department
+	for rows.Next() {
+		var department models.Department
+		if err := scanDepartment(rows, &department); err != nil {
+			utils.Log(ctx).Error("Error scanning row", zap.Error(err))
+			return nil, &employeeerror.EmployeeError{
+				Code:    http.StatusInternalServerError,
+				Message: "Error processing department records",
+				Trace:   txid,
+			}
+		}
+		departments = append(departments, department)
+	}
+	if err := rows.Err(); err != nil {
+		utils.Log(ctx).Error("Error iterating over rows", zap.Error(err))
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Error processing department records",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved department records from db", zap.Int("page", page), zap.Int("page_size", pageSize))
+	return departments, nil
+}
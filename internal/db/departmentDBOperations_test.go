@@ -0,0 +1,116 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDepartment_Duplicate(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectQuery(`INSERT INTO departments \(name, description, tenant_id\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
+		WithArgs("Payments", "", "default").
+		WillReturnError(&pgconn.PgError{Code: pgUniqueViolation})
+
+	_, employeeErr := p.CreateDepartment(newTestContext(), models.Department{Name: "Payments"})
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusConflict, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteDepartment_WithMembers(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM employees WHERE department_id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL`).
+		WithArgs(3, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	employeeErr := p.DeleteDepartment(newTestContext(), "3")
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusConflict, employeeErr.Code)
+	assert.Contains(t, employeeErr.Message, "2 employee(s)")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteDepartment_Empty(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM employees WHERE department_id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL`).
+		WithArgs(3, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`UPDATE employees SET department_id=NULL WHERE department_id=\$1 AND tenant_id=\$2 AND deleted_at IS NOT NULL`).
+		WithArgs(3, "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM departments WHERE id=\$1 AND tenant_id=\$2`).
+		WithArgs(3, "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(t, p.DeleteDepartment(newTestContext(), "3"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInMemory_Departments(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	departmentID, employeeErr := m.CreateDepartment(ctx, models.Department{Name: "Payments"})
+	assert.Nil(t, employeeErr)
+
+	_, employeeErr = m.CreateDepartment(ctx, models.Department{Name: "Payments"})
+	assert.Equal(t, http.StatusConflict, employeeErr.Code)
+
+	salary := usd("50000")
+	unknown := "42"
+	_, employeeErr = m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary, DepartmentID: &unknown})
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+
+	_, employeeErr = m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary, DepartmentID: &departmentID})
+	assert.Nil(t, employeeErr)
+	_, _ = m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Engineer", Salary: salary})
+
+	members, employeeErr := m.ListEmployee(ctx, models.EmployeeQuery{DepartmentID: &departmentID, Page: 1, PageSize: 10})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, 1, members.TotalCount)
+	assert.Equal(t, "John Doe", members.Employees[0].Name)
+
+	employeeErr = m.DeleteDepartment(ctx, departmentID)
+	assert.Equal(t, http.StatusConflict, employeeErr.Code)
+
+	assert.Nil(t, m.DeleteEmployee(ctx, members.Employees[0].ID, 0))
+	assert.Nil(t, m.DeleteDepartment(ctx, departmentID))
+
+	_, employeeErr = m.GetDepartmentByID(ctx, departmentID)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+}
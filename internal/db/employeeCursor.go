@@ -0,0 +1,189 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errInvalidCursor is returned for cursors that cannot be decoded
+var errInvalidCursor = errors.New("invalid cursor, request the first page again without a cursor")
+
+// employeeCursor is the decoded form of the opaque cursor handed out as next_cursor.
+// It records the sort order it was issued for and the sort key values of the last employee
+// of the page, the next page starts right after that key.
+type employeeCursor struct {
+	Sort string            `json:"s"`
+	Keys map[string]string `json:"k"`
+}
+
+// sortSignature renders sort keys the way the sort query parameter spells them
+func sortSignature(keys []models.SortField) string {
+	fields := make([]string, len(keys))
+	for i, key := range keys {
+		fields[i] = key.Field
+		if key.Descending {
+			fields[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// encodeEmployeeCursor returns the cursor of the page following employee
+func encodeEmployeeCursor(employee models.Employee, sort []models.SortField) string {
+	keys := employeeSortKeys(sort)
+	cursor := employeeCursor{Sort: sortSignature(keys), Keys: make(map[string]string, len(keys))}
+	for _, key := range keys {
+		switch key.Field {
+		case "id":
+			cursor.Keys[key.Field] = employee.ID
+		case "name":
+			cursor.Keys[key.Field] = employee.Name
+		case "position":
+			cursor.Keys[key.Field] = employee.Position
+		case "salary":
+			if employee.Salary != nil {
+				cursor.Keys[key.Field] = employee.Salary.Amount.String()
+			}
+		case "created_at":
+			cursor.Keys[key.Field] = employee.CreatedAt.Format(time.RFC3339Nano)
+		case "last_updated_at":
+			cursor.Keys[key.Field] = employee.LastUpdatedAt.Format(time.RFC3339Nano)
+		}
+	}
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// parseEmployeeCursor reads the opaque cursor handed out as next_cursor
+func parseEmployeeCursor(value string) (employeeCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return employeeCursor{}, errInvalidCursor
+	}
+	var cursor employeeCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return employeeCursor{}, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+// CursorSort returns the sort order a cursor of the employee listing was issued for, which the
+// page following the cursor is read in when the request gives none
+func CursorSort(value string) ([]models.SortField, error) {
+	cursor, err := parseEmployeeCursor(value)
+	if err != nil {
+		return nil, err
+	}
+	var sort []models.SortField
+	for _, field := range strings.Split(cursor.Sort, ",") {
+		key := models.SortField{Field: strings.TrimPrefix(field, "-"), Descending: strings.HasPrefix(field, "-")}
+		if _, ok := employeeSortColumns[key.Field]; !ok {
+			return nil, errInvalidCursor
+		}
+		sort = append(sort, key)
+	}
+	return sort, nil
+}
+
+// decodeEmployeeCursor returns an employee holding the sort key values recorded in the cursor,
+// and the sort order the page is read in: the given one, which must be the one the cursor was
+// issued for, or the one of the cursor when none is given.
+func decodeEmployeeCursor(value string, sort []models.SortField) (models.Employee, []models.SortField, error) {
+	cursor, err := parseEmployeeCursor(value)
+	if err != nil {
+		return models.Employee{}, nil, err
+	}
+
+	if len(sort) == 0 {
+		if sort, err = CursorSort(value); err != nil {
+			return models.Employee{}, nil, err
+		}
+	}
+	keys := employeeSortKeys(sort)
+	if cursor.Sort != sortSignature(keys) {
+		return models.Employee{}, nil, errors.New("cursor was issued for a different sort order")
+	}
+
+	var employee models.Employee
+	for _, key := range keys {
+		value, ok := cursor.Keys[key.Field]
+		if !ok {
+			return models.Employee{}, nil, errInvalidCursor
+		}
+		switch key.Field {
+		case "id":
+			if _, err = strconv.Atoi(value); err == nil {
+				employee.ID = value
+			}
+		case "name":
+			employee.Name = value
+		case "position":
+			employee.Position = value
+		case "salary":
+			var amount models.Decimal
+			if amount, err = models.ParseDecimal(value); err == nil {
+				employee.Salary = &models.Money{Amount: amount}
+			}
+		case "created_at":
+			employee.CreatedAt, err = time.Parse(time.RFC3339Nano, value)
+		case "last_updated_at":
+			employee.LastUpdatedAt, err = time.Parse(time.RFC3339Nano, value)
+		}
+		if err != nil {
+			return models.Employee{}, nil, errInvalidCursor
+		}
+	}
+	return employee, sort, nil
+}
+
+// employeeSortValue returns the value of a sort field as a query argument
+func employeeSortValue(employee models.Employee, field string) interface{} {
+	switch field {
+	case "id":
+		id, _ := strconv.Atoi(employee.ID)
+		return id
+	case "name":
+		return employee.Name
+	case "position":
+		return employee.Position
+	case "salary":
+		return employee.Salary.Amount
+	case "created_at":
+		return employee.CreatedAt
+	case "last_updated_at":
+		return employee.LastUpdatedAt
+	}
+	return nil
+}
+
+// buildEmployeeKeyset returns the condition selecting the employees that come after the cursor
+// employee in the sort order, with placeholders numbered from firstArg. Sort directions may be
+// mixed, so the row comparison is spelled out key by key:
+//
+//	(k1 > $1) OR (k1 = $1 AND k2 < $2) OR (k1 = $1 AND k2 = $2 AND id > $3)
+func buildEmployeeKeyset(sort []models.SortField, after models.Employee, firstArg int) (string, []interface{}) {
+	keys := employeeSortKeys(sort)
+	args := make([]interface{}, len(keys))
+	var alternatives []string
+	for i, key := range keys {
+		args[i] = employeeSortValue(after, key.Field)
+
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = $%d", employeeSortColumns[keys[j].Field], firstArg+j))
+		}
+		operator := ">"
+		if key.Descending {
+			operator = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s $%d", employeeSortColumns[key.Field], operator, firstArg+i))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
@@ -5,7 +5,9 @@ import (
 	employeeerror "assignment/internal/errors"
 	"assignment/internal/models"
 	"assignment/internal/utils"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -13,61 +15,549 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
 )
 
+// employeeColumnList is the list of columns scanned by scanEmployee
+var employeeColumnList = []string{"id", "name", "position", "salary", "salary_currency", "department_id", "manager_id", "created_at", "last_updated_at", "version", "deleted_at"}
+
+// employeeColumns is the column list scanned by scanEmployee
+var employeeColumns = strings.Join(employeeColumnList, ", ")
+
+// employeeColumnsOf qualifies the columns scanned by scanEmployee with a table alias
+func employeeColumnsOf(alias string) string {
+	columns := make([]string, len(employeeColumnList))
+	for i, column := range employeeColumnList {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEmployee scans a row selected with employeeColumns, followed by any extra columns
+func scanEmployee(row rowScanner, employee *models.Employee, extra ...interface{}) error {
+	var salary *models.Decimal
+	var currency string
+	dest := []interface{}{&employee.ID, &employee.Name, &employee.Position, &salary, &currency, &employee.DepartmentID, &employee.ManagerID, &employee.CreatedAt, &employee.LastUpdatedAt, &employee.Version, &employee.DeletedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	employee.Salary = nil
+	if salary != nil {
+		employee.Salary = &models.Money{Amount: *salary, Currency: currency}
+	}
+	return nil
+}
+
+// salaryValues returns the values of the salary and salary_currency columns for a salary
+func salaryValues(salary *models.Money) (interface{}, string) {
+	if salary == nil {
+		return nil, constants.DefaultCurrency
+	}
+	return salary.Amount, salary.Currency
+}
+
+// newSalaryChange records a salary as an entry of the salary history
+func newSalaryChange(employeeID string, salary *models.Money, effectiveFrom time.Time, reason string) models.SalaryChange {
+	change := models.SalaryChange{
+		EmployeeID:    employeeID,
+		Currency:      constants.DefaultCurrency,
+		EffectiveFrom: effectiveFrom,
+		Reason:        reason,
+	}
+	if salary != nil {
+		amount := salary.Amount
+		change.Amount, change.Currency = &amount, salary.Currency
+	}
+	return change
+}
+
+// isPgError reports whether err is a postgres error with the given SQLSTATE code
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+// referenceError maps a foreign key violation on employees to the reference that does not exist,
+// it returns nil for any other error
+func referenceError(err error, txid string) *employeeerror.EmployeeError {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != pgForeignKeyViolation {
+		return nil
+	}
+	message := "Department not found"
+	if strings.Contains(pgErr.ConstraintName, "manager_id") {
+		message = "Manager not found"
+	}
+	return &employeeerror.EmployeeError{
+		Code:    http.StatusBadRequest,
+		Message: message,
+		Trace:   txid,
+	}
+}
+
 // CreateEmployee function
 func (p postgres) CreateEmployee(ctx *gin.Context, employee models.Employee) (string, *employeeerror.EmployeeError) {
 	txid := ctx.Request.Header.Get(constants.TransactionID)
 
-	query := `INSERT INTO employees (name, position, salary) VALUES ($1, $2, $3) RETURNING id`
-	var employeeID int
+	createErr := &employeeerror.EmployeeError{
+		Trace:   txid,
+		Code:    http.StatusInternalServerError,
+		Message: "unable to add employee",
+	}
 
-	err := p.db.QueryRowContext(ctx, query, employee.Name, employee.Position, employee.Salary).Scan(&employeeID)
+	// The employee and its audit entry are written together
+	tx, err := p.beginTx(ctx, tenantOf(ctx), nil)
 	if err != nil {
-		fmt.Printf("error while running insert query, txid: %v\n", txid)
-		return "", &employeeerror.EmployeeError{
-			Trace:   txid,
-			Code:    http.StatusInternalServerError,
-			Message: "unable to add employee",
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return "", createErr
+	}
+	defer tx.Rollback()
+
+	created, insertErr := insertEmployee(ctx, tx, employee)
+	if insertErr != nil {
+		return "", insertErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing insert", zap.Error(err))
+		return "", createErr
+	}
+
+	utils.Log(ctx).Info("successfully added employee entry in db")
+	return created.ID, nil
+}
+
+// insertEmployee adds an employee in the transaction q along with their starting salary and
+// the audit entry of the creation
+func insertEmployee(ctx *gin.Context, q queryer, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	createErr := &employeeerror.EmployeeError{
+		Trace:   txid,
+		Code:    http.StatusInternalServerError,
+		Message: "unable to add employee",
+	}
+
+	if employee.ManagerID != nil {
+		if managerErr := checkManagerActive(ctx, q, *employee.ManagerID); managerErr != nil {
+			return models.Employee{}, managerErr
+		}
+	}
+
+	query := `INSERT INTO employees (name, position, salary, salary_currency, department_id, manager_id, tenant_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ` + employeeColumns
+	var created models.Employee
+
+	salary, currency := salaryValues(employee.Salary)
+	err := scanEmployee(q.QueryRowContext(ctx, query, employee.Name, employee.Position, salary, currency, employee.DepartmentID, employee.ManagerID, tenantOf(ctx)), &created)
+	if err != nil {
+		utils.Log(ctx).Error("error while running insert query", zap.Error(err))
+		if referenceErr := referenceError(err, txid); referenceErr != nil {
+			return models.Employee{}, referenceErr
 		}
+		return models.Employee{}, createErr
 	}
 
-	id := strconv.Itoa(employeeID)
-	utils.Logger.Info(fmt.Sprintf("successfully added employee entry in db, txid: %v\n", txid))
-	return id, nil
+	// The starting salary is the first entry of the salary history
+	hire := newSalaryChange(created.ID, created.Salary, created.CreatedAt, models.SalaryReasonHire)
+	if err := insertSalaryChange(ctx, q, tenantOf(ctx), &hire); err != nil {
+		utils.Log(ctx).Error("Error recording salary history", zap.Error(err))
+		return models.Employee{}, createErr
+	}
+
+	if err := insertAudit(ctx, q, requestAuditEntry(ctx, models.AuditCreate, nil, &created)); err != nil {
+		utils.Log(ctx).Error("Error writing audit entry", zap.Error(err))
+		return models.Employee{}, createErr
+	}
+	return created, nil
 }
 
-func (p postgres) DeleteEmployee(ctx *gin.Context, employeeId string) *employeeerror.EmployeeError {
+// DeleteEmployee soft deletes an employee by setting deleted_at, the row is only removed for good
+// by PurgeEmployees once the retention period is over
+func (p postgres) DeleteEmployee(ctx *gin.Context, employeeId string, version int) *employeeerror.EmployeeError {
 	txid := ctx.Request.Header.Get(constants.TransactionID)
 
 	// Convert employeeId to integer and handle any errors
 	empId, _ := strconv.Atoi(employeeId)
-	fmt.Println("empId : ", empId)
-	// SQL query to delete employee by ID
-	query := `DELETE FROM employees WHERE id=$1`
 
-	// Execute the query
-	if _, err := p.db.ExecContext(ctx, query, empId); err != nil {
-		fmt.Println("Error executing delete query, empId:", empId, "error:", err)
-		return &employeeerror.EmployeeError{
+	deleteErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to delete employee record",
+		Trace:   txid,
+	}
+
+	tx, err := p.beginTx(ctx, tenantOf(ctx), nil)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return deleteErr
+	}
+	defer tx.Rollback()
+
+	// Holding the hierarchy lock keeps a concurrent manager change from attaching a report
+	// to the employee after its reports were detached
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, hierarchyLockID); err != nil {
+		utils.Log(ctx).Error("Error acquiring hierarchy lock", zap.Error(err))
+		return deleteErr
+	}
+
+	before, found, lockErr := lockActiveEmployee(ctx, tx, empId)
+	if lockErr != nil {
+		return lockErr
+	}
+	// Like a hard delete, deleting an unknown or already deleted ID is not an error
+	if !found {
+		return nil
+	}
+	// Only delete the expected version, unless it is 0
+	if version != 0 && before.Version != version {
+		return versionMismatchError(txid)
+	}
+
+	if _, softDeleteErr := softDeleteEmployee(ctx, tx, before); softDeleteErr != nil {
+		return softDeleteErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing delete", zap.Error(err))
+		return deleteErr
+	}
+
+	utils.Log(ctx).Info("Successfully deleted employee entry from db")
+	return nil
+}
+
+// softDeleteEmployee soft deletes an employee locked by lockActiveEmployee and detaches its
+// reports, recording each change in the audit trail
+func softDeleteEmployee(ctx *gin.Context, tx *sql.Tx, before models.Employee) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	deleteErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to delete employee record",
+		Trace:   txid,
+	}
+
+	// SQL query to soft delete employee by ID
+	empId, _ := strconv.Atoi(before.ID)
+	var after models.Employee
+	tenant := tenantOf(ctx)
+	err := scanEmployee(tx.QueryRowContext(ctx, `UPDATE employees SET deleted_at=$2 WHERE id=$1 AND tenant_id=$3 RETURNING `+employeeColumns, empId, time.Now(), tenant), &after)
+	if err != nil {
+		utils.Log(ctx).Error("Error executing delete query", zap.Error(err))
+		return models.Employee{}, deleteErr
+	}
+	if err := insertAudit(ctx, tx, requestAuditEntry(ctx, models.AuditDelete, &before, &after)); err != nil {
+		utils.Log(ctx).Error("Error writing audit entry", zap.Error(err))
+		return models.Employee{}, deleteErr
+	}
+
+	// The reports of a deleted employee no longer have a manager, as with the former hard delete
+	rows, err := tx.QueryContext(ctx, `UPDATE employees SET manager_id=NULL WHERE manager_id=$1 AND tenant_id=$2 RETURNING `+employeeColumns, empId, tenant)
+	if err != nil {
+		utils.Log(ctx).Error("Error detaching reports", zap.Error(err))
+		return models.Employee{}, deleteErr
+	}
+	var reports []models.Employee
+	for rows.Next() {
+		var report models.Employee
+		if err := scanEmployee(rows, &report); err != nil {
+			rows.Close()
+			utils.Log(ctx).Error("Error scanning report", zap.Error(err))
+			return models.Employee{}, deleteErr
+		}
+		reports = append(reports, report)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		utils.Log(ctx).Error("Error detaching reports", zap.Error(err))
+		return models.Employee{}, deleteErr
+	}
+
+	// Each detached report is recorded as an update of its own
+	for _, report := range reports {
+		previous := report
+		previous.ManagerID = &after.ID
+		previous.Version--
+		if err := insertAudit(ctx, tx, requestAuditEntry(ctx, models.AuditUpdate, &previous, &report)); err != nil {
+			utils.Log(ctx).Error("Error writing audit entry", zap.Error(err))
+			return models.Employee{}, deleteErr
+		}
+	}
+	return after, nil
+}
+
+// lockActiveEmployee reads an employee of the tenant of the request that is not soft deleted and
+// locks its row until the end of the transaction, found is false when there is no such employee
+func lockActiveEmployee(ctx *gin.Context, q queryer, empId int) (employee models.Employee, found bool, employeeErr *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	err := scanEmployee(q.QueryRowContext(ctx, `SELECT `+employeeColumns+` FROM employees WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL FOR UPDATE`, empId, tenantOf(ctx)), &employee)
+	if err == sql.ErrNoRows {
+		return models.Employee{}, false, nil
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error reading employee", zap.Error(err))
+		return models.Employee{}, false, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve employee record",
+			Trace:   txid,
+		}
+	}
+	return employee, true, nil
+}
+
+// RestoreEmployee undoes the soft delete of an employee
+func (p postgres) RestoreEmployee(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	empId, _ := strconv.Atoi(employeeId)
+
+	restoreErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to restore employee record",
+		Trace:   txid,
+	}
+
+	tenant := tenantOf(ctx)
+	tx, err := p.beginTx(ctx, tenant, nil)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.Employee{}, restoreErr
+	}
+	defer tx.Rollback()
+
+	var before models.Employee
+	err = scanEmployee(tx.QueryRowContext(ctx, `SELECT `+employeeColumns+` FROM employees WHERE id=$1 AND tenant_id=$2 FOR UPDATE`, empId, tenant), &before)
+	if err == sql.ErrNoRows {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error executing query", zap.Error(err))
+		return models.Employee{}, restoreErr
+	}
+	if before.DeletedAt == nil {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusConflict,
+			Message: "Employee is not deleted",
+			Trace:   txid,
+		}
+	}
+
+	var employee models.Employee
+	err = scanEmployee(tx.QueryRowContext(ctx, `UPDATE employees SET deleted_at=NULL WHERE id=$1 AND tenant_id=$2 RETURNING `+employeeColumns, empId, tenant), &employee)
+	if err != nil {
+		utils.Log(ctx).Error("Error executing restore query", zap.Error(err))
+		return models.Employee{}, restoreErr
+	}
+	if err := insertAudit(ctx, tx, requestAuditEntry(ctx, models.AuditRestore, &before, &employee)); err != nil {
+		utils.Log(ctx).Error("Error writing audit entry", zap.Error(err))
+		return models.Employee{}, restoreErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing restore", zap.Error(err))
+		return models.Employee{}, restoreErr
+	}
+
+	utils.Log(ctx).Info("Successfully restored employee entry in db")
+	return employee, nil
+}
+
+// PurgeEmployees permanently removes the employees of every tenant soft deleted before the given
+// time and returns how many were removed. Each removal, and each report detached from a purged
+// manager, is audited under the given actor and transaction.
+func (p postgres) PurgeEmployees(ctx context.Context, deletedBefore time.Time, actor string, transactionID string) (int64, error) {
+	return p.purgeEmployees(ctx, allTenants, deletedBefore, actor, transactionID)
+}
+
+// PurgeTenantEmployees is PurgeEmployees for the employees of the tenant of the request, audited
+// under the actor and transaction of the request
+func (p postgres) PurgeTenantEmployees(ctx *gin.Context, deletedBefore time.Time) (int64, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	purged, err := p.purgeEmployees(ctx, tenantOf(ctx), deletedBefore, actorOf(ctx), txid)
+	if err != nil {
+		utils.Log(ctx).Error("Error purging employees", zap.Error(err))
+		return 0, &employeeerror.EmployeeError{
 			Code:    http.StatusInternalServerError,
-			Message: "Unable to delete employee record",
+			Message: "Unable to purge employee records",
 			Trace:   txid,
 		}
 	}
 
-	utils.Logger.Info(fmt.Sprintf("Successfully deleted employee entry from db, txid: %v\n", txid))
+	utils.Log(ctx).Info("Successfully purged employee entries from db", zap.Int64("purged", purged))
+	return purged, nil
+}
+
+// purgeEmployees purges the employees of the given tenant, allTenants for the background job
+func (p postgres) purgeEmployees(ctx context.Context, tenant string, deletedBefore time.Time, actor string, transactionID string) (int64, error) {
+	tx, err := p.beginTx(ctx, tenant, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{deletedBefore}
+	managerFilter, employeeFilter := "", ""
+	if tenant != allTenants {
+		args = append(args, tenant)
+		managerFilter, employeeFilter = " AND m.tenant_id=$2", " AND tenant_id=$2"
+	}
+
+	// The reports of a purged manager are left without one, which the foreign key of manager_id
+	// cannot do as it also holds the tenant. The manager is returned for the audit trail, as
+	// manager_id is already cleared in the returned row.
+	rows, err := tx.QueryContext(ctx, `UPDATE employees AS e SET manager_id=NULL FROM employees AS m WHERE e.manager_id=m.id AND e.tenant_id=m.tenant_id AND m.deleted_at IS NOT NULL AND m.deleted_at < $1`+managerFilter+` RETURNING `+employeeColumnsOf("e")+`, e.tenant_id, m.id`, args...)
+	if err != nil {
+		return 0, err
+	}
+	var detached []models.AuditEntry
+	for rows.Next() {
+		var report models.Employee
+		var tenant, managerID string
+		if err := scanEmployee(rows, &report, &tenant, &managerID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		// Each detached report is recorded as an update of its own
+		previous := report
+		previous.ManagerID = &managerID
+		previous.Version--
+		entry := newAuditEntry(models.AuditUpdate, &previous, &report, actor, transactionID)
+		entry.TenantID = tenant
+		detached = append(detached, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, entry := range detached {
+		if err := insertAudit(ctx, tx, entry); err != nil {
+			return 0, err
+		}
+	}
+
+	rows, err = tx.QueryContext(ctx, `DELETE FROM employees WHERE deleted_at IS NOT NULL AND deleted_at < $1`+employeeFilter+` RETURNING `+employeeColumns+`, tenant_id`, args...)
+	if err != nil {
+		return 0, err
+	}
+	var purged []models.Employee
+	var tenants []string
+	for rows.Next() {
+		var employee models.Employee
+		var tenant string
+		if err := scanEmployee(rows, &employee, &tenant); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		purged = append(purged, employee)
+		tenants = append(tenants, tenant)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, employee := range purged {
+		entry := newAuditEntry(models.AuditPurge, &employee, nil, actor, transactionID)
+		entry.TenantID = tenants[i]
+		if err := insertAudit(ctx, tx, entry); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(purged)), nil
+}
+
+// CountActiveEmployees returns the number of employees that are not soft deleted, by tenant
+func (p postgres) CountActiveEmployees(ctx context.Context) (map[string]int64, error) {
+	q, done, err := p.scopeTo(ctx, allTenants)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rows, err := q.QueryContext(ctx, `SELECT tenant_id, COUNT(*) FROM employees WHERE deleted_at IS NULL GROUP BY tenant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var tenant string
+		var count int64
+		if err := rows.Scan(&tenant, &count); err != nil {
+			return nil, err
+		}
+		counts[tenant] = count
+	}
+	return counts, rows.Err()
+}
+
+// checkManagerActive rejects a manager that does not exist, is soft deleted or belongs to another
+// tenant, the foreign key alone would accept them
+func checkManagerActive(ctx *gin.Context, q queryer, managerId string) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	var active bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM employees WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL)`, managerId, tenantOf(ctx)).Scan(&active)
+	if err != nil {
+		utils.Log(ctx).Error("Error checking manager", zap.String("manager_id", managerId), zap.Error(err))
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to verify manager",
+			Trace:   txid,
+		}
+	}
+	if !active {
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "Manager not found",
+			Trace:   txid,
+		}
+	}
 	return nil
 }
 
+func versionMismatchError(txid string) *employeeerror.EmployeeError {
+	return &employeeerror.EmployeeError{
+		Code:    http.StatusPreconditionFailed,
+		Message: "Employee was modified by someone else, fetch it again to get its current ETag",
+		Trace:   txid,
+	}
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
 func (p postgres) GetEmployeeByID(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
 	txid := ctx.Request.Header.Get(constants.TransactionID)
-	fmt.Println("employeeId:", employeeId)
 
 	// Convert employeeId to integer and handle any errors
 	empId, err := strconv.Atoi(employeeId)
 	if err != nil {
-		fmt.Println("Error converting employee ID to integer:", err)
+		utils.Log(ctx).Error("Error converting employee ID to integer", zap.Error(err))
 		return models.Employee{}, &employeeerror.EmployeeError{
 			Code:    http.StatusBadRequest,
 			Message: "Invalid employee ID",
@@ -75,12 +565,23 @@ func (p postgres) GetEmployeeByID(ctx *gin.Context, employeeId string) (models.E
 		}
 	}
 
-	// SQL query to get employee by ID
-	query := `SELECT id, name, position, salary, created_at, last_updated_at FROM employees WHERE id=$1`
+	// SQL query to get employee by ID, an employee of another tenant is not found
+	query := `SELECT ` + employeeColumns + ` FROM employees WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL`
+
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve employee record",
+			Trace:   txid,
+		}
+	}
+	defer done()
 
 	// Prepare to scan the result into an Employee struct
 	employee := &models.Employee{}
-	err = p.db.QueryRowContext(ctx, query, empId).Scan(&employee.ID, &employee.Name, &employee.Position, &employee.Salary, &employee.CreatedAt, &employee.LastUpdatedAt)
+	err = scanEmployee(q.QueryRowContext(ctx, query, empId, tenantOf(ctx)), employee)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.Employee{}, &employeeerror.EmployeeError{
@@ -89,7 +590,7 @@ func (p postgres) GetEmployeeByID(ctx *gin.Context, employeeId string) (models.E
 				Trace:   txid,
 			}
 		}
-		fmt.Println("Error executing query, empId:", empId, "error:", err)
+		utils.Log(ctx).Error("Error executing query", zap.Error(err))
 		return models.Employee{}, &employeeerror.EmployeeError{
 			Code:    http.StatusInternalServerError,
 			Message: "Unable to retrieve employee record",
@@ -97,100 +598,283 @@ func (p postgres) GetEmployeeByID(ctx *gin.Context, employeeId string) (models.E
 		}
 	}
 
-	utils.Logger.Info(fmt.Sprintf("Successfully retrieved employee entry from db, txid: %v\n", txid))
+	utils.Log(ctx).Info("Successfully retrieved employee entry from db")
 	return *employee, nil
 }
 
 func (p postgres) UpdateEmployee(ctx *gin.Context, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
 	txid := ctx.Request.Header.Get(constants.TransactionID)
 
+	// If no fields to update, return an error
+	if !hasEmployeeChanges(employee) {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "No fields to update",
+			Trace:   txid,
+		}
+	}
+
+	return p.writeEmployee(ctx, employee, false)
+}
+
+// ReplaceEmployee sets every field of an employee to the given value, a nil department or
+// manager clears it, unless the stored version differs from a non zero employee.Version
+func (p postgres) ReplaceEmployee(ctx *gin.Context, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
+	return p.writeEmployee(ctx, employee, true)
+}
+
+// writeEmployee runs updateEmployee in a transaction of its own
+func (p postgres) writeEmployee(ctx *gin.Context, employee models.Employee, replace bool) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	updateErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to update employee record",
+		Trace:   txid,
+	}
+
+	// The update and its audit entry are written together
+	tx, err := p.beginTx(ctx, tenantOf(ctx), nil)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.Employee{}, updateErr
+	}
+	defer tx.Rollback()
+
+	after, changeErr := updateEmployee(ctx, tx, employee, replace)
+	if changeErr != nil {
+		return models.Employee{}, changeErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing update", zap.Error(err))
+		return models.Employee{}, updateErr
+	}
+
+	utils.Log(ctx).Info("Successfully updated employee entry in db")
+	return after, nil
+}
+
+// hasEmployeeChanges reports whether an update sets at least one field of the employee
+func hasEmployeeChanges(employee models.Employee) bool {
+	return employee.Name != "" || employee.Position != "" || employee.Salary != nil || employee.DepartmentID != nil || employee.ManagerID != nil
+}
+
+// updateEmployee applies the fields set in employee to the stored employee, or all of them when
+// replace is set, unless its version differs from a non zero employee.Version. The change is
+// recorded in the audit trail and, for a new salary, in the salary history.
+func updateEmployee(ctx *gin.Context, tx *sql.Tx, employee models.Employee, replace bool) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
 	// Build the dynamic update query
 	var fields []string
 	var args []interface{}
 	argID := 1
 
-	if employee.Name != "" {
+	if replace || employee.Name != "" {
 		fields = append(fields, fmt.Sprintf("name=$%d", argID))
 		args = append(args, employee.Name)
 		argID++
 	}
-	if employee.Position != "" {
+	if replace || employee.Position != "" {
 		fields = append(fields, fmt.Sprintf("position=$%d", argID))
 		args = append(args, employee.Position)
 		argID++
 	}
 	if employee.Salary != nil {
-		fields = append(fields, fmt.Sprintf("salary=$%d", argID))
-		args = append(args, *employee.Salary)
+		fields = append(fields, fmt.Sprintf("salary=$%d, salary_currency=$%d", argID, argID+1))
+		args = append(args, employee.Salary.Amount, employee.Salary.Currency)
+		argID++
+		argID++
+	}
+	if replace || employee.DepartmentID != nil {
+		fields = append(fields, fmt.Sprintf("department_id=$%d", argID))
+		args = append(args, employee.DepartmentID)
+		argID++
+	}
+	if replace || employee.ManagerID != nil {
+		fields = append(fields, fmt.Sprintf("manager_id=$%d", argID))
+		args = append(args, employee.ManagerID)
 		argID++
 	}
 
-	// If no fields to update, return an error
-	if len(fields) == 0 {
+	// Add the last_updated_at field if there are other fields being updated
+	now := time.Now()
+	fields = append(fields, fmt.Sprintf("last_updated_at=$%d", argID))
+	args = append(args, now)
+	argID++
+
+	updateErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to update employee record",
+		Trace:   txid,
+	}
+
+	// Changing the manager holds the hierarchy lock,
+	// so that two concurrent changes cannot create a cycle together
+	if employee.ManagerID != nil {
+		if cycleErr := checkManagerCycle(ctx, tx, employee.ID, *employee.ManagerID); cycleErr != nil {
+			return models.Employee{}, cycleErr
+		}
+		if managerErr := checkManagerActive(ctx, tx, *employee.ManagerID); managerErr != nil {
+			return models.Employee{}, managerErr
+		}
+	}
+
+	empId, _ := strconv.Atoi(employee.ID)
+	before, found, lockErr := lockActiveEmployee(ctx, tx, empId)
+	if lockErr != nil {
+		return models.Employee{}, lockErr
+	}
+	if !found {
 		return models.Employee{}, &employeeerror.EmployeeError{
-			Code:    http.StatusBadRequest,
-			Message: "No fields to update",
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
 			Trace:   txid,
 		}
 	}
+	// Only update the expected version, unless it is 0
+	if employee.Version != 0 && before.Version != employee.Version {
+		return models.Employee{}, versionMismatchError(txid)
+	}
 
-	// Add the last_updated_at field if there are other fields being updated
-	fields = append(fields, fmt.Sprintf("last_updated_at=$%d", argID))
-	args = append(args, time.Now())
-	argID++
+	// The version is bumped by the employees_bump_version trigger
+	args = append(args, empId, tenantOf(ctx))
+	query := fmt.Sprintf("UPDATE employees SET %s WHERE id=$%d AND tenant_id=$%d RETURNING %s", strings.Join(fields, ", "), argID, argID+1, employeeColumns)
+	var after models.Employee
+	err := scanEmployee(tx.QueryRowContext(ctx, query, args...), &after)
+	if err != nil {
+		utils.Log(ctx).Error("Error executing update query", zap.Error(err))
+		if referenceErr := referenceError(err, txid); referenceErr != nil {
+			return models.Employee{}, referenceErr
+		}
+		return models.Employee{}, updateErr
+	}
+
+	// A new salary is appended to the salary history rather than losing the old one
+	if employee.Salary != nil && (before.Salary == nil || !before.Salary.Equal(*employee.Salary)) {
+		change := newSalaryChange(after.ID, after.Salary, now, models.SalaryReasonUpdate)
+		if err := insertSalaryChange(ctx, tx, tenantOf(ctx), &change); err != nil {
+			utils.Log(ctx).Error("Error recording salary history", zap.Error(err))
+			return models.Employee{}, updateErr
+		}
+	}
+
+	if err := insertAudit(ctx, tx, requestAuditEntry(ctx, models.AuditUpdate, &before, &after)); err != nil {
+		utils.Log(ctx).Error("Error writing audit entry", zap.Error(err))
+		return models.Employee{}, updateErr
+	}
+	return after, nil
+}
+
+func (p postgres) ListEmployee(ctx *gin.Context, query models.EmployeeQuery) (models.EmployeeList, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
 
-	// Add the ID to the arguments
-	args = append(args, employee.ID)
+	// A page following a cursor is read in the sort order of the cursor
+	var after models.Employee
+	if query.Cursor != "" {
+		var err error
+		if after, query.Sort, err = decodeEmployeeCursor(query.Cursor, query.Sort); err != nil {
+			return models.EmployeeList{}, &employeeerror.EmployeeError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+				Trace:   txid,
+			}
+		}
+	}
 
-	query := fmt.Sprintf("UPDATE employees SET %s WHERE id=$%d", strings.Join(fields, ", "), argID)
-	res, err := p.db.Exec(query, args...)
+	where, args := buildEmployeeFilter(tenantOf(ctx), query)
+	order, err := buildEmployeeOrder(query.Sort)
 	if err != nil {
-		fmt.Println("Error executing update query:", err)
-		return models.Employee{}, &employeeerror.EmployeeError{
-			Code:    http.StatusInternalServerError,
-			Message: "Unable to update employee record",
+		return models.EmployeeList{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
 			Trace:   txid,
 		}
 	}
 
-	// Check if any rows were affected
-	rowsAffected, err := res.RowsAffected()
+	q, done, err := p.scope(ctx)
 	if err != nil {
-		fmt.Println("Error checking rows affected:", err)
-		return models.Employee{}, &employeeerror.EmployeeError{
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.EmployeeList{}, &employeeerror.EmployeeError{
 			Code:    http.StatusInternalServerError,
-			Message: "Unable to verify update operation",
+			Message: "Unable to retrieve employee records",
 			Trace:   txid,
 		}
 	}
-	if rowsAffected == 0 {
-		return models.Employee{}, &employeeerror.EmployeeError{
-			Code:    http.StatusNotFound,
-			Message: "Employee not found",
+	defer done()
+
+	// Count every matching row so that clients can render page numbers
+	var totalCount int
+	if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM employees `+where, args...).Scan(&totalCount); err != nil {
+		utils.Log(ctx).Error("Error executing count query", zap.Error(err))
+		return models.EmployeeList{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve employee records",
 			Trace:   txid,
 		}
 	}
 
-	utils.Logger.Info(fmt.Sprintf("Successfully updated employee entry in db, txid: %v\n", txid))
-	return employee, nil
-}
+	// One row more than the page size tells whether another page follows
+	var listQuery string
+	if query.Cursor != "" {
+		// SQL query to list the employee records following the cursor
+		keyset, keysetArgs := buildEmployeeKeyset(query.Sort, after, len(args)+1)
+		where += " AND " + keyset
+		args = append(args, keysetArgs...)
+		listQuery = fmt.Sprintf(`SELECT %s
+               FROM employees %s
+               ORDER BY %s
+               LIMIT $%d`, employeeColumns, where, order, len(args)+1)
+		args = append(args, query.PageSize+1)
+	} else {
+		// Calculate the offset based on the page number and page size
+		offset := (query.Page - 1) * query.PageSize
 
-func (p postgres) ListEmployee(ctx *gin.Context, page int, pageSize int) ([]models.Employee, *employeeerror.EmployeeError) {
-	txid := ctx.Request.Header.Get(constants.TransactionID)
+		// SQL query to list employee records with pagination
+		listQuery = fmt.Sprintf(`SELECT %s
+               FROM employees %s
+               ORDER BY %s
+               LIMIT $%d OFFSET $%d`, employeeColumns, where, order, len(args)+1, len(args)+2)
+		args = append(args, query.PageSize+1, offset)
+	}
 
-	// Calculate the offset based on the page number and page size
-	offset := (page - 1) * pageSize
+	// Execute the query with the specified page size and offset
+	employees, employeeErr := queryEmployees(ctx, q, listQuery, args...)
+	if employeeErr != nil {
+		return models.EmployeeList{}, employeeErr
+	}
 
-	// SQL query to list employee records with pagination
-	query := `SELECT id, name, position, salary, created_at, last_updated_at 
-               FROM employees 
-               ORDER BY id 
-               LIMIT $1 OFFSET $2`
+	utils.Log(ctx).Info("Successfully retrieved employee records from db", zap.Int("page", query.Page), zap.Int("page_size", query.PageSize))
+	return employeePage(employees, totalCount, query), nil
+}
 
-	// Execute the query with the specified page size and offset
-	rows, err := p.db.QueryContext(ctx, query, pageSize, offset)
+// employeePage trims the extra row fetched past the page size and fills in the paging
+// fields of the list
+func employeePage(employees []models.Employee, totalCount int, query models.EmployeeQuery) models.EmployeeList {
+	list := models.EmployeeList{
+		Employees:  employees,
+		TotalCount: totalCount,
+		PageSize:   query.PageSize,
+	}
+	if query.Cursor == "" {
+		list.Page = query.Page
+	}
+	if query.PageSize > 0 && len(employees) > query.PageSize {
+		list.Employees = employees[:query.PageSize]
+		list.HasMore = true
+		list.NextCursor = encodeEmployeeCursor(list.Employees[len(list.Employees)-1], query.Sort)
+	}
+	return list
+}
+
+// queryEmployees runs a query selecting employeeColumns on q and scans every row
+func queryEmployees(ctx *gin.Context, q queryer, query string, args ...interface{}) ([]models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
-		fmt.Println("Error executing query:", err)
+		utils.Log(ctx).Error("Error executing query", zap.Error(err))
 		return nil, &employeeerror.EmployeeError{
 			Code:    http.StatusInternalServerError,
 			Message: "Unable to retrieve employee records",
@@ -199,12 +883,11 @@ func (p postgres) ListEmployee(ctx *gin.Context, page int, pageSize int) ([]mode
 	}
 	defer rows.Close()
 
-	// Iterate over the rows and scan the results into Employee structs
 	var employees []models.Employee
 	for rows.Next() {
 		var employee models.Employee
-		if err := rows.Scan(&employee.ID, &employee.Name, &employee.Position, &employee.Salary, &employee.CreatedAt, &employee.LastUpdatedAt); err != nil {
-			fmt.Println("Error scanning row:", err)
+		if err := scanEmployee(rows, &employee); err != nil {
+			utils.Log(ctx).Error("Error scanning row", zap.Error(err))
 			return nil, &employeeerror.EmployeeError{
 				Code:    http.StatusInternalServerError,
 				Message: "Error processing employee records",
@@ -213,17 +896,13 @@ func (p postgres) ListEmployee(ctx *gin.Context, page int, pageSize int) ([]mode
 		}
 		employees = append(employees, employee)
 	}
-
-	// Check for any errors encountered during iteration
 	if err := rows.Err(); err != nil {
-		fmt.Println("Error iterating over rows:", err)
+		utils.Log(ctx).Error("Error iterating over rows", zap.Error(err))
 		return nil, &employeeerror.EmployeeError{
 			Code:    http.StatusInternalServerError,
 			Message: "Error processing employee records",
 			Trace:   txid,
 		}
 	}
-
-	utils.Logger.Info(fmt.Sprintf("Successfully retrieved employee records from db (page %d, pageSize %d), txid: %v\n", page, pageSize, txid))
 	return employees, nil
 }
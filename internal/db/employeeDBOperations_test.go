@@ -4,8 +4,12 @@ import (
 	"assignment/internal/constants"
 	"assignment/internal/models"
 	"assignment/internal/utils"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"regexp"
 	"testing"
 	"time"
 
@@ -37,11 +41,16 @@ func TestCreateEmployee_Success(t *testing.T) {
 	}
 
 	// Set up the expected SQL query and result
-	var salary float64 = 50000.0
-	employee := models.Employee{Name: "John Doe", Position: "Engineer", Salary: &salary}
-	mock.ExpectQuery(`INSERT INTO employees \(name, position, salary\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
-		WithArgs(employee.Name, employee.Position, employee.Salary).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	salary := usd("50000")
+	employee := models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary}
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO employees \(name, position, salary, salary_currency, department_id, manager_id, tenant_id\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(employee.Name, employee.Position, "50000", "USD", employee.DepartmentID, employee.ManagerID, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "John Doe", "Engineer", 50000.0, "USD", nil, nil, now, now, 1, nil))
+	expectSalaryChange(mock, "1", models.SalaryReasonHire)
+	expectAudit(mock, "1", models.AuditCreate)
+	mock.ExpectCommit()
 
 	// Call the CreateEmployee function
 	employeeID, employeeErr := p.CreateEmployee(ctx, employee)
@@ -72,11 +81,13 @@ func TestCreateEmployee_Error(t *testing.T) {
 	p := postgres{db: mockDB}
 
 	// Set up the expected SQL query to return an error
-	var salary float64 = 50000.0
-	employee := models.Employee{Name: "John Doe", Position: "Engineer", Salary: &salary}
-	mock.ExpectQuery(`INSERT INTO employees \(name, position, salary\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
-		WithArgs(employee.Name, employee.Position, employee.Salary).
+	salary := usd("50000")
+	employee := models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO employees \(name, position, salary, salary_currency, department_id, manager_id, tenant_id\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(employee.Name, employee.Position, "50000", "USD", employee.DepartmentID, employee.ManagerID, "default").
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
 	// Create a test context and request
 	ctx := &gin.Context{
@@ -109,19 +120,27 @@ func TestUpdateEmployee_Success(t *testing.T) {
 
 	// Create a new instance of the postgres struct with the mock database
 	p := postgres{db: mockDB}
-	var salary float64 = 50000.0
+	salary := usd("50000")
 	// Set up the expected SQL query and result
 	employee := models.Employee{
 		ID:       "1",
 		Name:     "Updated Name",
 		Position: "Updated Position",
-		Salary:   &salary, // Assuming salary is updated
+		Salary:   salary, // Assuming salary is updated
 	}
-	mock.ExpectExec(`UPDATE employees SET name=\$1, position=\$2, salary=\$3, last_updated_at=\$4 WHERE id=\$5`).
-		WithArgs(employee.Name, employee.Position, *employee.Salary, sqlmock.AnyArg(), employee.ID).
-		WillReturnResult(sqlmock.NewResult(0, 1)) // Indicating one row affected
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, now, now, 1, nil))
+	mock.ExpectQuery(`UPDATE employees SET name=\$1, position=\$2, salary=\$3, salary_currency=\$4, last_updated_at=\$5 WHERE id=\$6 AND tenant_id=\$7 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(employee.Name, employee.Position, "50000", "USD", sqlmock.AnyArg(), 1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, employee.Name, employee.Position, "50000.0000", "USD", nil, nil, now, now, 2, nil))
+	expectSalaryChange(mock, "1", models.SalaryReasonUpdate)
+	expectAudit(mock, "1", models.AuditUpdate)
+	mock.ExpectCommit()
 
-		// Create a test context and request
+	// Create a test context and request
 	ctx := &gin.Context{
 		Request: &http.Request{
 			Header: http.Header{
@@ -136,8 +155,11 @@ func TestUpdateEmployee_Success(t *testing.T) {
 	// Assert that there's no error
 	assert.Nil(t, employeeErr)
 
-	// Assert the returned employee
-	assert.Equal(t, employee, updatedEmployee)
+	// Assert the returned employee is the updated row, at the version written by the update
+	assert.Equal(t, employee.Name, updatedEmployee.Name)
+	assert.Equal(t, employee.Position, updatedEmployee.Position)
+	assert.Equal(t, salary, updatedEmployee.Salary)
+	assert.Equal(t, 2, updatedEmployee.Version)
 
 	// Assert that all expectations were met
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -191,19 +213,25 @@ func TestUpdateEmployee_Error(t *testing.T) {
 	// Create a new instance of the postgres struct with the mock database
 	p := postgres{db: mockDB}
 
-	var salary float64 = 50000.0
+	salary := usd("50000")
 	// Set up the expected SQL query to return an error
 	employee := models.Employee{
 		ID:       "1",
 		Name:     "Updated Name",
 		Position: "Updated Position",
-		Salary:   &salary,
+		Salary:   salary,
 	}
-	mock.ExpectExec(`UPDATE employees SET name=\$1, position=\$2, salary=\$3, last_updated_at=\$4 WHERE id=\$5`).
-		WithArgs(employee.Name, employee.Position, *employee.Salary, sqlmock.AnyArg(), employee.ID).
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, now, now, 1, nil))
+	mock.ExpectQuery(`UPDATE employees SET name=\$1, position=\$2, salary=\$3, salary_currency=\$4, last_updated_at=\$5 WHERE id=\$6 AND tenant_id=\$7 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(employee.Name, employee.Position, "50000", "USD", sqlmock.AnyArg(), 1, "default").
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-		// Set up a test context with a transaction ID
+	// Set up a test context with a transaction ID
 	ctx := &gin.Context{
 		Request: &http.Request{
 			Header: http.Header{
@@ -223,6 +251,35 @@ func TestUpdateEmployee_Error(t *testing.T) {
 	assert.Equal(t, "Unable to update employee record", employeeErr.Message)
 }
 
+func TestReplaceEmployee_ClearsOptionalFields(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// Every column is written, the department and manager left out of the employee become NULL
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", "50000", "USD", "2", "3", now, now, 1, nil))
+	mock.ExpectQuery(`UPDATE employees SET name=\$1, position=\$2, salary=\$3, salary_currency=\$4, department_id=\$5, manager_id=\$6, last_updated_at=\$7 WHERE id=\$8 AND tenant_id=\$9 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs("Name", "Position", "50000", "USD", nil, nil, sqlmock.AnyArg(), 1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", "50000", "USD", nil, nil, now, now, 2, nil))
+	expectAudit(mock, "1", models.AuditUpdate)
+	mock.ExpectCommit()
+
+	employee, employeeErr := p.ReplaceEmployee(newTestContext(), models.Employee{ID: "1", Name: "Name", Position: "Position", Salary: usd("50000"), Version: 1})
+	assert.Nil(t, employeeErr)
+	assert.Nil(t, employee.DepartmentID)
+	assert.Nil(t, employee.ManagerID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetEmployeeByID_Success(t *testing.T) {
 	// Initialize logger and create a mock database
 	utils.InitLogClient()
@@ -236,22 +293,23 @@ func TestGetEmployeeByID_Success(t *testing.T) {
 	p := postgres{db: mockDB}
 
 	// Define test data
-	var salary float64 = 50000.0
+	salary := usd("50000")
 	employeeID := "1"
 	expectedEmployee := models.Employee{
 		ID:            employeeID,
 		Name:          "John Doe",
 		Position:      "Engineer",
-		Salary:        &salary,
+		Salary:        salary,
 		CreatedAt:     time.Now(),
 		LastUpdatedAt: time.Now(),
+		Version:       3,
 	}
 
 	// Set up the expected SQL query and result
-	mock.ExpectQuery(`SELECT id, name, position, salary, created_at, last_updated_at FROM employees WHERE id=\$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary", "created_at", "last_updated_at"}).
-			AddRow(expectedEmployee.ID, expectedEmployee.Name, expectedEmployee.Position, expectedEmployee.Salary, expectedEmployee.CreatedAt, expectedEmployee.LastUpdatedAt))
+	mock.ExpectQuery(`SELECT id, name, position, salary, salary_currency, department_id, manager_id, created_at, last_updated_at, version, deleted_at FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary", "salary_currency", "department_id", "manager_id", "created_at", "last_updated_at", "version", "deleted_at"}).
+			AddRow(expectedEmployee.ID, expectedEmployee.Name, expectedEmployee.Position, "50000.0000", "USD", nil, nil, expectedEmployee.CreatedAt, expectedEmployee.LastUpdatedAt, expectedEmployee.Version, nil))
 
 	// Create a test context with a transaction ID
 	ctx := &gin.Context{
@@ -274,3 +332,335 @@ func TestGetEmployeeByID_Success(t *testing.T) {
 	// Assert that all expectations were met
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGetEmployeeByID_RowLevelSecurity(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB, rowLevelSecurity: true}
+
+	// The query runs in a transaction the policies limit to the tenant of the request
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.tenant_id', \$1, true\)`).
+		WithArgs("acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT .+ FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL`).
+		WithArgs(1, "acme").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList))
+	mock.ExpectCommit()
+
+	_, employeeErr := p.GetEmployeeByID(newTenantContext("acme"), "1")
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListEmployee_FilteredAndSorted(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	salaryMax := decimal("60000")
+	query := models.EmployeeQuery{
+		Position:  "Engineer",
+		SalaryMax: &salaryMax,
+		Sort:      []models.SortField{{Field: "salary", Descending: true}},
+		Page:      2,
+		PageSize:  10,
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM employees WHERE tenant_id=\$1 AND deleted_at IS NULL AND position=\$2 AND salary<=\$3`).
+		WithArgs("default", "Engineer", "60000").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+`\s+FROM employees WHERE tenant_id=\$1 AND deleted_at IS NULL AND position=\$2 AND salary<=\$3\s+ORDER BY salary DESC, id ASC\s+LIMIT \$4 OFFSET \$5`).
+		WithArgs("default", "Engineer", "60000", 11, 10).
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).
+			AddRow("11", "John Doe", "Engineer", 50000.0, "USD", nil, nil, now, now, 1, nil))
+
+	list, employeeErr := p.ListEmployee(newTestContext(), query)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, 11, list.TotalCount)
+	assert.Len(t, list.Employees, 1)
+	assert.Equal(t, "John Doe", list.Employees[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListEmployee_Cursor(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	salary := usd("60000")
+	sort := []models.SortField{{Field: "salary", Descending: true}}
+	query := models.EmployeeQuery{
+		Position: "Engineer",
+		Sort:     sort,
+		Cursor:   encodeEmployeeCursor(models.Employee{ID: "7", Salary: salary}, sort),
+		PageSize: 1,
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM employees WHERE tenant_id=\$1 AND deleted_at IS NULL AND position=\$2`).
+		WithArgs("default", "Engineer").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+`\s+FROM employees WHERE tenant_id=\$1 AND deleted_at IS NULL AND position=\$2 AND \(\(salary < \$3\) OR \(salary = \$3 AND id > \$4\)\)\s+ORDER BY salary DESC, id ASC\s+LIMIT \$5$`).
+		WithArgs("default", "Engineer", "60000", 7, 2).
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).
+			AddRow("8", "John Doe", "Engineer", 50000.0, "USD", nil, nil, now, now, 1, nil).
+			AddRow("9", "Jane Doe", "Engineer", 40000.0, "USD", nil, nil, now, now, 1, nil))
+
+	list, employeeErr := p.ListEmployee(newTestContext(), query)
+	assert.Nil(t, employeeErr)
+	assert.Len(t, list.Employees, 1)
+	assert.True(t, list.HasMore)
+	assert.Zero(t, list.Page)
+
+	// The next cursor continues after the last employee of the page
+	after, _, err := decodeEmployeeCursor(list.NextCursor, sort)
+	assert.NoError(t, err)
+	assert.Equal(t, "8", after.ID)
+	assert.Equal(t, decimal("50000"), after.Salary.Amount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListEmployee_InvalidCursor(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// The cursor is rejected before the database is queried
+	_, employeeErr := p.ListEmployee(newTestContext(), models.EmployeeQuery{Cursor: "not a cursor", PageSize: 10})
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateEmployee_VersionMismatch(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// Someone else updated the employee to version 4 after it was read at version 3
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, now, now, 4, nil))
+	mock.ExpectRollback()
+
+	_, employeeErr := p.UpdateEmployee(newTestContext(), models.Employee{ID: "1", Name: "Updated Name", Version: 3})
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusPreconditionFailed, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteEmployee_Version(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).WithArgs(hierarchyLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, time.Now(), time.Now(), 4, nil))
+	mock.ExpectRollback()
+
+	employeeErr := p.DeleteEmployee(newTestContext(), "1", 3)
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusPreconditionFailed, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteEmployee_SoftDelete(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// The row is kept with deleted_at set and its reports lose their manager,
+	// every change is audited in the same transaction
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).WithArgs(hierarchyLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, now, now, 4, nil))
+	mock.ExpectQuery(`UPDATE employees SET deleted_at=\$2 WHERE id=\$1 AND tenant_id=\$3 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(1, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, now, now, 5, now))
+	expectAudit(mock, "1", models.AuditDelete)
+	mock.ExpectQuery(`UPDATE employees SET manager_id=NULL WHERE manager_id=\$1 AND tenant_id=\$2 RETURNING `+regexp.QuoteMeta(employeeColumns)).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).
+			AddRow(2, "Report", "Position", 30000.0, "USD", nil, nil, now, now, 2, nil).
+			AddRow(3, "Report", "Position", 30000.0, "USD", nil, nil, now, now, 7, nil))
+	expectAudit(mock, "2", models.AuditUpdate)
+	expectAudit(mock, "3", models.AuditUpdate)
+	mock.ExpectCommit()
+
+	assert.Nil(t, p.DeleteEmployee(newTestContext(), "1", 4))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreEmployee_NotDeleted(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT `+regexp.QuoteMeta(employeeColumns)+` FROM employees WHERE id=\$1 AND tenant_id=\$2 FOR UPDATE`).
+		WithArgs(1, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, time.Now(), time.Now(), 1, nil))
+	mock.ExpectRollback()
+
+	_, employeeErr := p.RestoreEmployee(newTestContext(), "1")
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusConflict, employeeErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeEmployees(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	cutoff := time.Now().AddDate(0, 0, -90)
+	deletedAt := cutoff.AddDate(0, 0, -1)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE employees AS e SET manager_id=NULL FROM employees AS m WHERE e.manager_id=m.id AND e.tenant_id=m.tenant_id AND m.deleted_at IS NOT NULL AND m.deleted_at < \$1 RETURNING ` + regexp.QuoteMeta(employeeColumnsOf("e")) + `, e.tenant_id, m.id`).
+		WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows(append(employeeColumnList, "tenant_id", "id")).
+			AddRow(3, "Report", "Position", 40000.0, "USD", nil, nil, deletedAt, cutoff, 4, nil, "acme", "1"))
+	// The detached report is audited with the manager it had
+	mock.ExpectExec(`INSERT INTO employee_audit`).
+		WithArgs("3", models.AuditUpdate, managedBy("1"), managedBy(""), "system", "txid", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`DELETE FROM employees WHERE deleted_at IS NOT NULL AND deleted_at < \$1 RETURNING ` + regexp.QuoteMeta(employeeColumns) + `, tenant_id`).
+		WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows(append(employeeColumnList, "tenant_id")).
+			AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, deletedAt, deletedAt, 2, deletedAt, "acme").
+			AddRow(2, "Name", "Position", 40000.0, "USD", nil, nil, deletedAt, deletedAt, 2, deletedAt, "globex"))
+	mock.ExpectExec(`INSERT INTO employee_audit`).
+		WithArgs("1", models.AuditPurge, sqlmock.AnyArg(), nil, "system", "txid", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO employee_audit`).
+		WithArgs("2", models.AuditPurge, sqlmock.AnyArg(), nil, "system", "txid", "globex").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	purged, err := p.PurgeEmployees(context.Background(), cutoff, "system", "txid")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTenantEmployees(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+	ctx := newTestContext()
+	ctx.Set(constants.TenantKey, "acme")
+	ctx.Set(constants.ActorKey, "alice")
+
+	// Both statements are limited to the tenant of the request
+	cutoff := time.Now().AddDate(0, 0, -90)
+	deletedAt := cutoff.AddDate(0, 0, -1)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE employees AS e SET manager_id=NULL FROM employees AS m WHERE e.manager_id=m.id AND e.tenant_id=m.tenant_id AND m.deleted_at IS NOT NULL AND m.deleted_at < \$1 AND m.tenant_id=\$2 RETURNING `).
+		WithArgs(cutoff, "acme").
+		WillReturnRows(sqlmock.NewRows(append(employeeColumnList, "tenant_id", "id")))
+	mock.ExpectQuery(`DELETE FROM employees WHERE deleted_at IS NOT NULL AND deleted_at < \$1 AND tenant_id=\$2 RETURNING `).
+		WithArgs(cutoff, "acme").
+		WillReturnRows(sqlmock.NewRows(append(employeeColumnList, "tenant_id")).
+			AddRow(1, "Name", "Position", 40000.0, "USD", nil, nil, deletedAt, deletedAt, 2, deletedAt, "acme"))
+	mock.ExpectExec(`INSERT INTO employee_audit`).
+		WithArgs("1", models.AuditPurge, sqlmock.AnyArg(), nil, "alice", ctx.Request.Header.Get(constants.TransactionID), "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	purged, employeeErr := p.PurgeTenantEmployees(ctx, cutoff)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, int64(1), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// managedBy matches the JSON document of an employee audited with the given manager, "" for none
+type managedBy string
+
+func (m managedBy) Match(value driver.Value) bool {
+	document, ok := value.(string)
+	var employee models.Employee
+	if !ok || json.Unmarshal([]byte(document), &employee) != nil {
+		return false
+	}
+	if employee.ManagerID == nil {
+		return m == ""
+	}
+	return *employee.ManagerID == string(m)
+}
+
+func TestCountActiveEmployees(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	mock.ExpectQuery(`SELECT tenant_id, COUNT\(\*\) FROM employees WHERE deleted_at IS NULL GROUP BY tenant_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "count"}).AddRow("acme", 3).AddRow("globex", 1))
+
+	counts, err := p.CountActiveEmployees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"acme": 3, "globex": 1}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"fmt"
+	"strings"
+)
+
+// employeeSortColumns whitelists the sort fields accepted from clients and maps them to SQL.
+// Anything not listed here never reaches a query.
+var employeeSortColumns = map[string]string{
+	"id":              "id",
+	"name":            "name",
+	"position":        "position",
+	"salary":          "salary",
+	"created_at":      "created_at",
+	"last_updated_at": "last_updated_at",
+}
+
+// likeEscaper escapes the LIKE wildcards so that a search for "50%" matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// buildEmployeeFilter turns the filters of the query into a WHERE clause with numbered
+// placeholders starting at $1, and the matching arguments. The employees are always those of the
+// tenant, which is the first argument.
+func buildEmployeeFilter(tenant string, query models.EmployeeQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	add("tenant_id=$%d", tenant)
+
+	if !query.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if query.Position != "" {
+		add("position=$%d", query.Position)
+	}
+	if query.SalaryMin != nil {
+		add("salary>=$%d", *query.SalaryMin)
+	}
+	if query.SalaryMax != nil {
+		add("salary<=$%d", *query.SalaryMax)
+	}
+	if query.CreatedAfter != nil {
+		add("created_at>$%d", *query.CreatedAfter)
+	}
+	if query.NameContains != "" {
+		add(`name ILIKE $%d ESCAPE '\'`, "%"+likeEscaper.Replace(query.NameContains)+"%")
+	}
+	if query.DepartmentID != nil {
+		add("department_id=$%d", *query.DepartmentID)
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// employeeSortKeys returns the sort fields followed by id, unless id is already one of them,
+// so that the order of employees is total and paging is deterministic
+func employeeSortKeys(sort []models.SortField) []models.SortField {
+	for _, field := range sort {
+		if field.Field == "id" {
+			return sort
+		}
+	}
+	return append(append([]models.SortField{}, sort...), models.SortField{Field: "id"})
+}
+
+// buildEmployeeOrder turns the sort fields into an ORDER BY list, id is always the final key
+// so that paging is deterministic
+func buildEmployeeOrder(sort []models.SortField) (string, error) {
+	var keys []string
+	for _, field := range employeeSortKeys(sort) {
+		column, ok := employeeSortColumns[field.Field]
+		if !ok {
+			return "", fmt.Errorf("cannot sort by %q", field.Field)
+		}
+		direction := "ASC"
+		if field.Descending {
+			direction = "DESC"
+		}
+		keys = append(keys, column+" "+direction)
+	}
+	return strings.Join(keys, ", "), nil
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildEmployeeFilter(t *testing.T) {
+	where, args := buildEmployeeFilter("acme", models.EmployeeQuery{})
+	assert.Equal(t, "WHERE tenant_id=$1 AND deleted_at IS NULL", where)
+	assert.Equal(t, []interface{}{"acme"}, args)
+
+	where, _ = buildEmployeeFilter("acme", models.EmployeeQuery{IncludeDeleted: true})
+	assert.Equal(t, "WHERE tenant_id=$1", where)
+
+	salaryMin := decimal("1000")
+	departmentID := "3"
+	where, args = buildEmployeeFilter("acme", models.EmployeeQuery{
+		Position:     "Engineer",
+		SalaryMin:    &salaryMin,
+		NameContains: `50%_off\`,
+		DepartmentID: &departmentID,
+	})
+	assert.Equal(t, `WHERE tenant_id=$1 AND deleted_at IS NULL AND position=$2 AND salary>=$3 AND name ILIKE $4 ESCAPE '\' AND department_id=$5`, where)
+	assert.Equal(t, []interface{}{"acme", "Engineer", salaryMin, `%50\%\_off\\%`, "3"}, args)
+}
+
+func TestBuildEmployeeOrder(t *testing.T) {
+	order, err := buildEmployeeOrder(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "id ASC", order)
+
+	order, err = buildEmployeeOrder([]models.SortField{{Field: "salary", Descending: true}, {Field: "name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "salary DESC, name ASC, id ASC", order)
+
+	order, err = buildEmployeeOrder([]models.SortField{{Field: "id", Descending: true}})
+	assert.NoError(t, err)
+	assert.Equal(t, "id DESC", order)
+
+	_, err = buildEmployeeOrder([]models.SortField{{Field: "salary; DROP TABLE employees"}})
+	assert.Error(t, err)
+}
+
+func TestEmployeeCursor_RoundTrip(t *testing.T) {
+	salary := usd("1234.5")
+	created := time.Date(2024, 1, 31, 10, 0, 0, 123456000, time.UTC)
+	sort := []models.SortField{{Field: "created_at", Descending: true}, {Field: "salary"}, {Field: "name"}}
+
+	cursor := encodeEmployeeCursor(models.Employee{ID: "42", Name: "John Doe", Salary: salary, CreatedAt: created}, sort)
+	after, cursorSort, err := decodeEmployeeCursor(cursor, sort)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", after.ID)
+	assert.Equal(t, "John Doe", after.Name)
+	assert.Equal(t, salary.Amount, after.Salary.Amount)
+	assert.True(t, created.Equal(after.CreatedAt))
+	assert.Equal(t, sort, cursorSort)
+
+	// Without a sort order the cursor continues its own, id included
+	after, cursorSort, err = decodeEmployeeCursor(cursor, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", after.ID)
+	assert.Equal(t, append(sort, models.SortField{Field: "id"}), cursorSort)
+
+	// A cursor only continues the sort order it was issued for
+	_, _, err = decodeEmployeeCursor(cursor, []models.SortField{{Field: "name"}})
+	assert.EqualError(t, err, "cursor was issued for a different sort order")
+
+	_, _, err = decodeEmployeeCursor("bm90IGpzb24", sort)
+	assert.Equal(t, errInvalidCursor, err)
+
+	// A cursor naming a column that cannot be sorted on is not trusted
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"password,id","k":{"password":"x","id":"1"}}`))
+	_, _, err = decodeEmployeeCursor(forged, nil)
+	assert.Equal(t, errInvalidCursor, err)
+}
+
+func TestBuildEmployeeKeyset(t *testing.T) {
+	salary := usd("1000")
+	keyset, args := buildEmployeeKeyset(nil, models.Employee{ID: "5"}, 1)
+	assert.Equal(t, "((id > $1))", keyset)
+	assert.Equal(t, []interface{}{5}, args)
+
+	keyset, args = buildEmployeeKeyset([]models.SortField{{Field: "salary", Descending: true}, {Field: "name"}},
+		models.Employee{ID: "5", Name: "John Doe", Salary: salary}, 3)
+	assert.Equal(t, "((salary < $3) OR (salary = $3 AND name > $4) OR (salary = $3 AND name = $4 AND id > $5))", keyset)
+	assert.Equal(t, []interface{}{salary.Amount, "John Doe", 5}, args)
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ExportEmployees passes every employee matching the filters of query to emit, in the sort
+// order of the query, and ignores its paging. The rows are fetched from a server-side cursor
+// constants.ExportBatchSize at a time, so memory use does not grow with the number of
+// employees. An error returned by emit stops the export.
+func (p postgres) ExportEmployees(ctx *gin.Context, query models.EmployeeQuery, emit func(models.Employee) error) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	where, args := buildEmployeeFilter(tenantOf(ctx), query)
+	order, err := buildEmployeeOrder(query.Sort)
+	if err != nil {
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Trace:   txid,
+		}
+	}
+
+	exportErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to export employee records",
+		Trace:   txid,
+	}
+
+	// The whole export reads from a single snapshot, however long it takes
+	tx, err := p.beginTx(ctx, tenantOf(ctx), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return exportErr
+	}
+	defer tx.Rollback()
+
+	declare := fmt.Sprintf(`DECLARE employee_export NO SCROLL CURSOR FOR SELECT %s
+               FROM employees %s
+               ORDER BY %s`, employeeColumns, where, order)
+	if _, err := tx.ExecContext(ctx, declare, args...); err != nil {
+		utils.Log(ctx).Error("Error declaring export cursor", zap.Error(err))
+		return exportErr
+	}
+
+	exported := 0
+	for {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`FETCH FORWARD %d FROM employee_export`, constants.ExportBatchSize))
+		if err != nil {
+			utils.Log(ctx).Error("Error fetching from export cursor", zap.Error(err))
+			return exportErr
+		}
+		fetched := 0
+		for rows.Next() {
+			var employee models.Employee
+			if err := scanEmployee(rows, &employee); err != nil {
+				rows.Close()
+				utils.Log(ctx).Error("Error scanning row", zap.Error(err))
+				return exportErr
+			}
+			fetched++
+			if err := emit(employee); err != nil {
+				rows.Close()
+				utils.Log(ctx).Error("Error writing exported employee", zap.Error(err))
+				return exportErr
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			utils.Log(ctx).Error("Error iterating over export cursor", zap.Error(err))
+			return exportErr
+		}
+		exported += fetched
+		if fetched < constants.ExportBatchSize {
+			break
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error closing export transaction", zap.Error(err))
+		return exportErr
+	}
+
+	utils.Log(ctx).Info("Successfully exported employee records from db", zap.Int("employees", exported))
+	return nil
+}
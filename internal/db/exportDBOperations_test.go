@@ -0,0 +1,97 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportEmployees(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// The listing filters and sort apply, the rows come from a cursor until a batch runs short
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DECLARE employee_export NO SCROLL CURSOR FOR SELECT `+regexp.QuoteMeta(employeeColumns)+`\s+FROM employees WHERE tenant_id=\$1 AND deleted_at IS NULL AND position=\$2\s+ORDER BY salary DESC, id ASC`).
+		WithArgs("default", "Engineer").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FETCH FORWARD 1000 FROM employee_export`).
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).
+			AddRow(1, "John Doe", "Engineer", "50000", "USD", nil, nil, now, now, 1, nil).
+			AddRow(2, "Jane Doe", "Engineer", "40000", "USD", nil, nil, now, now, 1, nil))
+	mock.ExpectCommit()
+
+	var exported []string
+	employeeErr := p.ExportEmployees(newTestContext(), models.EmployeeQuery{
+		Position: "Engineer",
+		Sort:     []models.SortField{{Field: "salary", Descending: true}},
+	}, func(employee models.Employee) error {
+		exported = append(exported, employee.Name)
+		return nil
+	})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, []string{"John Doe", "Jane Doe"}, exported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportEmployees_WriteError(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// A client that goes away stops the export
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DECLARE employee_export`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FETCH FORWARD 1000 FROM employee_export`).
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).
+			AddRow(1, "John Doe", "Engineer", "50000", "USD", nil, nil, now, now, 1, nil).
+			AddRow(2, "Jane Doe", "Engineer", "40000", "USD", nil, nil, now, now, 1, nil))
+	mock.ExpectRollback()
+
+	calls := 0
+	employeeErr := p.ExportEmployees(newTestContext(), models.EmployeeQuery{}, func(models.Employee) error {
+		calls++
+		return errors.New("broken pipe")
+	})
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInMemory_ExportEmployees(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	for _, name := range []string{"Carol", "Alice", "Bob"} {
+		_, _ = m.CreateEmployee(ctx, models.Employee{Name: name, Position: "Engineer", Salary: usd("50000")})
+	}
+	_ = m.DeleteEmployee(ctx, "3", 0)
+
+	var exported []string
+	employeeErr := m.ExportEmployees(ctx, models.EmployeeQuery{Sort: []models.SortField{{Field: "name"}}}, func(employee models.Employee) error {
+		exported = append(exported, employee.Name)
+		return nil
+	})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, []string{"Alice", "Carol"}, exported)
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"context"
+)
+
+// Ping checks that the database answers
+func (p postgres) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+// PendingMigrations returns how many schema migrations of this build are not applied yet
+func (p postgres) PendingMigrations(ctx context.Context) (int, error) {
+	migrator, err := NewMigrator(p.db)
+	if err != nil {
+		return 0, err
+	}
+	return migrator.Pending(ctx)
+}
+
+// PoolStats returns the statistics of the connection pool
+func (p postgres) PoolStats() (models.PoolStats, bool) {
+	stats := p.db.Stats()
+	return models.PoolStats{
+		MaxOpenConnections:  stats.MaxOpenConnections,
+		OpenConnections:     stats.OpenConnections,
+		InUse:               stats.InUse,
+		Idle:                stats.Idle,
+		WaitCount:           stats.WaitCount,
+		WaitDurationSeconds: stats.WaitDuration.Seconds(),
+	}, true
+}
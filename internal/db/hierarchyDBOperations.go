@@ -0,0 +1,220 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// hierarchyLockID is the postgres advisory lock key held while an employee's manager changes
+const hierarchyLockID = 7245532
+
+// checkManagerCycle rejects making managerId the manager of employeeId when employeeId
+// already appears in the management chain of managerId, e.g. as their own skip-level manager
+func checkManagerCycle(ctx *gin.Context, tx *sql.Tx, employeeId string, managerId string) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, hierarchyLockID); err != nil {
+		utils.Log(ctx).Error("Error acquiring hierarchy lock", zap.Error(err))
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to update employee record",
+			Trace:   txid,
+		}
+	}
+
+	query := `WITH RECURSIVE chain AS (
+                   SELECT id, manager_id, ARRAY[id] AS path FROM employees WHERE id=$1 AND tenant_id=$3
+                   UNION ALL
+                   SELECT e.id, e.manager_id, c.path || e.id
+                   FROM employees e JOIN chain c ON e.id = c.manager_id
+                   WHERE e.tenant_id=$3 AND NOT e.id = ANY(c.path)
+               )
+               SELECT EXISTS (SELECT 1 FROM chain WHERE id=$2)`
+
+	empId, _ := strconv.Atoi(employeeId)
+	mgrId, _ := strconv.Atoi(managerId)
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, query, mgrId, empId, tenantOf(ctx)).Scan(&cycle); err != nil {
+		utils.Log(ctx).Error("Error checking management chain", zap.Error(err))
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to update employee record",
+			Trace:   txid,
+		}
+	}
+	if cycle {
+		return managerCycleError(txid)
+	}
+	return nil
+}
+
+func managerCycleError(txid string) *employeeerror.EmployeeError {
+	return &employeeerror.EmployeeError{
+		Code:    http.StatusBadRequest,
+		Message: "Manager change would create a reporting cycle",
+		Trace:   txid,
+	}
+}
+
+// ListReports returns the employees reporting to employeeId up to depth levels down,
+// a depth of 0 returns every level
+func (p postgres) ListReports(ctx *gin.Context, employeeId string, depth int) ([]models.EmployeeReport, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	query := fmt.Sprintf(`WITH RECURSIVE reports AS (
+                   SELECT %s, 1 AS level, ARRAY[id] AS path FROM employees WHERE manager_id=$1 AND tenant_id=$3 AND deleted_at IS NULL
+                   UNION ALL
+                   SELECT %s, r.level + 1, r.path || e.id
+                   FROM employees e JOIN reports r ON e.manager_id = r.id
+                   WHERE ($2 = 0 OR r.level < $2) AND e.tenant_id=$3 AND NOT e.id = ANY(r.path) AND e.deleted_at IS NULL
+               )
+               SELECT %s, level FROM reports ORDER BY level, id`, employeeColumns, employeeColumnsOf("e"), employeeColumns)
+
+	empId, _ := strconv.Atoi(employeeId)
+	reportsErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to retrieve employee reports",
+		Trace:   txid,
+	}
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return nil, reportsErr
+	}
+	defer done()
+
+	rows, err := q.QueryContext(ctx, query, empId, depth, tenantOf(ctx))
+	if err != nil {
+		utils.Log(ctx).Error("Error executing query", zap.Error(err))
+		return nil, reportsErr
+	}
+	defer rows.Close()
+
+	var reports []models.EmployeeReport
+	for rows.Next() {
+		var report models.EmployeeReport
+		if err := scanEmployee(rows, &report.Employee, &report.Level); err != nil {
+			utils.Log(ctx).Error("Error scanning row", zap.Error(err))
+			return nil, &employeeerror.EmployeeError{
+				Code:    http.StatusInternalServerError,
+				Message: "Error processing employee records",
+				Trace:   txid,
+			}
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		utils.Log(ctx).Error("Error iterating over rows", zap.Error(err))
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Error processing employee records",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved employee reports from db")
+	return reports, nil
+}
+
+// GetManagementChain returns the employee followed by their manager, that manager's manager
+// and so on up to the root of the organisation
+func (p postgres) GetManagementChain(ctx *gin.Context, employeeId string) ([]models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	query := fmt.Sprintf(`WITH RECURSIVE chain AS (
+                   SELECT %s, 0 AS level, ARRAY[id] AS path FROM employees WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL
+                   UNION ALL
+                   SELECT %s, c.level + 1, c.path || e.id
+                   FROM employees e JOIN chain c ON e.id = c.manager_id
+                   WHERE e.tenant_id=$2 AND NOT e.id = ANY(c.path)
+               )
+               SELECT %s FROM chain ORDER BY level`, employeeColumns, employeeColumnsOf("e"), employeeColumns)
+
+	empId, _ := strconv.Atoi(employeeId)
+	q, done, scopeErr := p.scope(ctx)
+	if scopeErr != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(scopeErr))
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve employee records",
+			Trace:   txid,
+		}
+	}
+	defer done()
+
+	chain, err := queryEmployees(ctx, q, query, empId, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved management chain from db")
+	return chain, nil
+}
+
+// GetOrgChart returns every employee arranged as a tree under the employees without a manager,
+// soft-deleted employees are left out
+func (p postgres) GetOrgChart(ctx *gin.Context) ([]*models.OrgChartNode, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	query := fmt.Sprintf(`WITH RECURSIVE tree AS (
+                   SELECT %s, 0 AS level, ARRAY[id] AS path FROM employees WHERE manager_id IS NULL AND tenant_id=$1 AND deleted_at IS NULL
+                   UNION ALL
+                   SELECT %s, t.level + 1, t.path || e.id
+                   FROM employees e JOIN tree t ON e.manager_id = t.id
+                   WHERE e.tenant_id=$1 AND NOT e.id = ANY(t.path) AND e.deleted_at IS NULL
+               )
+               SELECT %s FROM tree ORDER BY level, id`, employeeColumns, employeeColumnsOf("e"), employeeColumns)
+
+	q, done, scopeErr := p.scope(ctx)
+	if scopeErr != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(scopeErr))
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve employee records",
+			Trace:   txid,
+		}
+	}
+	defer done()
+
+	employees, err := queryEmployees(ctx, q, query, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved org chart from db")
+	return buildOrgChart(employees), nil
+}
+
+// buildOrgChart nests employees under their managers, every manager must come before their reports
+func buildOrgChart(employees []models.Employee) []*models.OrgChartNode {
+	roots := []*models.OrgChartNode{}
+	nodes := make(map[string]*models.OrgChartNode, len(employees))
+	for _, employee := range employees {
+		node := &models.OrgChartNode{Employee: employee, Reports: []*models.OrgChartNode{}}
+		nodes[employee.ID] = node
+		if employee.ManagerID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if manager, ok := nodes[*employee.ManagerID]; ok {
+			manager.Reports = append(manager.Reports, node)
+		}
+	}
+	return roots
+}
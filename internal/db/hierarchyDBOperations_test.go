@@ -0,0 +1,96 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateEmployee_ManagerCycle(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// Employee 1 manages 2, so 2 cannot become the manager of 1
+	managerID := "2"
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).WithArgs(hierarchyLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`WITH RECURSIVE chain AS`).
+		WithArgs(2, 1, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, employeeErr := p.UpdateEmployee(newTestContext(), models.Employee{ID: "1", ManagerID: &managerID})
+	assert.NotNil(t, employeeErr)
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+	assert.Equal(t, "Manager change would create a reporting cycle", employeeErr.Message)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuildOrgChart(t *testing.T) {
+	one, two := "1", "2"
+	chart := buildOrgChart([]models.Employee{
+		{ID: "1", Name: "CEO"},
+		{ID: "5", Name: "CFO"},
+		{ID: "2", Name: "CTO", ManagerID: &one},
+		{ID: "3", Name: "Engineer", ManagerID: &two},
+	})
+
+	assert.Len(t, chart, 2)
+	assert.Equal(t, "CEO", chart[0].Name)
+	assert.Len(t, chart[0].Reports, 1)
+	assert.Equal(t, "CTO", chart[0].Reports[0].Name)
+	assert.Equal(t, "Engineer", chart[0].Reports[0].Reports[0].Name)
+	assert.Empty(t, chart[1].Reports)
+}
+
+func TestInMemory_Hierarchy(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	salary := usd("50000")
+	ceo, _ := m.CreateEmployee(ctx, models.Employee{Name: "CEO", Position: "CEO", Salary: salary})
+	cto, _ := m.CreateEmployee(ctx, models.Employee{Name: "CTO", Position: "CTO", Salary: salary, ManagerID: &ceo})
+	engineer, _ := m.CreateEmployee(ctx, models.Employee{Name: "Engineer", Position: "Engineer", Salary: salary, ManagerID: &cto})
+
+	reports, employeeErr := m.ListReports(ctx, ceo, 1)
+	assert.Nil(t, employeeErr)
+	assert.Len(t, reports, 1)
+	assert.Equal(t, cto, reports[0].ID)
+
+	reports, _ = m.ListReports(ctx, ceo, 0)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, 2, reports[1].Level)
+
+	chain, employeeErr := m.GetManagementChain(ctx, engineer)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, []string{engineer, cto, ceo}, []string{chain[0].ID, chain[1].ID, chain[2].ID})
+
+	// The CEO cannot report to their own skip-level report
+	_, employeeErr = m.UpdateEmployee(ctx, models.Employee{ID: ceo, ManagerID: &engineer})
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+
+	unknown := "42"
+	_, employeeErr = m.UpdateEmployee(ctx, models.Employee{ID: engineer, ManagerID: &unknown})
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+	assert.Equal(t, "Manager not found", employeeErr.Message)
+
+	chart, _ := m.GetOrgChart(ctx)
+	assert.Len(t, chart, 1)
+	assert.Equal(t, engineer, chart[0].Reports[0].Reports[0].ID)
+
+	// Deleting a manager detaches their reports
+	assert.Nil(t, m.DeleteEmployee(ctx, cto, 0))
+	orphan, _ := m.GetEmployeeByID(ctx, engineer)
+	assert.Nil(t, orphan.ManagerID)
+}
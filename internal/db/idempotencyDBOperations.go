@@ -0,0 +1,136 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReserveIdempotencyKey takes the key of a request about to run, keys being per tenant. It
+// returns true when the key was free, or held by an expired response, and is now held by this
+// request. Otherwise the key is left as it is and the response stored for it is returned, with a
+// StatusCode of 0 when the request holding it has not finished.
+func (p postgres) ReserveIdempotencyKey(ctx *gin.Context, reservation models.IdempotentResponse) (models.IdempotentResponse, bool, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	reserveErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to check the idempotency key",
+		Trace:   txid,
+	}
+
+	tenant := tenantOf(ctx)
+	q, done, err := p.scope(ctx)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return models.IdempotentResponse{}, false, reserveErr
+	}
+	defer done()
+
+	var key string
+	err = q.QueryRowContext(ctx, `INSERT INTO idempotency_keys (key, request_hash, created_at, expires_at, tenant_id) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, key) DO UPDATE SET request_hash=EXCLUDED.request_hash, status_code=NULL, content_type='', etag='', body=NULL,
+		created_at=EXCLUDED.created_at, expires_at=EXCLUDED.expires_at WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+		RETURNING key`, reservation.Key, reservation.RequestHash, time.Now(), reservation.ExpiresAt, tenant).Scan(&key)
+	if err == nil {
+		if err := done(); err != nil {
+			utils.Log(ctx).Error("Error reserving idempotency key", zap.Error(err))
+			return models.IdempotentResponse{}, false, reserveErr
+		}
+		return reservation, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		utils.Log(ctx).Error("Error reserving idempotency key", zap.Error(err))
+		return models.IdempotentResponse{}, false, reserveErr
+	}
+
+	// The key is held by a live response
+	var stored models.IdempotentResponse
+	var statusCode sql.NullInt64
+	err = q.QueryRowContext(ctx, `SELECT key, request_hash, status_code, content_type, etag, body, expires_at FROM idempotency_keys WHERE tenant_id=$1 AND key=$2`, tenant, reservation.Key).
+		Scan(&stored.Key, &stored.RequestHash, &statusCode, &stored.ContentType, &stored.ETag, &stored.Body, &stored.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released by a failed request in the meantime, the client can retry
+		stored = models.IdempotentResponse{Key: reservation.Key, RequestHash: reservation.RequestHash}
+		return stored, false, nil
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error reading idempotency key", zap.Error(err))
+		return models.IdempotentResponse{}, false, reserveErr
+	}
+	stored.StatusCode = int(statusCode.Int64)
+	return stored, false, nil
+}
+
+// SaveIdempotentResponse stores the response of the request holding the key
+func (p postgres) SaveIdempotentResponse(ctx *gin.Context, response models.IdempotentResponse) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	q, done, err := p.scope(ctx)
+	if err == nil {
+		_, err = q.ExecContext(ctx, `UPDATE idempotency_keys SET status_code=$1, content_type=$2, etag=$3, body=$4 WHERE tenant_id=$5 AND key=$6 AND request_hash=$7`,
+			response.StatusCode, response.ContentType, response.ETag, response.Body, tenantOf(ctx), response.Key, response.RequestHash)
+		if doneErr := done(); err == nil {
+			err = doneErr
+		}
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error saving idempotent response", zap.Error(err))
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to save the response of the idempotency key",
+			Trace:   txid,
+		}
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey frees the key of a request that failed without a response worth
+// replaying, so that a retry runs the request again
+func (p postgres) ReleaseIdempotencyKey(ctx *gin.Context, key string) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	q, done, err := p.scope(ctx)
+	if err == nil {
+		_, err = q.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE tenant_id=$1 AND key=$2 AND status_code IS NULL`, tenantOf(ctx), key)
+		if doneErr := done(); err == nil {
+			err = doneErr
+		}
+	}
+	if err != nil {
+		utils.Log(ctx).Error("Error releasing idempotency key", zap.Error(err))
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to release the idempotency key",
+			Trace:   txid,
+		}
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys removes the responses of every tenant that expired before the given time
+// and returns how many were removed
+func (p postgres) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	q, done, err := p.scopeTo(ctx, allTenants)
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, expiredBefore)
+	if err != nil {
+		done()
+		return 0, err
+	}
+	if err := done(); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveIdempotencyKey_Free(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectQuery(`INSERT INTO idempotency_keys \(key, request_hash, created_at, expires_at, tenant_id\) VALUES \(\$1, \$2, \$3, \$4, \$5\)\s+ON CONFLICT \(tenant_id, key\) DO UPDATE .* WHERE idempotency_keys.expires_at <= EXCLUDED.created_at\s+RETURNING key`).
+		WithArgs("key-1", "hash", sqlmock.AnyArg(), expiresAt, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("key-1"))
+
+	_, reserved, employeeErr := p.ReserveIdempotencyKey(newTestContext(), models.IdempotentResponse{Key: "key-1", RequestHash: "hash", ExpiresAt: expiresAt})
+	assert.Nil(t, employeeErr)
+	assert.True(t, reserved)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserveIdempotencyKey_Taken(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}))
+	mock.ExpectQuery(`SELECT key, request_hash, status_code, content_type, etag, body, expires_at FROM idempotency_keys WHERE tenant_id=\$1 AND key=\$2`).
+		WithArgs("default", "key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "request_hash", "status_code", "content_type", "etag", "body", "expires_at"}).
+			AddRow("key-1", "hash", 200, "application/json", `"1"`, []byte(`{"employee_id":"7"}`), expiresAt))
+
+	stored, reserved, employeeErr := p.ReserveIdempotencyKey(newTestContext(), models.IdempotentResponse{Key: "key-1", RequestHash: "hash", ExpiresAt: expiresAt})
+	assert.Nil(t, employeeErr)
+	assert.False(t, reserved)
+	assert.Equal(t, 200, stored.StatusCode)
+	assert.Equal(t, `"1"`, stored.ETag)
+	assert.Equal(t, `{"employee_id":"7"}`, string(stored.Body))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInMemory_IdempotencyKeys(t *testing.T) {
+	m := NewInMemory()
+	ctx := newTestContext()
+	reservation := models.IdempotentResponse{Key: "key-1", RequestHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+
+	_, reserved, _ := m.ReserveIdempotencyKey(ctx, reservation)
+	assert.True(t, reserved)
+
+	// Held by the request in progress
+	stored, reserved, _ := m.ReserveIdempotencyKey(ctx, reservation)
+	assert.False(t, reserved)
+	assert.Equal(t, 0, stored.StatusCode)
+
+	// A released key can be taken again
+	m.ReleaseIdempotencyKey(ctx, "key-1")
+	_, reserved, _ = m.ReserveIdempotencyKey(ctx, reservation)
+	assert.True(t, reserved)
+
+	response := reservation
+	response.StatusCode = 200
+	response.Body = []byte(`{"employee_id":"1"}`)
+	assert.Nil(t, m.SaveIdempotentResponse(ctx, response))
+	// A completed response is not released
+	m.ReleaseIdempotencyKey(ctx, "key-1")
+	stored, reserved, _ = m.ReserveIdempotencyKey(ctx, reservation)
+	assert.False(t, reserved)
+	assert.Equal(t, 200, stored.StatusCode)
+	assert.Equal(t, `{"employee_id":"1"}`, string(stored.Body))
+
+	// An expired response frees its key
+	purged, err := m.PurgeIdempotencyKeys(context.Background(), time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	_, reserved, _ = m.ReserveIdempotencyKey(ctx, reservation)
+	assert.True(t, reserved)
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ImportEmployees creates the employees of a bulk import in one transaction and returns the
+// outcome of each row. An atomic import stops at the first row that fails and creates nothing,
+// a best effort import only rolls back the rows that fail, using a savepoint per row.
+func (p postgres) ImportEmployees(ctx *gin.Context, rows []models.ImportRow, atomic bool) ([]models.ImportResult, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	importErr := &employeeerror.EmployeeError{
+		Code:    http.StatusInternalServerError,
+		Message: "Unable to import employee records",
+		Trace:   txid,
+	}
+
+	tx, err := p.beginTx(ctx, tenantOf(ctx), nil)
+	if err != nil {
+		utils.Log(ctx).Error("Error starting transaction", zap.Error(err))
+		return nil, importErr
+	}
+	defer tx.Rollback()
+
+	results := make([]models.ImportResult, 0, len(rows))
+	for _, row := range rows {
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT import_row`); err != nil {
+				utils.Log(ctx).Error("Error creating savepoint", zap.Error(err))
+				return nil, importErr
+			}
+		}
+
+		created, insertErr := insertEmployee(ctx, tx, row.Employee)
+		if insertErr != nil {
+			// A failing database fails the whole import, only a rejected row is reported
+			if insertErr.Code >= http.StatusInternalServerError {
+				return nil, insertErr
+			}
+			if atomic {
+				return []models.ImportResult{{Line: row.Line, Error: insertErr.Message}}, nil
+			}
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT import_row`); err != nil {
+				utils.Log(ctx).Error("Error rolling back to savepoint", zap.Error(err))
+				return nil, importErr
+			}
+			results = append(results, models.ImportResult{Line: row.Line, Error: insertErr.Message})
+			continue
+		}
+
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT import_row`); err != nil {
+				utils.Log(ctx).Error("Error releasing savepoint", zap.Error(err))
+				return nil, importErr
+			}
+		}
+		results = append(results, models.ImportResult{Line: row.Line, EmployeeID: created.ID})
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.Log(ctx).Error("Error committing import", zap.Error(err))
+		return nil, importErr
+	}
+
+	utils.Log(ctx).Info("successfully imported employee entries in db")
+	return results, nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+const insertEmployeeQuery = `INSERT INTO employees \(name, position, salary, salary_currency, department_id, manager_id, tenant_id\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7\) RETURNING `
+
+// importRows are two employees read from lines 2 and 3 of an import file, the second one
+// referring to a department that does not exist
+func importRows() []models.ImportRow {
+	departmentID := "9"
+	return []models.ImportRow{
+		{Line: 2, Employee: models.Employee{Name: "John Doe", Position: "Engineer", Salary: usd("50000")}},
+		{Line: 3, Employee: models.Employee{Name: "Jane Doe", Position: "Engineer", Salary: usd("60000"), DepartmentID: &departmentID}},
+	}
+}
+
+func TestImportEmployees_Atomic(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// The second row fails, so the first one is rolled back with it
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(insertEmployeeQuery+regexp.QuoteMeta(employeeColumns)).
+		WithArgs("John Doe", "Engineer", "50000", "USD", nil, nil, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "John Doe", "Engineer", "50000", "USD", nil, nil, now, now, 1, nil))
+	expectSalaryChange(mock, "1", models.SalaryReasonHire)
+	expectAudit(mock, "1", models.AuditCreate)
+	mock.ExpectQuery(insertEmployeeQuery+regexp.QuoteMeta(employeeColumns)).
+		WithArgs("Jane Doe", "Engineer", "60000", "USD", "9", nil, "default").
+		WillReturnError(&pgconn.PgError{Code: pgForeignKeyViolation, ConstraintName: "employees_department_id_fkey"})
+	mock.ExpectRollback()
+
+	results, employeeErr := p.ImportEmployees(newTestContext(), importRows(), true)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, []models.ImportResult{{Line: 3, Error: "Department not found"}}, results)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportEmployees_BestEffort(t *testing.T) {
+	utils.InitLogClient()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	p := postgres{db: mockDB}
+
+	// Only the failing row is rolled back, to its savepoint
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT import_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insertEmployeeQuery+regexp.QuoteMeta(employeeColumns)).
+		WithArgs("John Doe", "Engineer", "50000", "USD", nil, nil, "default").
+		WillReturnRows(sqlmock.NewRows(employeeColumnList).AddRow(1, "John Doe", "Engineer", "50000", "USD", nil, nil, now, now, 1, nil))
+	expectSalaryChange(mock, "1", models.SalaryReasonHire)
+	expectAudit(mock, "1", models.AuditCreate)
+	mock.ExpectExec(`RELEASE SAVEPOINT import_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT import_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insertEmployeeQuery+regexp.QuoteMeta(employeeColumns)).
+		WithArgs("Jane Doe", "Engineer", "60000", "USD", "9", nil, "default").
+		WillReturnError(&pgconn.PgError{Code: pgForeignKeyViolation, ConstraintName: "employees_department_id_fkey"})
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT import_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	results, employeeErr := p.ImportEmployees(newTestContext(), importRows(), false)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, []models.ImportResult{{Line: 2, EmployeeID: "1"}, {Line: 3, Error: "Department not found"}}, results)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInMemory_ImportEmployees(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	// An atomic import creates nothing when a row is rejected
+	results, employeeErr := m.ImportEmployees(ctx, importRows(), true)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, []models.ImportResult{{Line: 3, Error: "Department not found"}}, results)
+	list, _ := m.ListEmployee(ctx, models.EmployeeQuery{Page: 1, PageSize: 10})
+	assert.Zero(t, list.TotalCount)
+
+	results, employeeErr = m.ImportEmployees(ctx, importRows(), false)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, []models.ImportResult{{Line: 2, EmployeeID: "1"}, {Line: 3, Error: "Department not found"}}, results)
+	employee, employeeErr := m.GetEmployeeByID(ctx, "1")
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, "John Doe", employee.Name)
+}
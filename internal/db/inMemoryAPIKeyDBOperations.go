@@ -0,0 +1,110 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storedAPIKey returns a copy of a stored key without its hash
+func storedAPIKey(key models.APIKey) models.APIKey {
+	key.KeyHash = ""
+	key.Scopes = slices.Clone(key.Scopes)
+	return key
+}
+
+func (m *inMemory) CreateAPIKey(ctx *gin.Context, key models.APIKey) (models.APIKey, *employeeerror.EmployeeError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastAPIKeyID++
+	key.ID = strconv.Itoa(m.lastAPIKeyID)
+	key.Scopes = slices.Clone(key.Scopes)
+	key.CreatedAt = time.Now()
+	key.LastUsedAt = nil
+	key.RevokedAt = nil
+	created := key
+	key.Key = ""
+	m.apiKeys[m.lastAPIKeyID] = key
+	created.KeyHash = ""
+	return created, nil
+}
+
+func (m *inMemory) ListAPIKeys(ctx *gin.Context) ([]models.APIKey, *employeeerror.EmployeeError) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(m.apiKeys))
+	for _, key := range m.apiKeys {
+		keys = append(keys, storedAPIKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(keys[i].ID)
+		b, _ := strconv.Atoi(keys[j].ID)
+		return a < b
+	})
+	return keys, nil
+}
+
+func (m *inMemory) RotateAPIKey(ctx *gin.Context, keyId string, rotated models.APIKey) (models.APIKey, *employeeerror.EmployeeError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, _ := strconv.Atoi(keyId)
+	key, ok := m.apiKeys[id]
+	if !ok || key.RevokedAt != nil {
+		return models.APIKey{}, apiKeyNotFoundError(ctx.Request.Header.Get(constants.TransactionID))
+	}
+	key.Prefix = rotated.Prefix
+	key.KeyHash = rotated.KeyHash
+	key.LastUsedAt = nil
+	m.apiKeys[id] = key
+
+	key = storedAPIKey(key)
+	key.Key = rotated.Key
+	return key, nil
+}
+
+func (m *inMemory) RevokeAPIKey(ctx *gin.Context, keyId string) *employeeerror.EmployeeError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, _ := strconv.Atoi(keyId)
+	key, ok := m.apiKeys[id]
+	if !ok {
+		return apiKeyNotFoundError(ctx.Request.Header.Get(constants.TransactionID))
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+		m.apiKeys[id] = key
+	}
+	return nil
+}
+
+func (m *inMemory) UseAPIKey(ctx *gin.Context, keyHash string) (models.APIKey, bool, *employeeerror.EmployeeError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, key := range m.apiKeys {
+		if key.KeyHash != keyHash {
+			continue
+		}
+		if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+			return models.APIKey{}, false, nil
+		}
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+			key.LastUsedAt = &now
+			m.apiKeys[id] = key
+		}
+		return storedAPIKey(key), true, nil
+	}
+	return models.APIKey{}, false, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAudit appends an entry to the audit trail, the caller must hold the write lock
+// of the change being recorded
+func (m *inMemory) recordAudit(entry models.AuditEntry) {
+	m.lastAuditID++
+	entry.ID = m.lastAuditID
+	entry.CreatedAt = time.Now()
+	m.audit = append(m.audit, entry)
+}
+
+// matchesAuditQuery applies the filters of the query like buildAuditFilter does in SQL
+func matchesAuditQuery(entry models.AuditEntry, query models.AuditQuery) bool {
+	if query.EmployeeID != nil && entry.EmployeeID != canonicalID(*query.EmployeeID) {
+		return false
+	}
+	if query.Actor != "" && entry.Actor != query.Actor {
+		return false
+	}
+	if query.From != nil && entry.CreatedAt.Before(*query.From) {
+		return false
+	}
+	if query.To != nil && !entry.CreatedAt.Before(*query.To) {
+		return false
+	}
+	return true
+}
+
+func (m *inMemory) ListAudit(ctx *gin.Context, query models.AuditQuery) (models.AuditList, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	// postgres rejects a negative LIMIT or OFFSET
+	offset := (query.Page - 1) * query.PageSize
+	if offset < 0 || query.PageSize < 0 {
+		return models.AuditList{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve audit records",
+			Trace:   txid,
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// The newest changes come first
+	entries := []models.AuditEntry{}
+	totalCount := 0
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		if !matchesAuditQuery(m.audit[i], query) {
+			continue
+		}
+		if totalCount >= offset && len(entries) < query.PageSize {
+			entries = append(entries, m.audit[i])
+		}
+		totalCount++
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved audit records from memory")
+	return models.AuditList{
+		Entries:    entries,
+		TotalCount: totalCount,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"fmt"
+	"maps"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// inMemoryState is the part of the in-memory store a batch changes, kept so that a failed or
+// dry run batch can be rolled back like a postgres transaction
+type inMemoryState struct {
+	employees     map[int]models.Employee
+	audit         []models.AuditEntry
+	lastAuditID   int64
+	salaryHistory map[int][]models.SalaryChange
+	lastSalaryID  int64
+}
+
+// snapshot saves the employees, audit trail and salary history, the caller must hold the lock.
+// Entries are only ever appended to the slices, so keeping their length is enough to undo it.
+func (m *inMemory) snapshot() inMemoryState {
+	return inMemoryState{
+		employees:     maps.Clone(m.employees),
+		audit:         m.audit,
+		lastAuditID:   m.lastAuditID,
+		salaryHistory: maps.Clone(m.salaryHistory),
+		lastSalaryID:  m.lastSalaryID,
+	}
+}
+
+// rollback restores a snapshot, the caller must hold the lock
+func (m *inMemory) rollback(state inMemoryState) {
+	m.employees = state.employees
+	m.audit = state.audit
+	m.lastAuditID = state.lastAuditID
+	m.salaryHistory = state.salaryHistory
+	m.lastSalaryID = state.lastSalaryID
+}
+
+func (m *inMemory) BatchUpdateEmployees(ctx *gin.Context, selection models.BatchSelection, update models.Employee, dryRun bool) ([]models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids, selectErr := m.selectBatchEmployees(txid, selection)
+	if selectErr != nil {
+		return nil, selectErr
+	}
+	if missing := missingBatchIDs(selection.IDs, ids); len(missing) > 0 {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employees not found: " + strings.Join(missing, ", "),
+			Trace:   txid,
+		}
+	}
+
+	state := m.snapshot()
+	employees := make([]models.Employee, 0, len(ids))
+	for _, id := range ids {
+		employee := update
+		employee.ID = strconv.Itoa(id)
+		updated, updateErr := m.updateEmployee(ctx, employee, false)
+		if updateErr != nil {
+			m.rollback(state)
+			return nil, batchRowError(employee.ID, updateErr)
+		}
+		employees = append(employees, updated)
+	}
+
+	if dryRun {
+		m.rollback(state)
+		utils.Log(ctx).Info("dry run of batch update rolled back", zap.Int("employees", len(employees)))
+		return employees, nil
+	}
+	utils.Log(ctx).Info("Successfully updated employee entries in memory", zap.Int("employees", len(employees)))
+	return employees, nil
+}
+
+func (m *inMemory) BatchDeleteEmployees(ctx *gin.Context, selection models.BatchSelection, dryRun bool) ([]models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids, selectErr := m.selectBatchEmployees(txid, selection)
+	if selectErr != nil {
+		return nil, selectErr
+	}
+
+	state := m.snapshot()
+	employees := make([]models.Employee, 0, len(ids))
+	for _, id := range ids {
+		employees = append(employees, m.softDeleteEmployee(ctx, m.employees[id]))
+	}
+
+	if dryRun {
+		m.rollback(state)
+		utils.Log(ctx).Info("dry run of batch delete rolled back", zap.Int("employees", len(employees)))
+		return employees, nil
+	}
+	utils.Log(ctx).Info("Successfully deleted employee entries from memory", zap.Int("employees", len(employees)))
+	return employees, nil
+}
+
+// selectBatchEmployees returns the IDs of the active employees of a batch in ascending order,
+// like the postgres selectBatchEmployees. The caller must hold the lock.
+func (m *inMemory) selectBatchEmployees(txid string, selection models.BatchSelection) ([]int, *employeeerror.EmployeeError) {
+	var ids []int
+	if selection.Query != nil {
+		for id, employee := range m.employees {
+			if matchesEmployeeQuery(employee, *selection.Query) {
+				ids = append(ids, id)
+			}
+		}
+	} else {
+		for _, id := range selection.IDs {
+			empId, _ := strconv.Atoi(id)
+			if employee, ok := m.employees[empId]; ok && employee.DeletedAt == nil {
+				ids = append(ids, empId)
+			}
+		}
+	}
+	sort.Ints(ids)
+
+	if len(ids) > constants.MaxBatchSize {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("filter matches more than %d employees", constants.MaxBatchSize),
+			Trace:   txid,
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordSalaryChange appends a change to the salary history of an employee and returns it with
+// its ID and creation time, the caller must hold the write lock
+func (m *inMemory) recordSalaryChange(change models.SalaryChange) models.SalaryChange {
+	m.lastSalaryID++
+	change.ID = m.lastSalaryID
+	change.CreatedAt = time.Now()
+	if change.Amount != nil {
+		amount := *change.Amount
+		change.Amount = &amount
+	}
+	empId, _ := strconv.Atoi(change.EmployeeID)
+	m.salaryHistory[empId] = append(m.salaryHistory[empId], change)
+	return change
+}
+
+// salaryChanges returns the salary history of an employee newest first, like the postgres
+// implementation orders it, the caller must hold the lock
+func (m *inMemory) salaryChanges(empId int) []models.SalaryChange {
+	changes := slices.Clone(m.salaryHistory[empId])
+	slices.SortStableFunc(changes, func(a, b models.SalaryChange) int {
+		if c := b.EffectiveFrom.Compare(a.EffectiveFrom); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return changes
+}
+
+// applyDueSalary brings the salary of an employee in line with the latest change in effect at
+// asOf and reports whether it changed, the caller must hold the write lock
+func (m *inMemory) applyDueSalary(empId int, asOf time.Time, actor string, transactionID string) bool {
+	employee := m.employees[empId]
+	current := resolveCompensation(employee.ID, m.salaryChanges(empId), asOf).Current
+	if current == nil || current.Amount == nil {
+		return false
+	}
+	salary := models.Money{Amount: *current.Amount, Currency: current.Currency}
+	if employee.Salary != nil && employee.Salary.Equal(salary) {
+		return false
+	}
+
+	before := copyEmployee(employee)
+	employee.Salary = &salary
+	employee.LastUpdatedAt = time.Now()
+	employee.Version++
+	m.employees[empId] = employee
+	m.recordAudit(newAuditEntry(models.AuditUpdate, &before, &employee, actor, transactionID))
+	return true
+}
+
+func (m *inMemory) GetCompensation(ctx *gin.Context, employeeId string, asOf time.Time) (models.Compensation, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	empId, _ := strconv.Atoi(employeeId)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	employee, ok := m.employees[empId]
+	if !ok || employee.DeletedAt != nil {
+		return models.Compensation{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved compensation records from memory")
+	return resolveCompensation(employee.ID, m.salaryChanges(empId), asOf), nil
+}
+
+func (m *inMemory) ScheduleSalaryChange(ctx *gin.Context, change models.SalaryChange) (models.SalaryChange, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	empId, _ := strconv.Atoi(change.EmployeeID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	employee, ok := m.employees[empId]
+	if !ok || employee.DeletedAt != nil {
+		return models.SalaryChange{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+
+	change.EmployeeID = employee.ID
+	change = m.recordSalaryChange(change)
+	if now := time.Now(); !change.EffectiveFrom.After(now) {
+		m.applyDueSalary(empId, now, actorOf(ctx), txid)
+	}
+
+	utils.Log(ctx).Info("Successfully recorded salary change in memory")
+	return change, nil
+}
+
+func (m *inMemory) ApplySalaryChanges(ctx context.Context, asOf time.Time, actor string, transactionID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int
+	for id, employee := range m.employees {
+		if employee.DeletedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	var applied int64
+	for _, id := range ids {
+		if m.applyDueSalary(id, asOf, actor, transactionID) {
+			applied++
+		}
+	}
+	return applied, nil
+}
@@ -0,0 +1,583 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// inMemory is a thread-safe EmployeeDBService kept entirely in process memory.
+// It mirrors the behaviour of the postgres implementation so that the API can
+// be run locally, or exercised in tests, without a database.
+type inMemory struct {
+	mu               sync.RWMutex
+	employees        map[int]models.Employee
+	lastID           int
+	departments      map[int]models.Department
+	lastDepartmentID int
+	audit            []models.AuditEntry
+	lastAuditID      int64
+	salaryHistory    map[int][]models.SalaryChange
+	lastSalaryID     int64
+	// responses of the requests made with an Idempotency-Key, by key
+	idempotentResponses map[string]models.IdempotentResponse
+	// API keys by ID, holding the hash of the key
+	apiKeys      map[int]models.APIKey
+	lastAPIKeyID int
+}
+
+func NewInMemory() *inMemory {
+	return &inMemory{
+		employees:           make(map[int]models.Employee),
+		departments:         make(map[int]models.Department),
+		salaryHistory:       make(map[int][]models.SalaryChange),
+		idempotentResponses: make(map[string]models.IdempotentResponse),
+		apiKeys:             make(map[int]models.APIKey),
+	}
+}
+
+// copyEmployee returns a copy of the employee that does not share any pointer with the original
+func copyEmployee(employee models.Employee) models.Employee {
+	if employee.Salary != nil {
+		salary := *employee.Salary
+		employee.Salary = &salary
+	}
+	if employee.DepartmentID != nil {
+		departmentID := *employee.DepartmentID
+		employee.DepartmentID = &departmentID
+	}
+	if employee.ManagerID != nil {
+		managerID := *employee.ManagerID
+		employee.ManagerID = &managerID
+	}
+	if employee.DeletedAt != nil {
+		deletedAt := *employee.DeletedAt
+		employee.DeletedAt = &deletedAt
+	}
+	return employee
+}
+
+// canonicalID formats a numeric ID the way postgres returns it, so "007" and "7" compare equal
+func canonicalID(id string) string {
+	if n, err := strconv.Atoi(id); err == nil {
+		return strconv.Itoa(n)
+	}
+	return id
+}
+
+// departmentExists emulates the employees.department_id foreign key, the caller must hold the lock
+func (m *inMemory) departmentExists(departmentID *string) bool {
+	if departmentID == nil {
+		return true
+	}
+	deptId, err := strconv.Atoi(*departmentID)
+	if err != nil {
+		return false
+	}
+	_, ok := m.departments[deptId]
+	return ok
+}
+
+// employeeExists emulates the employees.manager_id foreign key together with checkManagerActive,
+// which also rejects soft-deleted managers, the caller must hold the lock
+func (m *inMemory) employeeExists(employeeID *string) bool {
+	if employeeID == nil {
+		return true
+	}
+	empId, err := strconv.Atoi(*employeeID)
+	if err != nil {
+		return false
+	}
+	employee, ok := m.employees[empId]
+	return ok && employee.DeletedAt == nil
+}
+
+// inManagementChain reports whether target is employeeID or one of their managers,
+// the caller must hold the lock
+func (m *inMemory) inManagementChain(employeeID string, target string) bool {
+	visited := make(map[string]bool)
+	for current := employeeID; !visited[current]; {
+		if current == target {
+			return true
+		}
+		visited[current] = true
+		empId, _ := strconv.Atoi(current)
+		employee, ok := m.employees[empId]
+		if !ok || employee.ManagerID == nil {
+			return false
+		}
+		current = *employee.ManagerID
+	}
+	return false
+}
+
+// matchesEmployeeQuery applies the filters of the query like buildEmployeeFilter does in SQL
+func matchesEmployeeQuery(employee models.Employee, query models.EmployeeQuery) bool {
+	if !query.IncludeDeleted && employee.DeletedAt != nil {
+		return false
+	}
+	if query.Position != "" && employee.Position != query.Position {
+		return false
+	}
+	if query.SalaryMin != nil && (employee.Salary == nil || employee.Salary.Amount.Cmp(*query.SalaryMin) < 0) {
+		return false
+	}
+	if query.SalaryMax != nil && (employee.Salary == nil || employee.Salary.Amount.Cmp(*query.SalaryMax) > 0) {
+		return false
+	}
+	if query.CreatedAfter != nil && !employee.CreatedAt.After(*query.CreatedAfter) {
+		return false
+	}
+	if query.NameContains != "" && !strings.Contains(strings.ToLower(employee.Name), strings.ToLower(query.NameContains)) {
+		return false
+	}
+	if query.DepartmentID != nil && (employee.DepartmentID == nil || *employee.DepartmentID != canonicalID(*query.DepartmentID)) {
+		return false
+	}
+	return true
+}
+
+// compareEmployees orders two employees by a single sort field, returning -1, 0 or 1
+func compareEmployees(a, b models.Employee, field string) int {
+	switch field {
+	case "id":
+		x, _ := strconv.Atoi(a.ID)
+		y, _ := strconv.Atoi(b.ID)
+		return cmp.Compare(x, y)
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "position":
+		return strings.Compare(a.Position, b.Position)
+	case "salary":
+		var x, y models.Decimal
+		if a.Salary != nil {
+			x = a.Salary.Amount
+		}
+		if b.Salary != nil {
+			y = b.Salary.Amount
+		}
+		return x.Cmp(y)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case "last_updated_at":
+		return a.LastUpdatedAt.Compare(b.LastUpdatedAt)
+	}
+	return 0
+}
+
+// compareBySortKeys orders two employees by the sort keys, returning -1, 0 or 1
+func compareBySortKeys(a, b models.Employee, keys []models.SortField) int {
+	for _, key := range keys {
+		if c := compareEmployees(a, b, key.Field); c != 0 {
+			if key.Descending {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+// sortEmployees orders employees like buildEmployeeOrder does in SQL, with id as the final key
+func sortEmployees(employees []models.Employee, fields []models.SortField) error {
+	for _, field := range fields {
+		if _, ok := employeeSortColumns[field.Field]; !ok {
+			return fmt.Errorf("cannot sort by %q", field.Field)
+		}
+	}
+	keys := employeeSortKeys(fields)
+	sort.SliceStable(employees, func(i, j int) bool {
+		return compareBySortKeys(employees[i], employees[j], keys) < 0
+	})
+	return nil
+}
+
+// CreateEmployee function
+func (m *inMemory) CreateEmployee(ctx *gin.Context, employee models.Employee) (string, *employeeerror.EmployeeError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	employeeID, employeeErr := m.createEmployee(ctx, employee)
+	if employeeErr != nil {
+		return "", employeeErr
+	}
+
+	utils.Log(ctx).Info("successfully added employee entry in memory")
+	return employeeID, nil
+}
+
+// checkEmployeeReferences verifies that the department and manager of a new employee exist
+func (m *inMemory) checkEmployeeReferences(txid string, employee models.Employee) *employeeerror.EmployeeError {
+	if !m.departmentExists(employee.DepartmentID) {
+		return &employeeerror.EmployeeError{
+			Trace:   txid,
+			Code:    http.StatusBadRequest,
+			Message: "Department not found",
+		}
+	}
+
+	if !m.employeeExists(employee.ManagerID) {
+		return &employeeerror.EmployeeError{
+			Trace:   txid,
+			Code:    http.StatusBadRequest,
+			Message: "Manager not found",
+		}
+	}
+	return nil
+}
+
+// createEmployee adds an employee along with their starting salary and the audit entry of the
+// creation, the caller must hold the write lock
+func (m *inMemory) createEmployee(ctx *gin.Context, employee models.Employee) (string, *employeeerror.EmployeeError) {
+	if referenceErr := m.checkEmployeeReferences(ctx.Request.Header.Get(constants.TransactionID), employee); referenceErr != nil {
+		return "", referenceErr
+	}
+
+	m.lastID++
+	now := time.Now()
+	employee = copyEmployee(employee)
+	employee.ID = strconv.Itoa(m.lastID)
+	if employee.DepartmentID != nil {
+		*employee.DepartmentID = canonicalID(*employee.DepartmentID)
+	}
+	if employee.ManagerID != nil {
+		*employee.ManagerID = canonicalID(*employee.ManagerID)
+	}
+	employee.CreatedAt = now
+	employee.LastUpdatedAt = now
+	employee.Version = 1
+	m.employees[m.lastID] = employee
+	m.recordSalaryChange(newSalaryChange(employee.ID, employee.Salary, now, models.SalaryReasonHire))
+	m.recordAudit(requestAuditEntry(ctx, models.AuditCreate, nil, &employee))
+	return employee.ID, nil
+}
+
+func (m *inMemory) DeleteEmployee(ctx *gin.Context, employeeId string, version int) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	// Like the postgres implementation, deleting an unknown or already deleted ID is not an error
+	empId, _ := strconv.Atoi(employeeId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.employees[empId]
+	if !ok || existing.DeletedAt != nil {
+		return nil
+	}
+	if version != 0 && existing.Version != version {
+		return versionMismatchError(txid)
+	}
+	m.softDeleteEmployee(ctx, existing)
+
+	utils.Log(ctx).Info("Successfully deleted employee entry from memory")
+	return nil
+}
+
+// softDeleteEmployee soft deletes an active employee and detaches its reports like the postgres
+// softDeleteEmployee, the caller must hold the lock
+func (m *inMemory) softDeleteEmployee(ctx *gin.Context, existing models.Employee) models.Employee {
+	empId, _ := strconv.Atoi(existing.ID)
+	before := copyEmployee(existing)
+	now := time.Now()
+	existing.DeletedAt = &now
+	existing.Version++
+	m.employees[empId] = existing
+	m.recordAudit(requestAuditEntry(ctx, models.AuditDelete, &before, &existing))
+
+	// The reports of a deleted employee no longer have a manager
+	var reports []int
+	for id, employee := range m.employees {
+		if employee.ManagerID != nil && *employee.ManagerID == strconv.Itoa(empId) {
+			reports = append(reports, id)
+		}
+	}
+	sort.Ints(reports)
+	for _, id := range reports {
+		employee := m.employees[id]
+		previous := copyEmployee(employee)
+		employee.ManagerID = nil
+		employee.Version++
+		m.employees[id] = employee
+		m.recordAudit(requestAuditEntry(ctx, models.AuditUpdate, &previous, &employee))
+	}
+	return copyEmployee(existing)
+}
+
+func (m *inMemory) RestoreEmployee(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	empId, _ := strconv.Atoi(employeeId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	employee, ok := m.employees[empId]
+	if !ok {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+	if employee.DeletedAt == nil {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusConflict,
+			Message: "Employee is not deleted",
+			Trace:   txid,
+		}
+	}
+	before := copyEmployee(employee)
+	employee.DeletedAt = nil
+	employee.Version++
+	m.employees[empId] = employee
+	m.recordAudit(requestAuditEntry(ctx, models.AuditRestore, &before, &employee))
+
+	utils.Log(ctx).Info("Successfully restored employee entry in memory")
+	return copyEmployee(employee), nil
+}
+
+func (m *inMemory) PurgeEmployees(ctx context.Context, deletedBefore time.Time, actor string, transactionID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged []int
+	for id, employee := range m.employees {
+		if employee.DeletedAt != nil && employee.DeletedAt.Before(deletedBefore) {
+			purged = append(purged, id)
+		}
+	}
+	sort.Ints(purged)
+	for _, id := range purged {
+		employee := m.employees[id]
+		delete(m.employees, id)
+		delete(m.salaryHistory, id)
+		m.recordAudit(newAuditEntry(models.AuditPurge, &employee, nil, actor, transactionID))
+	}
+	return int64(len(purged)), nil
+}
+
+// countActive returns the number of employees that are not soft deleted
+func (m *inMemory) countActive() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var active int64
+	for _, employee := range m.employees {
+		if employee.DeletedAt == nil {
+			active++
+		}
+	}
+	return active
+}
+
+func (m *inMemory) GetEmployeeByID(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	empId, err := strconv.Atoi(employeeId)
+	if err != nil {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid employee ID",
+			Trace:   txid,
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	employee, ok := m.employees[empId]
+	if !ok || employee.DeletedAt != nil {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved employee entry from memory")
+	return copyEmployee(employee), nil
+}
+
+func (m *inMemory) UpdateEmployee(ctx *gin.Context, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	if !hasEmployeeChanges(employee) {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "No fields to update",
+			Trace:   txid,
+		}
+	}
+
+	return m.writeEmployee(ctx, employee, false)
+}
+
+func (m *inMemory) ReplaceEmployee(ctx *gin.Context, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
+	return m.writeEmployee(ctx, employee, true)
+}
+
+func (m *inMemory) writeEmployee(ctx *gin.Context, employee models.Employee, replace bool) (models.Employee, *employeeerror.EmployeeError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated, updateErr := m.updateEmployee(ctx, employee, replace)
+	if updateErr != nil {
+		return models.Employee{}, updateErr
+	}
+
+	utils.Log(ctx).Info("Successfully updated employee entry in memory")
+	return updated, nil
+}
+
+// updateEmployee applies the fields set in employee, or all of them when replace is set, like
+// the postgres updateEmployee. The caller must hold the lock.
+func (m *inMemory) updateEmployee(ctx *gin.Context, employee models.Employee, replace bool) (models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	// A non numeric ID fails the UPDATE statement in postgres
+	empId, err := strconv.Atoi(employee.ID)
+	if err != nil {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to update employee record",
+			Trace:   txid,
+		}
+	}
+
+	existing, ok := m.employees[empId]
+	if !ok || existing.DeletedAt != nil {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+	if employee.Version != 0 && existing.Version != employee.Version {
+		return models.Employee{}, versionMismatchError(txid)
+	}
+
+	if !m.departmentExists(employee.DepartmentID) {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "Department not found",
+			Trace:   txid,
+		}
+	}
+
+	if !m.employeeExists(employee.ManagerID) {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "Manager not found",
+			Trace:   txid,
+		}
+	}
+	if employee.ManagerID != nil && m.inManagementChain(canonicalID(*employee.ManagerID), existing.ID) {
+		return models.Employee{}, managerCycleError(txid)
+	}
+
+	before := copyEmployee(existing)
+	if replace || employee.Name != "" {
+		existing.Name = employee.Name
+	}
+	if replace || employee.Position != "" {
+		existing.Position = employee.Position
+	}
+	if employee.Salary != nil {
+		salary := *employee.Salary
+		existing.Salary = &salary
+	}
+	if replace {
+		existing.DepartmentID, existing.ManagerID = nil, nil
+	}
+	if employee.DepartmentID != nil {
+		departmentID := canonicalID(*employee.DepartmentID)
+		existing.DepartmentID = &departmentID
+	}
+	if employee.ManagerID != nil {
+		managerID := canonicalID(*employee.ManagerID)
+		existing.ManagerID = &managerID
+	}
+	existing.LastUpdatedAt = time.Now()
+	existing.Version++
+	m.employees[empId] = existing
+	if employee.Salary != nil && (before.Salary == nil || !before.Salary.Equal(*employee.Salary)) {
+		m.recordSalaryChange(newSalaryChange(existing.ID, existing.Salary, existing.LastUpdatedAt, models.SalaryReasonUpdate))
+	}
+	m.recordAudit(requestAuditEntry(ctx, models.AuditUpdate, &before, &existing))
+	return copyEmployee(existing), nil
+}
+
+func (m *inMemory) ListEmployee(ctx *gin.Context, query models.EmployeeQuery) (models.EmployeeList, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	// Calculate the offset based on the page number and page size
+	offset := (query.Page - 1) * query.PageSize
+
+	// postgres rejects a negative LIMIT or OFFSET
+	if (offset < 0 && query.Cursor == "") || query.PageSize < 0 {
+		return models.EmployeeList{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve employee records",
+			Trace:   txid,
+		}
+	}
+
+	// A page following a cursor is read in the sort order of the cursor
+	var after models.Employee
+	if query.Cursor != "" {
+		var err error
+		if after, query.Sort, err = decodeEmployeeCursor(query.Cursor, query.Sort); err != nil {
+			return models.EmployeeList{}, &employeeerror.EmployeeError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+				Trace:   txid,
+			}
+		}
+	}
+
+	m.mu.RLock()
+	var matching []models.Employee
+	for _, employee := range m.employees {
+		if matchesEmployeeQuery(employee, query) {
+			matching = append(matching, copyEmployee(employee))
+		}
+	}
+	m.mu.RUnlock()
+
+	if err := sortEmployees(matching, query.Sort); err != nil {
+		return models.EmployeeList{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Trace:   txid,
+		}
+	}
+
+	// Like buildEmployeeKeyset, a cursor starts the page after the last employee it saw
+	if query.Cursor != "" {
+		keys := employeeSortKeys(query.Sort)
+		offset = sort.Search(len(matching), func(i int) bool {
+			return compareBySortKeys(matching[i], after, keys) > 0
+		})
+	}
+
+	// One employee more than the page size tells whether another page follows
+	var employees []models.Employee
+	for i := offset; i < len(matching) && i <= offset+query.PageSize; i++ {
+		employees = append(employees, matching[i])
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved employee records from memory", zap.Int("page", query.Page), zap.Int("page_size", query.PageSize))
+	return employeePage(employees, len(matching), query), nil
+}
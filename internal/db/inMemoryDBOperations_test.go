@@ -0,0 +1,509 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContext() *gin.Context {
+	return &gin.Context{
+		Request: &http.Request{
+			Header: http.Header{
+				constants.TransactionID: []string{"test-transaction-id"},
+			},
+		},
+	}
+}
+
+// decimal parses a decimal number of a test fixture
+func decimal(value string) models.Decimal {
+	d, err := models.ParseDecimal(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// usd returns a salary in US dollars
+func usd(amount string) *models.Money {
+	return &models.Money{Amount: decimal(amount), Currency: "USD"}
+}
+
+func TestInMemory_CreateAndGetEmployee(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	salary := usd("50000")
+	employeeID, employeeErr := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, "1", employeeID)
+
+	// Mutating the caller's salary must not leak into the store
+	salary.Amount = decimal("1")
+
+	employee, employeeErr := m.GetEmployeeByID(ctx, employeeID)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, "John Doe", employee.Name)
+	assert.Equal(t, usd("50000"), employee.Salary)
+	assert.False(t, employee.CreatedAt.IsZero())
+	assert.Equal(t, employee.CreatedAt, employee.LastUpdatedAt)
+
+	secondID, _ := m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Manager", Salary: salary})
+	assert.Equal(t, "2", secondID)
+}
+
+func TestInMemory_GetEmployeeByID_Errors(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	_, employeeErr := m.GetEmployeeByID(ctx, "abc")
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+
+	_, employeeErr = m.GetEmployeeByID(ctx, "42")
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	assert.Equal(t, "Employee not found", employeeErr.Message)
+}
+
+func TestInMemory_UpdateEmployee(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	salary := usd("50000")
+	employeeID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary})
+	before, _ := m.GetEmployeeByID(ctx, employeeID)
+
+	_, employeeErr := m.UpdateEmployee(ctx, models.Employee{ID: employeeID, Position: "Sr. Engineer"})
+	assert.Nil(t, employeeErr)
+
+	after, _ := m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, "John Doe", after.Name)
+	assert.Equal(t, "Sr. Engineer", after.Position)
+	assert.Equal(t, before.CreatedAt, after.CreatedAt)
+	assert.True(t, after.LastUpdatedAt.After(before.LastUpdatedAt) || after.LastUpdatedAt.Equal(before.LastUpdatedAt))
+
+	_, employeeErr = m.UpdateEmployee(ctx, models.Employee{ID: employeeID})
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+
+	_, employeeErr = m.UpdateEmployee(ctx, models.Employee{ID: "42", Name: "Nobody"})
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+}
+
+func TestInMemory_ReplaceEmployee(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	departmentID, _ := m.CreateDepartment(ctx, models.Department{Name: "Payments"})
+	managerID, _ := m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Manager", Salary: usd("60000")})
+	employeeID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: usd("50000"), DepartmentID: &departmentID, ManagerID: &managerID})
+
+	replaced, employeeErr := m.ReplaceEmployee(ctx, models.Employee{ID: employeeID, Name: "John Doe", Position: "Sr. Engineer", Salary: usd("55000")})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, "Sr. Engineer", replaced.Position)
+	assert.Nil(t, replaced.DepartmentID)
+	assert.Nil(t, replaced.ManagerID)
+
+	_, employeeErr = m.ReplaceEmployee(ctx, models.Employee{ID: managerID, Name: "Jane Doe", Position: "Manager", Salary: usd("60000"), ManagerID: &employeeID, Version: 5})
+	assert.Equal(t, http.StatusPreconditionFailed, employeeErr.Code)
+}
+
+func TestInMemory_Version(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	salary := usd("50000")
+	managerID, _ := m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Manager", Salary: salary})
+	employeeID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary, ManagerID: &managerID})
+	employee, _ := m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, 1, employee.Version)
+
+	updated, employeeErr := m.UpdateEmployee(ctx, models.Employee{ID: employeeID, Position: "Sr. Engineer", Version: 1})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, 2, updated.Version)
+
+	// A write based on the stale version is refused
+	_, employeeErr = m.UpdateEmployee(ctx, models.Employee{ID: employeeID, Position: "Staff Engineer", Version: 1})
+	assert.Equal(t, http.StatusPreconditionFailed, employeeErr.Code)
+	assert.Equal(t, http.StatusPreconditionFailed, m.DeleteEmployee(ctx, employeeID, 1).Code)
+
+	// Detaching the reports of a deleted manager is a write too
+	assert.Nil(t, m.DeleteEmployee(ctx, managerID, 1))
+	employee, _ = m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, 3, employee.Version)
+}
+
+func TestInMemory_DeleteEmployee(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	salary := usd("50000")
+	employeeID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary})
+
+	assert.Nil(t, m.DeleteEmployee(ctx, employeeID, 0))
+	_, employeeErr := m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+
+	// IDs are never reused after a delete
+	nextID, _ := m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Engineer", Salary: salary})
+	assert.Equal(t, "2", nextID)
+}
+
+func TestInMemory_SoftDelete(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	salary := usd("50000")
+	departmentID, _ := m.CreateDepartment(ctx, models.Department{Name: "Payments"})
+	managerID, _ := m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Manager", Salary: salary})
+	employeeID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary, ManagerID: &managerID, DepartmentID: &departmentID})
+
+	assert.Nil(t, m.DeleteEmployee(ctx, employeeID, 0))
+	_, employeeErr := m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	_, employeeErr = m.UpdateEmployee(ctx, models.Employee{ID: employeeID, Name: "Ghost"})
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	reports, _ := m.ListReports(ctx, managerID, 0)
+	assert.Empty(t, reports)
+
+	list, _ := m.ListEmployee(ctx, models.EmployeeQuery{Page: 1, PageSize: 10})
+	assert.Equal(t, 1, list.TotalCount)
+	list, _ = m.ListEmployee(ctx, models.EmployeeQuery{IncludeDeleted: true, Page: 1, PageSize: 10})
+	assert.Equal(t, 2, list.TotalCount)
+	assert.NotNil(t, list.Employees[1].DeletedAt)
+
+	// A deleted employee cannot become a manager
+	_, employeeErr = m.CreateEmployee(ctx, models.Employee{Name: "Jim Doe", Position: "Engineer", Salary: salary, ManagerID: &employeeID})
+	assert.Equal(t, "Manager not found", employeeErr.Message)
+
+	restored, employeeErr := m.RestoreEmployee(ctx, employeeID)
+	assert.Nil(t, employeeErr)
+	assert.Nil(t, restored.DeletedAt)
+	_, employeeErr = m.RestoreEmployee(ctx, employeeID)
+	assert.Equal(t, http.StatusConflict, employeeErr.Code)
+
+	// Deleted members do not keep a department alive
+	assert.Nil(t, m.DeleteEmployee(ctx, employeeID, 0))
+	assert.Nil(t, m.DeleteDepartment(ctx, departmentID))
+
+	purged, err := m.PurgeEmployees(context.Background(), time.Now().Add(-time.Hour), "system", "txid")
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+	purged, _ = m.PurgeEmployees(context.Background(), time.Now().Add(time.Second), "system", "txid")
+	assert.Equal(t, int64(1), purged)
+	_, employeeErr = m.RestoreEmployee(ctx, employeeID)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+}
+
+func TestInMemory_ListEmployee(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	salary := usd("50000")
+	for i := 1; i <= 5; i++ {
+		_, _ = m.CreateEmployee(ctx, models.Employee{Name: fmt.Sprintf("Employee %d", i), Position: "Engineer", Salary: salary})
+	}
+
+	list, employeeErr := m.ListEmployee(ctx, models.EmployeeQuery{Page: 2, PageSize: 2})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, 5, list.TotalCount)
+	assert.Len(t, list.Employees, 2)
+	assert.Equal(t, "3", list.Employees[0].ID)
+	assert.Equal(t, "4", list.Employees[1].ID)
+
+	list, employeeErr = m.ListEmployee(ctx, models.EmployeeQuery{Page: 3, PageSize: 2})
+	assert.Nil(t, employeeErr)
+	assert.Len(t, list.Employees, 1)
+
+	list, employeeErr = m.ListEmployee(ctx, models.EmployeeQuery{Page: 4, PageSize: 2})
+	assert.Nil(t, employeeErr)
+	assert.Empty(t, list.Employees)
+	assert.Equal(t, 5, list.TotalCount)
+
+	_, employeeErr = m.ListEmployee(ctx, models.EmployeeQuery{Page: 0, PageSize: 2})
+	assert.Equal(t, http.StatusInternalServerError, employeeErr.Code)
+}
+
+func TestInMemory_ListEmployee_FilterAndSort(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	for _, e := range []struct {
+		name     string
+		position string
+		salary   string
+	}{
+		{"Alice Smith", "Engineer", "70000"},
+		{"Bob Stone", "Manager", "90000"},
+		{"Carol 50% Jones", "Engineer", "50000"},
+		{"Dave Smithers", "Engineer", "70000"},
+	} {
+		_, _ = m.CreateEmployee(ctx, models.Employee{Name: e.name, Position: e.position, Salary: usd(e.salary)})
+	}
+
+	salaryMin := decimal("60000")
+	list, employeeErr := m.ListEmployee(ctx, models.EmployeeQuery{
+		Position:  "Engineer",
+		SalaryMin: &salaryMin,
+		Sort:      []models.SortField{{Field: "salary", Descending: true}, {Field: "name", Descending: true}},
+		Page:      1,
+		PageSize:  10,
+	})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, 2, list.TotalCount)
+	assert.Equal(t, "Dave Smithers", list.Employees[0].Name)
+	assert.Equal(t, "Alice Smith", list.Employees[1].Name)
+
+	// Search is case insensitive and treats wildcards literally
+	list, _ = m.ListEmployee(ctx, models.EmployeeQuery{NameContains: "SMITH", Page: 1, PageSize: 10})
+	assert.Equal(t, 2, list.TotalCount)
+	list, _ = m.ListEmployee(ctx, models.EmployeeQuery{NameContains: "50%", Page: 1, PageSize: 10})
+	assert.Equal(t, 1, list.TotalCount)
+	list, _ = m.ListEmployee(ctx, models.EmployeeQuery{NameContains: "%", Page: 1, PageSize: 10})
+	assert.Equal(t, 1, list.TotalCount)
+
+	// Equal sort keys fall back to id
+	list, _ = m.ListEmployee(ctx, models.EmployeeQuery{Sort: []models.SortField{{Field: "position"}}, Page: 1, PageSize: 10})
+	assert.Equal(t, []string{"1", "3", "4", "2"}, []string{list.Employees[0].ID, list.Employees[1].ID, list.Employees[2].ID, list.Employees[3].ID})
+
+	future := time.Now().Add(time.Hour)
+	list, _ = m.ListEmployee(ctx, models.EmployeeQuery{CreatedAfter: &future, Page: 1, PageSize: 10})
+	assert.Equal(t, 0, list.TotalCount)
+}
+
+func TestInMemory_ListEmployee_Cursor(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	for i := 1; i <= 5; i++ {
+		salary := usd(strconv.Itoa(i % 2 * 1000))
+		_, _ = m.CreateEmployee(ctx, models.Employee{Name: fmt.Sprintf("Employee %d", i), Position: "Engineer", Salary: salary})
+	}
+
+	// Walk the listing by salary, an employee added mid-walk before the cursor is not repeated
+	sort := []models.SortField{{Field: "salary", Descending: true}}
+	list, employeeErr := m.ListEmployee(ctx, models.EmployeeQuery{Sort: sort, Page: 1, PageSize: 2})
+	assert.Nil(t, employeeErr)
+	assert.True(t, list.HasMore)
+	seen := []string{list.Employees[0].ID, list.Employees[1].ID}
+
+	salary := usd("5000")
+	_, _ = m.CreateEmployee(ctx, models.Employee{Name: "Late Joiner", Position: "Engineer", Salary: salary})
+
+	// The following pages are read in the sort order of the cursor, which need not be repeated
+	for list.HasMore {
+		list, employeeErr = m.ListEmployee(ctx, models.EmployeeQuery{Cursor: list.NextCursor, PageSize: 2})
+		assert.Nil(t, employeeErr)
+		for _, employee := range list.Employees {
+			seen = append(seen, employee.ID)
+		}
+	}
+	assert.Equal(t, []string{"1", "3", "5", "2", "4"}, seen)
+	assert.Empty(t, list.NextCursor)
+
+	_, employeeErr = m.ListEmployee(ctx, models.EmployeeQuery{Cursor: "bogus", PageSize: 2})
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+
+	// An explicit sort order must be the one of the cursor
+	list, _ = m.ListEmployee(ctx, models.EmployeeQuery{Sort: sort, Page: 1, PageSize: 2})
+	_, employeeErr = m.ListEmployee(ctx, models.EmployeeQuery{Sort: []models.SortField{{Field: "name"}}, Cursor: list.NextCursor, PageSize: 2})
+	assert.Equal(t, http.StatusBadRequest, employeeErr.Code)
+	assert.Equal(t, "cursor was issued for a different sort order", employeeErr.Message)
+}
+
+func TestInMemory_ConcurrentCreate(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+
+	salary := usd("50000")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = m.CreateEmployee(newTestContext(), models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary})
+		}()
+	}
+	wg.Wait()
+
+	list, _ := m.ListEmployee(newTestContext(), models.EmployeeQuery{Page: 1, PageSize: 100})
+	assert.Len(t, list.Employees, 50)
+}
+
+func TestInMemory_Audit(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+	ctx.Request.Header.Set(constants.TransactionID, "audit-txid")
+
+	salary := usd("50000")
+	managerID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Manager", Salary: salary})
+	reportID, _ := m.CreateEmployee(ctx, models.Employee{Name: "Jane Doe", Position: "Engineer", Salary: salary, ManagerID: &managerID})
+	_, employeeErr := m.UpdateEmployee(ctx, models.Employee{ID: reportID, Position: "Senior Engineer"})
+	assert.Nil(t, employeeErr)
+
+	// Deleting the manager also records the report losing its manager
+	assert.Nil(t, m.DeleteEmployee(ctx, managerID, 0))
+
+	history, employeeErr := m.ListAudit(ctx, models.AuditQuery{EmployeeID: &reportID, Page: 1, PageSize: 10})
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, 3, history.TotalCount)
+	assert.Equal(t, []string{models.AuditUpdate, models.AuditUpdate, models.AuditCreate},
+		[]string{history.Entries[0].Action, history.Entries[1].Action, history.Entries[2].Action})
+	assert.Nil(t, history.Entries[2].Before)
+
+	var before, after models.Employee
+	assert.NoError(t, json.Unmarshal(history.Entries[0].Before, &before))
+	assert.NoError(t, json.Unmarshal(history.Entries[0].After, &after))
+	assert.Equal(t, managerID, *before.ManagerID)
+	assert.Nil(t, after.ManagerID)
+	assert.Equal(t, constants.UnknownActor, history.Entries[0].Actor)
+	assert.Equal(t, "audit-txid", history.Entries[0].TransactionID)
+
+	// The history outlives a purge, which is recorded under the given actor
+	purged, _ := m.PurgeEmployees(context.Background(), time.Now().Add(time.Second), constants.SystemActor, "purge-txid")
+	assert.Equal(t, int64(1), purged)
+	history, _ = m.ListAudit(ctx, models.AuditQuery{EmployeeID: &managerID, Page: 1, PageSize: 10})
+	assert.Equal(t, []string{models.AuditPurge, models.AuditDelete, models.AuditCreate},
+		[]string{history.Entries[0].Action, history.Entries[1].Action, history.Entries[2].Action})
+	assert.Nil(t, history.Entries[0].After)
+
+	audit, _ := m.ListAudit(ctx, models.AuditQuery{Actor: constants.SystemActor, Page: 1, PageSize: 10})
+	assert.Equal(t, 1, audit.TotalCount)
+	assert.Equal(t, "purge-txid", audit.Entries[0].TransactionID)
+
+	// The time range includes from and excludes to
+	from := time.Now()
+	audit, _ = m.ListAudit(ctx, models.AuditQuery{From: &from, Page: 1, PageSize: 10})
+	assert.Zero(t, audit.TotalCount)
+	audit, _ = m.ListAudit(ctx, models.AuditQuery{To: &from, Page: 2, PageSize: 4})
+	assert.Equal(t, 6, audit.TotalCount)
+	assert.Len(t, audit.Entries, 2)
+	assert.Equal(t, int64(2), audit.Entries[0].ID)
+}
+
+func TestInMemory_Compensation(t *testing.T) {
+	utils.InitLogClient()
+	m := NewInMemory()
+	ctx := newTestContext()
+
+	salary := usd("50000")
+	employeeID, _ := m.CreateEmployee(ctx, models.Employee{Name: "John Doe", Position: "Engineer", Salary: salary})
+
+	// An update appends to the history rather than losing the old salary
+	_, employeeErr := m.UpdateEmployee(ctx, models.Employee{ID: employeeID, Salary: usd("55000")})
+	assert.Nil(t, employeeErr)
+
+	// A scheduled raise is not in effect yet
+	scheduled := decimal("60000")
+	_, employeeErr = m.ScheduleSalaryChange(ctx, models.SalaryChange{EmployeeID: employeeID, Amount: &scheduled, Currency: "USD", EffectiveFrom: time.Now().Add(time.Hour), Reason: "promotion"})
+	assert.Nil(t, employeeErr)
+
+	compensation, employeeErr := m.GetCompensation(ctx, employeeID, time.Now())
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, decimal("55000"), *compensation.Current.Amount)
+	assert.Equal(t, []string{models.SalaryReasonUpdate, models.SalaryReasonHire},
+		[]string{compensation.History[0].Reason, compensation.History[1].Reason})
+	assert.Len(t, compensation.Scheduled, 1)
+
+	// A backdated change does not replace a later one in effect
+	backdated := decimal("40000")
+	_, employeeErr = m.ScheduleSalaryChange(ctx, models.SalaryChange{EmployeeID: employeeID, Amount: &backdated, Currency: "USD", EffectiveFrom: time.Now().AddDate(-1, 0, 0)})
+	assert.Nil(t, employeeErr)
+	employee, _ := m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, usd("55000"), employee.Salary)
+
+	// Once due, the raise becomes the salary of the employee
+	applied, _ := m.ApplySalaryChanges(context.Background(), time.Now().Add(2*time.Hour), constants.SystemActor, "txid")
+	assert.Equal(t, int64(1), applied)
+	employee, _ = m.GetEmployeeByID(ctx, employeeID)
+	assert.Equal(t, usd("60000"), employee.Salary)
+	assert.Equal(t, 3, employee.Version)
+	applied, _ = m.ApplySalaryChanges(context.Background(), time.Now().Add(2*time.Hour), constants.SystemActor, "txid")
+	assert.Zero(t, applied)
+
+	_, employeeErr = m.GetCompensation(ctx, "99", time.Now())
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+}
+
+// newTenantContext returns a test context of a request made for the given tenant
+func newTenantContext(tenant string) *gin.Context {
+	ctx := newTestContext()
+	ctx.Set(constants.TenantKey, tenant)
+	return ctx
+}
+
+func TestInMemory_Tenants(t *testing.T) {
+	utils.InitLogClient()
+	m := newInMemoryTenants()
+	acme, globex := newTenantContext("acme"), newTenantContext("globex")
+
+	employeeID, _ := m.CreateEmployee(acme, models.Employee{Name: "John Doe", Position: "Engineer", Salary: usd("50000")})
+
+	// Another tenant does not reach the employee, whatever ID it asks for
+	_, employeeErr := m.GetEmployeeByID(globex, employeeID)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	assert.Nil(t, m.DeleteEmployee(globex, employeeID, 0))
+	list, _ := m.ListEmployee(globex, models.EmployeeQuery{Page: 1, PageSize: 10})
+	assert.Empty(t, list.Employees)
+
+	employee, employeeErr := m.GetEmployeeByID(acme, employeeID)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, "John Doe", employee.Name)
+
+	// An API key is found whatever the tenant of the request, and carries its own tenant
+	_, _ = m.CreateAPIKey(globex, models.APIKey{Name: "payroll", KeyHash: "hash"})
+	key, ok, _ := m.UseAPIKey(newTestContext(), "hash")
+	assert.True(t, ok)
+	assert.Equal(t, "globex", key.TenantID)
+
+	counts, _ := m.CountActiveEmployees(context.Background())
+	assert.Equal(t, map[string]int64{"acme": 1}, counts)
+
+	// The background jobs go through every tenant
+	_ = m.DeleteEmployee(acme, employeeID, 0)
+	purged, _ := m.PurgeEmployees(context.Background(), time.Now().Add(time.Hour), constants.SystemActor, "txid")
+	assert.Equal(t, int64(1), purged)
+}
+
+func TestInMemory_PurgeTenantEmployees(t *testing.T) {
+	utils.InitLogClient()
+	m := newInMemoryTenants()
+	acme, globex := newTenantContext("acme"), newTenantContext("globex")
+
+	acmeID, _ := m.CreateEmployee(acme, models.Employee{Name: "John Doe", Position: "Engineer", Salary: usd("50000")})
+	globexID, _ := m.CreateEmployee(globex, models.Employee{Name: "Jane Roe", Position: "Engineer", Salary: usd("50000")})
+	_ = m.DeleteEmployee(acme, acmeID, 0)
+	_ = m.DeleteEmployee(globex, globexID, 0)
+
+	// The purge of a request leaves the deleted employees of the other tenants in place
+	purged, employeeErr := m.PurgeTenantEmployees(acme, time.Now().Add(time.Hour))
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, int64(1), purged)
+
+	_, employeeErr = m.RestoreEmployee(acme, acmeID)
+	assert.Equal(t, http.StatusNotFound, employeeErr.Code)
+	employee, employeeErr := m.RestoreEmployee(globex, globexID)
+	assert.Nil(t, employeeErr)
+	assert.Equal(t, "Jane Roe", employee.Name)
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// departmentNameTaken emulates the unique constraint on departments.name, the caller must hold the lock
+func (m *inMemory) departmentNameTaken(name string, exceptID int) bool {
+	for id, department := range m.departments {
+		if id != exceptID && department.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateDepartment function
+func (m *inMemory) CreateDepartment(ctx *gin.Context, department models.Department) (string, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.departmentNameTaken(department.Name, 0) {
+		return "", &employeeerror.EmployeeError{
+			Trace:   txid,
+			Code:    http.StatusConflict,
+			Message: "Department already exists",
+		}
+	}
+
+	m.lastDepartmentID++
+	now := time.Now()
+	department.ID = strconv.Itoa(m.lastDepartmentID)
+	department.CreatedAt = now
+	department.LastUpdatedAt = now
+	m.departments[m.lastDepartmentID] = department
+
+	utils.Log(ctx).Info("successfully added department entry in memory")
+	return department.ID, nil
+}
+
+func (m *inMemory) GetDepartmentByID(ctx *gin.Context, departmentId string) (models.Department, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	deptId, err := strconv.Atoi(departmentId)
+	if err != nil {
+		return models.Department{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid department ID",
+			Trace:   txid,
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	department, ok := m.departments[deptId]
+	if !ok {
+		return models.Department{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Department not found",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved department entry from memory")
+	return department, nil
+}
+
+func (m *inMemory) UpdateDepartment(ctx *gin.Context, department models.Department) (models.Department, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	deptId, _ := strconv.Atoi(department.ID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.departments[deptId]
+	if !ok {
+		return models.Department{}, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Department not found",
+			Trace:   txid,
+		}
+	}
+	if m.departmentNameTaken(department.Name, deptId) {
+		return models.Department{}, &employeeerror.EmployeeError{
+			Code:    http.StatusConflict,
+			Message: "Department already exists",
+			Trace:   txid,
+		}
+	}
+
+	existing.Name = department.Name
+	existing.Description = department.Description
+	existing.LastUpdatedAt = time.Now()
+	m.departments[deptId] = existing
+
+	utils.Log(ctx).Info("Successfully updated department entry in memory")
+	return existing, nil
+}
+
+func (m *inMemory) DeleteDepartment(ctx *gin.Context, departmentId string) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	deptId, _ := strconv.Atoi(departmentId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members := 0
+	for _, employee := range m.employees {
+		if employee.DepartmentID != nil && *employee.DepartmentID == strconv.Itoa(deptId) && employee.DeletedAt == nil {
+			members++
+		}
+	}
+	if members > 0 {
+		return departmentNotEmptyError(txid, members)
+	}
+
+	// Soft-deleted employees lose the department instead of keeping it alive
+	for id, employee := range m.employees {
+		if employee.DepartmentID != nil && *employee.DepartmentID == strconv.Itoa(deptId) {
+			employee.DepartmentID = nil
+			employee.Version++
+			m.employees[id] = employee
+		}
+	}
+	delete(m.departments, deptId)
+
+	utils.Log(ctx).Info("Successfully deleted department entry from memory")
+	return nil
+}
+
+func (m *inMemory) ListDepartments(ctx *gin.Context, page int, pageSize int) ([]models.Department, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	offset := (page - 1) * pageSize
+	if offset < 0 || pageSize < 0 {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to retrieve department records",
+			Trace:   txid,
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]int, 0, len(m.departments))
+	for id := range m.departments {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var departments []models.Department
+	for i := offset; i < len(ids) && i < offset+pageSize; i++ {
+		departments = append(departments, m.departments[ids[i]])
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved department records from memory", zap.Int("page", page), zap.Int("page_size", pageSize))
+	return departments, nil
+}
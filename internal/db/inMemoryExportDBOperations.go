@@ -0,0 +1,48 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (m *inMemory) ExportEmployees(ctx *gin.Context, query models.EmployeeQuery, emit func(models.Employee) error) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	// Like the snapshot of the postgres export, later writes do not show up in the export
+	m.mu.RLock()
+	var matching []models.Employee
+	for _, employee := range m.employees {
+		if matchesEmployeeQuery(employee, query) {
+			matching = append(matching, copyEmployee(employee))
+		}
+	}
+	m.mu.RUnlock()
+
+	if err := sortEmployees(matching, query.Sort); err != nil {
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Trace:   txid,
+		}
+	}
+
+	for _, employee := range matching {
+		if err := emit(employee); err != nil {
+			utils.Log(ctx).Error("Error writing exported employee", zap.Error(err))
+			return &employeeerror.EmployeeError{
+				Code:    http.StatusInternalServerError,
+				Message: "Unable to export employee records",
+				Trace:   txid,
+			}
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully exported employee records from memory", zap.Int("employees", len(matching)))
+	return nil
+}
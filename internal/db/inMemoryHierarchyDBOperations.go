@@ -0,0 +1,129 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reportsByManager indexes the employees that are not soft deleted by manager ID ordered by
+// their own ID, the caller must hold the lock
+func (m *inMemory) reportsByManager() map[string][]models.Employee {
+	ids := make([]int, 0, len(m.employees))
+	for id := range m.employees {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	byManager := make(map[string][]models.Employee)
+	for _, id := range ids {
+		employee := m.employees[id]
+		if employee.DeletedAt != nil {
+			continue
+		}
+		managerID := ""
+		if employee.ManagerID != nil {
+			managerID = *employee.ManagerID
+		}
+		byManager[managerID] = append(byManager[managerID], copyEmployee(employee))
+	}
+	return byManager
+}
+
+func (m *inMemory) ListReports(ctx *gin.Context, employeeId string, depth int) ([]models.EmployeeReport, *employeeerror.EmployeeError) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byManager := m.reportsByManager()
+
+	// Breadth first, so reports come out ordered by level and then ID like the recursive CTE
+	var reports []models.EmployeeReport
+	visited := map[string]bool{canonicalID(employeeId): true}
+	level := []string{canonicalID(employeeId)}
+	for current := 1; len(level) > 0 && (depth == 0 || current <= depth); current++ {
+		var levelReports []models.EmployeeReport
+		for _, managerID := range level {
+			for _, employee := range byManager[managerID] {
+				if !visited[employee.ID] {
+					visited[employee.ID] = true
+					levelReports = append(levelReports, models.EmployeeReport{Employee: employee, Level: current})
+				}
+			}
+		}
+		sort.Slice(levelReports, func(i, j int) bool {
+			a, _ := strconv.Atoi(levelReports[i].ID)
+			b, _ := strconv.Atoi(levelReports[j].ID)
+			return a < b
+		})
+
+		level = nil
+		for _, report := range levelReports {
+			reports = append(reports, report)
+			level = append(level, report.ID)
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved employee reports from memory")
+	return reports, nil
+}
+
+func (m *inMemory) GetManagementChain(ctx *gin.Context, employeeId string) ([]models.Employee, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var chain []models.Employee
+	visited := make(map[int]bool)
+	empId, _ := strconv.Atoi(employeeId)
+	for !visited[empId] {
+		employee, ok := m.employees[empId]
+		if !ok || employee.DeletedAt != nil {
+			break
+		}
+		visited[empId] = true
+		chain = append(chain, copyEmployee(employee))
+		if employee.ManagerID == nil {
+			break
+		}
+		empId, _ = strconv.Atoi(*employee.ManagerID)
+	}
+
+	if len(chain) == 0 {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusNotFound,
+			Message: "Employee not found",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved management chain from memory")
+	return chain, nil
+}
+
+func (m *inMemory) GetOrgChart(ctx *gin.Context) ([]*models.OrgChartNode, *employeeerror.EmployeeError) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Flatten the tree top down so that managers precede their reports, as buildOrgChart expects
+	byManager := m.reportsByManager()
+	var employees []models.Employee
+	level := byManager[""]
+	for len(level) > 0 {
+		var next []models.Employee
+		for _, employee := range level {
+			employees = append(employees, employee)
+			next = append(next, byManager[employee.ID]...)
+		}
+		level = next
+	}
+
+	utils.Log(ctx).Info("Successfully retrieved org chart from memory")
+	return buildOrgChart(employees), nil
+}
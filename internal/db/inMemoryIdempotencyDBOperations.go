@@ -0,0 +1,65 @@
+package db
+
+import (
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (m *inMemory) ReserveIdempotencyKey(ctx *gin.Context, reservation models.IdempotentResponse) (models.IdempotentResponse, bool, *employeeerror.EmployeeError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.idempotentResponses[reservation.Key]; ok && stored.ExpiresAt.After(time.Now()) {
+		stored.Body = slices.Clone(stored.Body)
+		return stored, false, nil
+	}
+	reservation.StatusCode = 0
+	reservation.Body = nil
+	m.idempotentResponses[reservation.Key] = reservation
+	return reservation, true, nil
+}
+
+func (m *inMemory) SaveIdempotentResponse(ctx *gin.Context, response models.IdempotentResponse) *employeeerror.EmployeeError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.idempotentResponses[response.Key]
+	if !ok || stored.RequestHash != response.RequestHash {
+		return nil
+	}
+	stored.StatusCode = response.StatusCode
+	stored.ContentType = response.ContentType
+	stored.ETag = response.ETag
+	stored.Body = slices.Clone(response.Body)
+	m.idempotentResponses[response.Key] = stored
+	return nil
+}
+
+func (m *inMemory) ReleaseIdempotencyKey(ctx *gin.Context, key string) *employeeerror.EmployeeError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.idempotentResponses[key]; ok && stored.StatusCode == 0 {
+		delete(m.idempotentResponses, key)
+	}
+	return nil
+}
+
+func (m *inMemory) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int64
+	for key, stored := range m.idempotentResponses {
+		if !stored.ExpiresAt.After(expiredBefore) {
+			delete(m.idempotentResponses, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
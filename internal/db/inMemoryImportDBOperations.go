@@ -0,0 +1,40 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (m *inMemory) ImportEmployees(ctx *gin.Context, rows []models.ImportRow, atomic bool) ([]models.ImportResult, *employeeerror.EmployeeError) {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Rows can only refer to records that already exist, so checking every row up front is
+	// enough for an atomic import to create none of them when one is rejected
+	if atomic {
+		for _, row := range rows {
+			if referenceErr := m.checkEmployeeReferences(txid, row.Employee); referenceErr != nil {
+				return []models.ImportResult{{Line: row.Line, Error: referenceErr.Message}}, nil
+			}
+		}
+	}
+
+	results := make([]models.ImportResult, 0, len(rows))
+	for _, row := range rows {
+		employeeID, employeeErr := m.createEmployee(ctx, row.Employee)
+		if employeeErr != nil {
+			results = append(results, models.ImportResult{Line: row.Line, Error: employeeErr.Message})
+			continue
+		}
+		results = append(results, models.ImportResult{Line: row.Line, EmployeeID: employeeID})
+	}
+
+	utils.Log(ctx).Info("successfully imported employee entries in memory")
+	return results, nil
+}
@@ -0,0 +1,260 @@
+package db
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// inMemoryTenants is the in-memory EmployeeDBService of a multi-tenant API. Every tenant has a
+// store of its own, created on first use, so a request never sees the records of another tenant
+// whatever ID it asks for. The background jobs work on the stores of every tenant.
+type inMemoryTenants struct {
+	mu     sync.Mutex
+	stores map[string]*inMemory
+}
+
+func newInMemoryTenants() *inMemoryTenants {
+	return &inMemoryTenants{stores: make(map[string]*inMemory)}
+}
+
+// store returns the store of the tenant of the request
+func (t *inMemoryTenants) store(ctx *gin.Context) *inMemory {
+	tenant := tenantOf(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	store, ok := t.stores[tenant]
+	if !ok {
+		store = NewInMemory()
+		t.stores[tenant] = store
+	}
+	return store
+}
+
+// tenants returns the stores of every tenant by tenant, sorted so that the jobs go through them in
+// the same order every time
+func (t *inMemoryTenants) tenants() ([]string, map[string]*inMemory) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stores := make(map[string]*inMemory, len(t.stores))
+	names := make([]string, 0, len(t.stores))
+	for tenant, store := range t.stores {
+		stores[tenant] = store
+		names = append(names, tenant)
+	}
+	sort.Strings(names)
+	return names, stores
+}
+
+func (t *inMemoryTenants) CreateEmployee(ctx *gin.Context, employee models.Employee) (string, *employeeerror.EmployeeError) {
+	return t.store(ctx).CreateEmployee(ctx, employee)
+}
+
+func (t *inMemoryTenants) DeleteEmployee(ctx *gin.Context, employeeId string, version int) *employeeerror.EmployeeError {
+	return t.store(ctx).DeleteEmployee(ctx, employeeId, version)
+}
+
+func (t *inMemoryTenants) GetEmployeeByID(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
+	return t.store(ctx).GetEmployeeByID(ctx, employeeId)
+}
+
+func (t *inMemoryTenants) UpdateEmployee(ctx *gin.Context, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
+	return t.store(ctx).UpdateEmployee(ctx, employee)
+}
+
+func (t *inMemoryTenants) ReplaceEmployee(ctx *gin.Context, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
+	return t.store(ctx).ReplaceEmployee(ctx, employee)
+}
+
+func (t *inMemoryTenants) ListEmployee(ctx *gin.Context, query models.EmployeeQuery) (models.EmployeeList, *employeeerror.EmployeeError) {
+	return t.store(ctx).ListEmployee(ctx, query)
+}
+
+func (t *inMemoryTenants) RestoreEmployee(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
+	return t.store(ctx).RestoreEmployee(ctx, employeeId)
+}
+
+func (t *inMemoryTenants) PurgeEmployees(ctx context.Context, deletedBefore time.Time, actor string, transactionID string) (int64, error) {
+	var purged int64
+	names, stores := t.tenants()
+	for _, tenant := range names {
+		n, err := stores[tenant].PurgeEmployees(ctx, deletedBefore, actor, transactionID)
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+	return purged, nil
+}
+
+// PurgeTenantEmployees purges the store of the tenant of the request only
+func (t *inMemoryTenants) PurgeTenantEmployees(ctx *gin.Context, deletedBefore time.Time) (int64, *employeeerror.EmployeeError) {
+	// The purge of a store cannot fail
+	purged, _ := t.store(ctx).PurgeEmployees(ctx, deletedBefore, actorOf(ctx), ctx.Request.Header.Get(constants.TransactionID))
+	return purged, nil
+}
+
+// CountActiveEmployees returns the number of employees that are not soft deleted, by tenant
+func (t *inMemoryTenants) CountActiveEmployees(ctx context.Context) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	names, stores := t.tenants()
+	for _, tenant := range names {
+		if active := stores[tenant].countActive(); active > 0 {
+			counts[tenant] = active
+		}
+	}
+	return counts, nil
+}
+
+func (t *inMemoryTenants) ImportEmployees(ctx *gin.Context, rows []models.ImportRow, atomic bool) ([]models.ImportResult, *employeeerror.EmployeeError) {
+	return t.store(ctx).ImportEmployees(ctx, rows, atomic)
+}
+
+func (t *inMemoryTenants) ExportEmployees(ctx *gin.Context, query models.EmployeeQuery, emit func(models.Employee) error) *employeeerror.EmployeeError {
+	return t.store(ctx).ExportEmployees(ctx, query, emit)
+}
+
+func (t *inMemoryTenants) BatchUpdateEmployees(ctx *gin.Context, selection models.BatchSelection, update models.Employee, dryRun bool) ([]models.Employee, *employeeerror.EmployeeError) {
+	return t.store(ctx).BatchUpdateEmployees(ctx, selection, update, dryRun)
+}
+
+func (t *inMemoryTenants) BatchDeleteEmployees(ctx *gin.Context, selection models.BatchSelection, dryRun bool) ([]models.Employee, *employeeerror.EmployeeError) {
+	return t.store(ctx).BatchDeleteEmployees(ctx, selection, dryRun)
+}
+
+func (t *inMemoryTenants) CreateDepartment(ctx *gin.Context, department models.Department) (string, *employeeerror.EmployeeError) {
+	return t.store(ctx).CreateDepartment(ctx, department)
+}
+
+func (t *inMemoryTenants) GetDepartmentByID(ctx *gin.Context, departmentId string) (models.Department, *employeeerror.EmployeeError) {
+	return t.store(ctx).GetDepartmentByID(ctx, departmentId)
+}
+
+func (t *inMemoryTenants) UpdateDepartment(ctx *gin.Context, department models.Department) (models.Department, *employeeerror.EmployeeError) {
+	return t.store(ctx).UpdateDepartment(ctx, department)
+}
+
+func (t *inMemoryTenants) DeleteDepartment(ctx *gin.Context, departmentId string) *employeeerror.EmployeeError {
+	return t.store(ctx).DeleteDepartment(ctx, departmentId)
+}
+
+func (t *inMemoryTenants) ListDepartments(ctx *gin.Context, page int, pageSize int) ([]models.Department, *employeeerror.EmployeeError) {
+	return t.store(ctx).ListDepartments(ctx, page, pageSize)
+}
+
+func (t *inMemoryTenants) ListReports(ctx *gin.Context, employeeId string, depth int) ([]models.EmployeeReport, *employeeerror.EmployeeError) {
+	return t.store(ctx).ListReports(ctx, employeeId, depth)
+}
+
+func (t *inMemoryTenants) GetManagementChain(ctx *gin.Context, employeeId string) ([]models.Employee, *employeeerror.EmployeeError) {
+	return t.store(ctx).GetManagementChain(ctx, employeeId)
+}
+
+func (t *inMemoryTenants) GetOrgChart(ctx *gin.Context) ([]*models.OrgChartNode, *employeeerror.EmployeeError) {
+	return t.store(ctx).GetOrgChart(ctx)
+}
+
+func (t *inMemoryTenants) ListAudit(ctx *gin.Context, query models.AuditQuery) (models.AuditList, *employeeerror.EmployeeError) {
+	return t.store(ctx).ListAudit(ctx, query)
+}
+
+func (t *inMemoryTenants) GetCompensation(ctx *gin.Context, employeeId string, asOf time.Time) (models.Compensation, *employeeerror.EmployeeError) {
+	return t.store(ctx).GetCompensation(ctx, employeeId, asOf)
+}
+
+func (t *inMemoryTenants) ScheduleSalaryChange(ctx *gin.Context, change models.SalaryChange) (models.SalaryChange, *employeeerror.EmployeeError) {
+	return t.store(ctx).ScheduleSalaryChange(ctx, change)
+}
+
+func (t *inMemoryTenants) ApplySalaryChanges(ctx context.Context, asOf time.Time, actor string, transactionID string) (int64, error) {
+	var applied int64
+	names, stores := t.tenants()
+	for _, tenant := range names {
+		n, err := stores[tenant].ApplySalaryChanges(ctx, asOf, actor, transactionID)
+		if err != nil {
+			return applied, err
+		}
+		applied += n
+	}
+	return applied, nil
+}
+
+func (t *inMemoryTenants) ReserveIdempotencyKey(ctx *gin.Context, reservation models.IdempotentResponse) (models.IdempotentResponse, bool, *employeeerror.EmployeeError) {
+	return t.store(ctx).ReserveIdempotencyKey(ctx, reservation)
+}
+
+func (t *inMemoryTenants) SaveIdempotentResponse(ctx *gin.Context, response models.IdempotentResponse) *employeeerror.EmployeeError {
+	return t.store(ctx).SaveIdempotentResponse(ctx, response)
+}
+
+func (t *inMemoryTenants) ReleaseIdempotencyKey(ctx *gin.Context, key string) *employeeerror.EmployeeError {
+	return t.store(ctx).ReleaseIdempotencyKey(ctx, key)
+}
+
+func (t *inMemoryTenants) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	var purged int64
+	names, stores := t.tenants()
+	for _, tenant := range names {
+		n, err := stores[tenant].PurgeIdempotencyKeys(ctx, expiredBefore)
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+	return purged, nil
+}
+
+func (t *inMemoryTenants) CreateAPIKey(ctx *gin.Context, key models.APIKey) (models.APIKey, *employeeerror.EmployeeError) {
+	return t.store(ctx).CreateAPIKey(ctx, key)
+}
+
+func (t *inMemoryTenants) ListAPIKeys(ctx *gin.Context) ([]models.APIKey, *employeeerror.EmployeeError) {
+	return t.store(ctx).ListAPIKeys(ctx)
+}
+
+func (t *inMemoryTenants) RotateAPIKey(ctx *gin.Context, keyId string, rotated models.APIKey) (models.APIKey, *employeeerror.EmployeeError) {
+	return t.store(ctx).RotateAPIKey(ctx, keyId, rotated)
+}
+
+func (t *inMemoryTenants) RevokeAPIKey(ctx *gin.Context, keyId string) *employeeerror.EmployeeError {
+	return t.store(ctx).RevokeAPIKey(ctx, keyId)
+}
+
+// UseAPIKey looks the key up in the store of every tenant, as the tenant of the request is
+// resolved from the key
+func (t *inMemoryTenants) UseAPIKey(ctx *gin.Context, keyHash string) (models.APIKey, bool, *employeeerror.EmployeeError) {
+	names, stores := t.tenants()
+	for _, tenant := range names {
+		key, ok, err := stores[tenant].UseAPIKey(ctx, keyHash)
+		if err != nil {
+			return models.APIKey{}, false, err
+		}
+		if ok {
+			key.TenantID = tenant
+			return key, true, nil
+		}
+	}
+	return models.APIKey{}, false, nil
+}
+
+// Ping always succeeds, the stores are in the process
+func (t *inMemoryTenants) Ping(ctx context.Context) error {
+	return nil
+}
+
+// PendingMigrations is always 0, the stores have no schema
+func (t *inMemoryTenants) PendingMigrations(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// PoolStats reports that there is no connection pool
+func (t *inMemoryTenants) PoolStats() (models.PoolStats, bool) {
+	return models.PoolStats{}, false
+}
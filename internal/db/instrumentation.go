@@ -0,0 +1,272 @@
+package db
+
+import (
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/metrics"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.uber.org/zap"
+)
+
+var (
+	queryDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of the calls to the database, by repository method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	queryErrors = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Calls to the database that failed, by repository method.",
+	}, []string{"method"})
+)
+
+// activeEmployeesTimeout bounds the count of active employees made on every scrape
+const activeEmployeesTimeout = 5 * time.Second
+
+// instrumentedDB is an EmployeeDBService recording the duration and the failures of every call
+// made to the repository it wraps. A call failing with a client error, e.g. an unknown ID, did
+// not fail in the database and is not counted as an error.
+type instrumentedDB struct {
+	repo EmployeeDBService
+}
+
+// instrument wraps the repository so that its calls are measured, and exports the number of
+// active employees
+func instrument(repo EmployeeDBService) EmployeeDBService {
+	instrumented := instrumentedDB{repo: repo}
+	metrics.NewGaugeFunc("employees_active", "Employees that are not soft deleted, by tenant.", func() []metrics.Sample {
+		ctx, cancel := context.WithTimeout(context.Background(), activeEmployeesTimeout)
+		defer cancel()
+		counts, err := instrumented.CountActiveEmployees(ctx)
+		if err != nil {
+			utils.Logger.Error("Unable to count the active employees", zap.Error(err))
+			return nil
+		}
+		samples := make([]metrics.Sample, 0, len(counts))
+		for tenant, count := range counts {
+			samples = append(samples, metrics.Sample{Labels: []string{tenant}, Value: float64(count)})
+		}
+		return samples
+	}, "tenant")
+	return instrumented
+}
+
+// registerPoolMetrics exports the statistics of the connection pool as the go_sql_* metrics,
+// labelled with db_name="employees"
+func registerPoolMetrics(conn *sql.DB) {
+	metrics.Registry.MustRegister(collectors.NewDBStatsCollector(conn, "employees"))
+}
+
+// observeQuery records a call to the repository started at start, deferred with the address of
+// its error
+func observeQuery(method string, start time.Time, err **employeeerror.EmployeeError) {
+	queryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if *err != nil && (*err).Code >= http.StatusInternalServerError {
+		queryErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// observeJob is observeQuery for the calls of the background jobs
+func observeJob(method string, start time.Time, err *error) {
+	queryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if *err != nil {
+		queryErrors.WithLabelValues(method).Inc()
+	}
+}
+
+func (i instrumentedDB) CreateEmployee(ctx *gin.Context, employee models.Employee) (_ string, err *employeeerror.EmployeeError) {
+	defer observeQuery("CreateEmployee", time.Now(), &err)
+	return i.repo.CreateEmployee(ctx, employee)
+}
+
+func (i instrumentedDB) DeleteEmployee(ctx *gin.Context, employeeId string, version int) (err *employeeerror.EmployeeError) {
+	defer observeQuery("DeleteEmployee", time.Now(), &err)
+	return i.repo.DeleteEmployee(ctx, employeeId, version)
+}
+
+func (i instrumentedDB) GetEmployeeByID(ctx *gin.Context, employeeId string) (_ models.Employee, err *employeeerror.EmployeeError) {
+	defer observeQuery("GetEmployeeByID", time.Now(), &err)
+	return i.repo.GetEmployeeByID(ctx, employeeId)
+}
+
+func (i instrumentedDB) UpdateEmployee(ctx *gin.Context, employee models.Employee) (_ models.Employee, err *employeeerror.EmployeeError) {
+	defer observeQuery("UpdateEmployee", time.Now(), &err)
+	return i.repo.UpdateEmployee(ctx, employee)
+}
+
+func (i instrumentedDB) ReplaceEmployee(ctx *gin.Context, employee models.Employee) (_ models.Employee, err *employeeerror.EmployeeError) {
+	defer observeQuery("ReplaceEmployee", time.Now(), &err)
+	return i.repo.ReplaceEmployee(ctx, employee)
+}
+
+func (i instrumentedDB) ListEmployee(ctx *gin.Context, query models.EmployeeQuery) (_ models.EmployeeList, err *employeeerror.EmployeeError) {
+	defer observeQuery("ListEmployee", time.Now(), &err)
+	return i.repo.ListEmployee(ctx, query)
+}
+
+func (i instrumentedDB) RestoreEmployee(ctx *gin.Context, employeeId string) (_ models.Employee, err *employeeerror.EmployeeError) {
+	defer observeQuery("RestoreEmployee", time.Now(), &err)
+	return i.repo.RestoreEmployee(ctx, employeeId)
+}
+
+func (i instrumentedDB) PurgeEmployees(ctx context.Context, deletedBefore time.Time, actor string, transactionID string) (_ int64, err error) {
+	defer observeJob("PurgeEmployees", time.Now(), &err)
+	return i.repo.PurgeEmployees(ctx, deletedBefore, actor, transactionID)
+}
+
+func (i instrumentedDB) PurgeTenantEmployees(ctx *gin.Context, deletedBefore time.Time) (_ int64, err *employeeerror.EmployeeError) {
+	defer observeQuery("PurgeTenantEmployees", time.Now(), &err)
+	return i.repo.PurgeTenantEmployees(ctx, deletedBefore)
+}
+
+func (i instrumentedDB) ImportEmployees(ctx *gin.Context, rows []models.ImportRow, atomic bool) (_ []models.ImportResult, err *employeeerror.EmployeeError) {
+	defer observeQuery("ImportEmployees", time.Now(), &err)
+	return i.repo.ImportEmployees(ctx, rows, atomic)
+}
+
+func (i instrumentedDB) ExportEmployees(ctx *gin.Context, query models.EmployeeQuery, emit func(models.Employee) error) (err *employeeerror.EmployeeError) {
+	defer observeQuery("ExportEmployees", time.Now(), &err)
+	return i.repo.ExportEmployees(ctx, query, emit)
+}
+
+func (i instrumentedDB) BatchUpdateEmployees(ctx *gin.Context, selection models.BatchSelection, update models.Employee, dryRun bool) (_ []models.Employee, err *employeeerror.EmployeeError) {
+	defer observeQuery("BatchUpdateEmployees", time.Now(), &err)
+	return i.repo.BatchUpdateEmployees(ctx, selection, update, dryRun)
+}
+
+func (i instrumentedDB) BatchDeleteEmployees(ctx *gin.Context, selection models.BatchSelection, dryRun bool) (_ []models.Employee, err *employeeerror.EmployeeError) {
+	defer observeQuery("BatchDeleteEmployees", time.Now(), &err)
+	return i.repo.BatchDeleteEmployees(ctx, selection, dryRun)
+}
+
+func (i instrumentedDB) CountActiveEmployees(ctx context.Context) (_ map[string]int64, err error) {
+	defer observeJob("CountActiveEmployees", time.Now(), &err)
+	return i.repo.CountActiveEmployees(ctx)
+}
+
+func (i instrumentedDB) CreateDepartment(ctx *gin.Context, department models.Department) (_ string, err *employeeerror.EmployeeError) {
+	defer observeQuery("CreateDepartment", time.Now(), &err)
+	return i.repo.CreateDepartment(ctx, department)
+}
+
+func (i instrumentedDB) GetDepartmentByID(ctx *gin.Context, departmentId string) (_ models.Department, err *employeeerror.EmployeeError) {
+	defer observeQuery("GetDepartmentByID", time.Now(), &err)
+	return i.repo.GetDepartmentByID(ctx, departmentId)
+}
+
+func (i instrumentedDB) UpdateDepartment(ctx *gin.Context, department models.Department) (_ models.Department, err *employeeerror.EmployeeError) {
+	defer observeQuery("UpdateDepartment", time.Now(), &err)
+	return i.repo.UpdateDepartment(ctx, department)
+}
+
+func (i instrumentedDB) DeleteDepartment(ctx *gin.Context, departmentId string) (err *employeeerror.EmployeeError) {
+	defer observeQuery("DeleteDepartment", time.Now(), &err)
+	return i.repo.DeleteDepartment(ctx, departmentId)
+}
+
+func (i instrumentedDB) ListDepartments(ctx *gin.Context, page int, pageSize int) (_ []models.Department, err *employeeerror.EmployeeError) {
+	defer observeQuery("ListDepartments", time.Now(), &err)
+	return i.repo.ListDepartments(ctx, page, pageSize)
+}
+
+func (i instrumentedDB) ListReports(ctx *gin.Context, employeeId string, depth int) (_ []models.EmployeeReport, err *employeeerror.EmployeeError) {
+	defer observeQuery("ListReports", time.Now(), &err)
+	return i.repo.ListReports(ctx, employeeId, depth)
+}
+
+func (i instrumentedDB) GetManagementChain(ctx *gin.Context, employeeId string) (_ []models.Employee, err *employeeerror.EmployeeError) {
+	defer observeQuery("GetManagementChain", time.Now(), &err)
+	return i.repo.GetManagementChain(ctx, employeeId)
+}
+
+func (i instrumentedDB) GetOrgChart(ctx *gin.Context) (_ []*models.OrgChartNode, err *employeeerror.EmployeeError) {
+	defer observeQuery("GetOrgChart", time.Now(), &err)
+	return i.repo.GetOrgChart(ctx)
+}
+
+func (i instrumentedDB) ListAudit(ctx *gin.Context, query models.AuditQuery) (_ models.AuditList, err *employeeerror.EmployeeError) {
+	defer observeQuery("ListAudit", time.Now(), &err)
+	return i.repo.ListAudit(ctx, query)
+}
+
+func (i instrumentedDB) GetCompensation(ctx *gin.Context, employeeId string, asOf time.Time) (_ models.Compensation, err *employeeerror.EmployeeError) {
+	defer observeQuery("GetCompensation", time.Now(), &err)
+	return i.repo.GetCompensation(ctx, employeeId, asOf)
+}
+
+func (i instrumentedDB) ScheduleSalaryChange(ctx *gin.Context, change models.SalaryChange) (_ models.SalaryChange, err *employeeerror.EmployeeError) {
+	defer observeQuery("ScheduleSalaryChange", time.Now(), &err)
+	return i.repo.ScheduleSalaryChange(ctx, change)
+}
+
+func (i instrumentedDB) ApplySalaryChanges(ctx context.Context, asOf time.Time, actor string, transactionID string) (_ int64, err error) {
+	defer observeJob("ApplySalaryChanges", time.Now(), &err)
+	return i.repo.ApplySalaryChanges(ctx, asOf, actor, transactionID)
+}
+
+func (i instrumentedDB) ReserveIdempotencyKey(ctx *gin.Context, reservation models.IdempotentResponse) (_ models.IdempotentResponse, _ bool, err *employeeerror.EmployeeError) {
+	defer observeQuery("ReserveIdempotencyKey", time.Now(), &err)
+	return i.repo.ReserveIdempotencyKey(ctx, reservation)
+}
+
+func (i instrumentedDB) SaveIdempotentResponse(ctx *gin.Context, response models.IdempotentResponse) (err *employeeerror.EmployeeError) {
+	defer observeQuery("SaveIdempotentResponse", time.Now(), &err)
+	return i.repo.SaveIdempotentResponse(ctx, response)
+}
+
+func (i instrumentedDB) ReleaseIdempotencyKey(ctx *gin.Context, key string) (err *employeeerror.EmployeeError) {
+	defer observeQuery("ReleaseIdempotencyKey", time.Now(), &err)
+	return i.repo.ReleaseIdempotencyKey(ctx, key)
+}
+
+func (i instrumentedDB) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (_ int64, err error) {
+	defer observeJob("PurgeIdempotencyKeys", time.Now(), &err)
+	return i.repo.PurgeIdempotencyKeys(ctx, expiredBefore)
+}
+
+func (i instrumentedDB) CreateAPIKey(ctx *gin.Context, key models.APIKey) (_ models.APIKey, err *employeeerror.EmployeeError) {
+	defer observeQuery("CreateAPIKey", time.Now(), &err)
+	return i.repo.CreateAPIKey(ctx, key)
+}
+
+func (i instrumentedDB) ListAPIKeys(ctx *gin.Context) (_ []models.APIKey, err *employeeerror.EmployeeError) {
+	defer observeQuery("ListAPIKeys", time.Now(), &err)
+	return i.repo.ListAPIKeys(ctx)
+}
+
+func (i instrumentedDB) RotateAPIKey(ctx *gin.Context, keyId string, rotated models.APIKey) (_ models.APIKey, err *employeeerror.EmployeeError) {
+	defer observeQuery("RotateAPIKey", time.Now(), &err)
+	return i.repo.RotateAPIKey(ctx, keyId, rotated)
+}
+
+func (i instrumentedDB) RevokeAPIKey(ctx *gin.Context, keyId string) (err *employeeerror.EmployeeError) {
+	defer observeQuery("RevokeAPIKey", time.Now(), &err)
+	return i.repo.RevokeAPIKey(ctx, keyId)
+}
+
+func (i instrumentedDB) UseAPIKey(ctx *gin.Context, keyHash string) (_ models.APIKey, _ bool, err *employeeerror.EmployeeError) {
+	defer observeQuery("UseAPIKey", time.Now(), &err)
+	return i.repo.UseAPIKey(ctx, keyHash)
+}
+
+func (i instrumentedDB) Ping(ctx context.Context) (err error) {
+	defer observeJob("Ping", time.Now(), &err)
+	return i.repo.Ping(ctx)
+}
+
+func (i instrumentedDB) PendingMigrations(ctx context.Context) (_ int, err error) {
+	defer observeJob("PendingMigrations", time.Now(), &err)
+	return i.repo.PendingMigrations(ctx)
+}
+
+func (i instrumentedDB) PoolStats() (models.PoolStats, bool) {
+	return i.repo.PoolStats()
+}
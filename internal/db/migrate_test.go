@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	// Versions must be strictly increasing and every migration must be reversible
+	for i, migration := range migrations {
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+		assert.Len(t, migration.Checksum, 64)
+		if i > 0 {
+			assert.Greater(t, migration.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	missingDown := fstest.MapFS{
+		"migrations/0001_init.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+	}
+	_, err := loadMigrations(missingDown, "migrations")
+	assert.Error(t, err)
+
+	duplicateVersion := fstest.MapFS{
+		"migrations/0001_init.up.sql":    {Data: []byte("CREATE TABLE a (id INT);")},
+		"migrations/0001_init.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"migrations/0001_other.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+		"migrations/0001_other.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+	_, err = loadMigrations(duplicateVersion, "migrations")
+	assert.Error(t, err)
+
+	badName := fstest.MapFS{
+		"migrations/init.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"migrations/init.down.sql": {Data: []byte("DROP TABLE a;")},
+	}
+	_, err = loadMigrations(badName, "migrations")
+	assert.Error(t, err)
+}
+
+func testMigrations(t *testing.T) []Migration {
+	migrations, err := loadMigrations(fstest.MapFS{
+		"migrations/0001_init.up.sql":     {Data: []byte("CREATE TABLE a (id INT);")},
+		"migrations/0001_init.down.sql":   {Data: []byte("DROP TABLE a;")},
+		"migrations/0002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+		"migrations/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+	}, "migrations")
+	assert.NoError(t, err)
+	return migrations
+}
+
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigrator_UpAppliesPending(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	migrations := testMigrations(t)
+	m := &Migrator{db: mockDB, migrations: migrations}
+
+	expectLock(mock)
+	mock.ExpectQuery(`SELECT version, checksum, applied_at FROM schema_migrations ORDER BY version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, migrations[0].Checksum, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations \(version, name, checksum\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs(2, "second", migrations[1].Checksum).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := m.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpChecksumMismatch(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	m := &Migrator{db: mockDB, migrations: testMigrations(t)}
+
+	expectLock(mock)
+	mock.ExpectQuery(`SELECT version, checksum, applied_at FROM schema_migrations ORDER BY version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, "0000000000000000000000000000000000000000000000000000000000000000", time.Now()))
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := m.Up(context.Background())
+	assert.ErrorContains(t, err, "checksum mismatch")
+	assert.Equal(t, 0, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_DownRollsBackLatest(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	migrations := testMigrations(t)
+	m := &Migrator{db: mockDB, migrations: migrations}
+
+	expectLock(mock)
+	mock.ExpectQuery(`SELECT version, checksum, applied_at FROM schema_migrations ORDER BY version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, migrations[0].Checksum, time.Now()).
+			AddRow(2, migrations[1].Checksum, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DROP TABLE b;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations WHERE version=\$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rolledBack, err := m.Down(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Pending(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	m := &Migrator{db: mockDB, migrations: testMigrations(t)}
+
+	mock.ExpectQuery(`SELECT version FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+
+	pending, err := m.Pending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pending)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,8 +1,9 @@
-CREATE TABLE employees (
+-- IF NOT EXISTS lets databases created with the old sql/createTable.sql script adopt the migrations
+CREATE TABLE IF NOT EXISTS employees (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     position VARCHAR(255) NOT NULL,
     salary NUMERIC(15, 2) NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
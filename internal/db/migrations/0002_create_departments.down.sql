@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS employees_department_id_idx;
+
+ALTER TABLE employees DROP COLUMN IF EXISTS department_id;
+
+DROP TABLE IF EXISTS departments;
@@ -0,0 +1,11 @@
+CREATE TABLE departments (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE employees ADD COLUMN department_id INTEGER REFERENCES departments (id) ON DELETE RESTRICT;
+
+CREATE INDEX employees_department_id_idx ON employees (department_id);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS employees_bump_version ON employees;
+
+DROP FUNCTION IF EXISTS employees_bump_version();
+
+ALTER TABLE employees DROP COLUMN IF EXISTS version;
@@ -0,0 +1,14 @@
+ALTER TABLE employees ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Every write bumps the version, including the manager_id reset done by ON DELETE SET NULL,
+-- so that an ETag handed out before the write no longer matches
+CREATE FUNCTION employees_bump_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER employees_bump_version
+    BEFORE UPDATE ON employees
+    FOR EACH ROW EXECUTE FUNCTION employees_bump_version();
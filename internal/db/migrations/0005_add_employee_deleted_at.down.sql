@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS employees_deleted_at_idx;
+
+ALTER TABLE employees DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+ALTER TABLE employees ADD COLUMN deleted_at TIMESTAMP;
+
+-- Supports the retention purge, which only looks at soft-deleted rows
+CREATE INDEX employees_deleted_at_idx ON employees (deleted_at) WHERE deleted_at IS NOT NULL;
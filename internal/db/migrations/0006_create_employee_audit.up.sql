@@ -0,0 +1,16 @@
+-- One row per change of an employee, written in the same transaction as the change.
+-- employee_id has no foreign key so that the history outlives a purged employee.
+CREATE TABLE employee_audit (
+    id BIGSERIAL PRIMARY KEY,
+    employee_id INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    before JSONB,
+    after JSONB,
+    actor VARCHAR(255) NOT NULL,
+    transaction_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX employee_audit_employee_id_idx ON employee_audit (employee_id, id);
+CREATE INDEX employee_audit_created_at_idx ON employee_audit (created_at);
+CREATE INDEX employee_audit_actor_idx ON employee_audit (actor, created_at);
@@ -0,0 +1,17 @@
+-- Every salary an employee has had or is scheduled to have. employees.salary holds the
+-- latest change in effect.
+CREATE TABLE salary_history (
+    id BIGSERIAL PRIMARY KEY,
+    employee_id INTEGER NOT NULL REFERENCES employees (id) ON DELETE CASCADE,
+    amount NUMERIC(15, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    effective_from TIMESTAMP NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX salary_history_employee_id_idx ON salary_history (employee_id, effective_from);
+
+-- The salary of existing employees is the first entry of their history
+INSERT INTO salary_history (employee_id, amount, currency, effective_from, reason)
+SELECT id, salary, 'USD', COALESCE(created_at, CURRENT_TIMESTAMP), 'hire' FROM employees;
@@ -0,0 +1,3 @@
+ALTER TABLE salary_history ALTER COLUMN amount TYPE NUMERIC(15, 2);
+ALTER TABLE employees DROP COLUMN IF EXISTS salary_currency;
+ALTER TABLE employees ALTER COLUMN salary TYPE NUMERIC(15, 2);
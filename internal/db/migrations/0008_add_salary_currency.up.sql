@@ -0,0 +1,5 @@
+-- Salaries carry their currency, and four decimal places so that currencies with three or
+-- four minor digits fit. Existing salaries were all recorded in USD.
+ALTER TABLE employees ALTER COLUMN salary TYPE NUMERIC(17, 4);
+ALTER TABLE employees ADD COLUMN salary_currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE salary_history ALTER COLUMN amount TYPE NUMERIC(17, 4);
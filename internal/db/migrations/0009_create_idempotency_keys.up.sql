@@ -0,0 +1,14 @@
+-- Responses of the requests made with an Idempotency-Key, replayed when the request is retried.
+-- A key whose status_code is NULL is held by a request still in progress.
+CREATE TABLE idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status_code INTEGER,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    etag VARCHAR(64) NOT NULL DEFAULT '',
+    body BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
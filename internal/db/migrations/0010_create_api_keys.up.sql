@@ -0,0 +1,15 @@
+-- Keys of the services calling the API. Only the SHA-256 hash of a key is stored, the key itself
+-- is shown once when it is created or rotated. prefix is the start of the key, to tell keys apart.
+-- scopes are the permissions granted to the key, separated by spaces like OAuth scopes.
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    owner VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
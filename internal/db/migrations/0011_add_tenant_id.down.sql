@@ -0,0 +1,24 @@
+ALTER TABLE api_keys DROP COLUMN tenant_id;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN tenant_id;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
+
+ALTER TABLE salary_history DROP COLUMN tenant_id;
+
+DROP INDEX IF EXISTS employee_audit_tenant_id_idx;
+ALTER TABLE employee_audit DROP COLUMN tenant_id;
+
+ALTER TABLE employees DROP CONSTRAINT employees_manager_id_fkey;
+ALTER TABLE employees DROP CONSTRAINT employees_department_id_fkey;
+ALTER TABLE employees DROP CONSTRAINT employees_tenant_id_id_key;
+ALTER TABLE employees DROP COLUMN tenant_id;
+ALTER TABLE employees ADD CONSTRAINT employees_department_id_fkey
+    FOREIGN KEY (department_id) REFERENCES departments (id) ON DELETE RESTRICT;
+ALTER TABLE employees ADD CONSTRAINT employees_manager_id_fkey
+    FOREIGN KEY (manager_id) REFERENCES employees (id) ON DELETE SET NULL;
+
+ALTER TABLE departments DROP CONSTRAINT departments_tenant_id_id_key;
+ALTER TABLE departments DROP CONSTRAINT departments_tenant_id_name_key;
+ALTER TABLE departments DROP COLUMN tenant_id;
+ALTER TABLE departments ADD CONSTRAINT departments_name_key UNIQUE (name);
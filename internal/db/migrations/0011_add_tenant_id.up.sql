@@ -0,0 +1,38 @@
+-- Every table holds the records of several tenants, the organisations sharing the deployment.
+-- The records created before belong to the "default" tenant. Once they are assigned, the default
+-- is dropped so that a write naming no tenant fails rather than landing in the default tenant.
+ALTER TABLE departments ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE departments ALTER COLUMN tenant_id DROP DEFAULT;
+-- Department names are unique within a tenant
+ALTER TABLE departments DROP CONSTRAINT departments_name_key;
+ALTER TABLE departments ADD CONSTRAINT departments_tenant_id_name_key UNIQUE (tenant_id, name);
+ALTER TABLE departments ADD CONSTRAINT departments_tenant_id_id_key UNIQUE (tenant_id, id);
+
+ALTER TABLE employees ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE employees ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE employees ADD CONSTRAINT employees_tenant_id_id_key UNIQUE (tenant_id, id);
+-- An employee can only belong to a department of its own tenant
+ALTER TABLE employees DROP CONSTRAINT employees_department_id_fkey;
+ALTER TABLE employees ADD CONSTRAINT employees_department_id_fkey
+    FOREIGN KEY (tenant_id, department_id) REFERENCES departments (tenant_id, id) ON DELETE RESTRICT;
+-- An employee can only report to a manager of its own tenant. ON DELETE SET NULL would clear
+-- tenant_id along with manager_id, so the purge clears the manager_id of the reports itself.
+ALTER TABLE employees DROP CONSTRAINT employees_manager_id_fkey;
+ALTER TABLE employees ADD CONSTRAINT employees_manager_id_fkey
+    FOREIGN KEY (tenant_id, manager_id) REFERENCES employees (tenant_id, id);
+
+ALTER TABLE employee_audit ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE employee_audit ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX employee_audit_tenant_id_idx ON employee_audit (tenant_id, id);
+
+ALTER TABLE salary_history ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE salary_history ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Tenants pick their Idempotency-Keys independently
+ALTER TABLE idempotency_keys ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE idempotency_keys ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (tenant_id, key);
+
+ALTER TABLE api_keys ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ALTER COLUMN tenant_id DROP DEFAULT;
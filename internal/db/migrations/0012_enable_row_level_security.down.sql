@@ -0,0 +1,17 @@
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+ALTER TABLE idempotency_keys DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON salary_history;
+ALTER TABLE salary_history DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON employee_audit;
+ALTER TABLE employee_audit DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON departments;
+ALTER TABLE departments DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON employees;
+ALTER TABLE employees DISABLE ROW LEVEL SECURITY;
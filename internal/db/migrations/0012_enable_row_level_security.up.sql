@@ -0,0 +1,27 @@
+-- Row level security keeps a query that forgets its tenant_id condition from reaching the rows
+-- of another tenant. The policies limit a role to the tenant set in app.tenant_id, "*" being
+-- every tenant, for the background jobs. They do not apply to the owner of the tables, so they
+-- are only enforced once the API connects as another role with tenancy.row_level_security set.
+ALTER TABLE employees ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON employees
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE departments ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON departments
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE employee_audit ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON employee_audit
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE salary_history ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON salary_history
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON idempotency_keys
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
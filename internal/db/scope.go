@@ -0,0 +1,29 @@
+package db
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tenantOf returns the tenant a request is made for, stored under constants.TenantKey by the
+// authentication of the request, tenancy.default_tenant otherwise
+func tenantOf(ctx *gin.Context) string {
+	if tenant := ctx.GetString(constants.TenantKey); tenant != "" {
+		return tenant
+	}
+	if tenant := config.GetConfig().Tenancy.DefaultTenant; tenant != "" {
+		return tenant
+	}
+	return constants.DefaultTenant
+}
+
+// actorOf returns who makes a request, stored under constants.ActorKey by the authentication of
+// the request
+func actorOf(ctx *gin.Context) string {
+	if actor := ctx.GetString(constants.ActorKey); actor != "" {
+		return actor
+	}
+	return constants.UnknownActor
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"assignment/internal/tracing"
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedConnector opens connections whose statements are traced: every statement run within a
+// trace is a client span, a child of the span of its context, recording the sanitized query text.
+// The statements of the background jobs, which run outside of any trace, are not traced.
+type tracedConnector struct {
+	connector driver.Connector
+}
+
+// newTracedConnector returns the connector of the pgx driver for the connection string, traced
+func newTracedConnector(connString string) (driver.Connector, error) {
+	connector, err := stdlib.GetDefaultDriver().(driver.DriverContext).OpenConnector(connString)
+	if err != nil {
+		return nil, err
+	}
+	return tracedConnector{connector: connector}, nil
+}
+
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn}, nil
+}
+
+func (c tracedConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// startStatementSpan starts the span of a statement, nil outside of a trace
+func startStatementSpan(ctx context.Context, query string) trace.Span {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	_, span := tracing.Tracer().Start(ctx, tracing.SQLOperation(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(tracing.SanitizeSQL(query)),
+		),
+	)
+	return span
+}
+
+// endStatementSpan ends the span of a statement, recording its error
+func endStatementSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedConn is a connection of the pgx driver whose statements are traced. The pgx connection
+// implements every optional interface forwarded here.
+type tracedConn struct {
+	driver.Conn
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	span := startStatementSpan(ctx, query)
+	defer func() { endStatementSpan(span, err) }()
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	span := startStatementSpan(ctx, query)
+	defer func() { endStatementSpan(span, err) }()
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	return c.Conn.(driver.NamedValueChecker).CheckNamedValue(value)
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+// tracedStmt is a prepared statement whose executions are traced
+type tracedStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	span := startStatementSpan(ctx, s.query)
+	defer func() { endStatementSpan(span, err) }()
+	return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	span := startStatementSpan(ctx, s.query)
+	defer func() { endStatementSpan(span, err) }()
+	return s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/tracing"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// statementConn is a driver connection answering every statement with err
+type statementConn struct {
+	driver.Conn
+	err error
+}
+
+func (c statementConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), c.err
+}
+
+func TestTracedConn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	assert.NoError(t, tracing.Init(config.Tracing{Enabled: true, Exporter: tracing.ExporterFile, File: path, SampleRatio: 1}))
+
+	conn := &tracedConn{Conn: statementConn{err: errors.New("deadlock detected")}}
+
+	// Statements outside of a trace are not traced
+	_, err := conn.ExecContext(context.Background(), `DELETE FROM idempotency_keys WHERE expires_at <= $1`, nil)
+	assert.Error(t, err)
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "deleteEmployee")
+	_, err = conn.ExecContext(ctx, `UPDATE employees SET deleted_at=now()
+		WHERE tenant_id=$1 AND id=$2 AND name='John'`, nil)
+	assert.Error(t, err)
+	span.End()
+	assert.NoError(t, tracing.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `{"Name":"UPDATE",`)
+	assert.Contains(t, string(data), `"SpanKind":3,`)
+	assert.Contains(t, string(data), `{"Key":"db.query.text","Value":{"Type":"STRING","Value":"UPDATE employees SET deleted_at=now() WHERE tenant_id=$1 AND id=$2 AND name=?"}}`)
+	assert.Contains(t, string(data), `"Status":{"Code":"Error","Description":"deadlock detected"}`)
+	assert.NotContains(t, string(data), "idempotency_keys")
+}
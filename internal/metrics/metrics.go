@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds the metrics served on the metrics endpoint. It only holds those of the
+// application, the Go runtime and process collectors are not registered.
+var Registry = prometheus.NewRegistry()
+
+// Factory registers the metrics it creates with Registry, e.g.
+//
+//	var jobs = metrics.Factory.NewCounterVec(prometheus.CounterOpts{Name: "jobs_total", Help: "Jobs run."}, []string{"job"})
+var Factory = promauto.With(Registry)
+
+// Handler serves Registry to a Prometheus scrape, in the text exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Sample is one value of a gauge collected when the registry is scraped, Labels holding the
+// values of its label names in order
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// gaugeFunc is a gauge whose samples are returned by collect on every scrape
+type gaugeFunc struct {
+	desc    *prometheus.Desc
+	collect func() []Sample
+}
+
+// NewGaugeFunc registers a gauge with Registry whose samples, one per combination of the values
+// of its label names, are returned by collect on every scrape, e.g. the active employees of every
+// tenant. Unlike prometheus.NewGaugeFunc, the gauge may have labels.
+func NewGaugeFunc(name, help string, collect func() []Sample, labels ...string) {
+	Registry.MustRegister(newGaugeFunc(name, help, collect, labels...))
+}
+
+func newGaugeFunc(name, help string, collect func() []Sample, labels ...string) *gaugeFunc {
+	return &gaugeFunc{desc: prometheus.NewDesc(name, help, labels, nil), collect: collect}
+}
+
+func (g *gaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *gaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	for _, sample := range g.collect() {
+		metric, err := prometheus.NewConstMetric(g.desc, prometheus.GaugeValue, sample.Value, sample.Labels...)
+		if err != nil {
+			metric = prometheus.NewInvalidMetric(g.desc, err)
+		}
+		ch <- metric
+	}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+// scrape serves handler to a scrape and parses the text exposition it answers
+func scrape(t *testing.T, handler http.Handler) map[string]*dto.MetricFamily {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(recorder.Body)
+	assert.NoError(t, err)
+	return families
+}
+
+// labels returns the label pairs of a metric as a map
+func labels(metric *dto.Metric) map[string]string {
+	pairs := make(map[string]string)
+	for _, pair := range metric.GetLabel() {
+		pairs[pair.GetName()] = pair.GetValue()
+	}
+	return pairs
+}
+
+func TestHandler(t *testing.T) {
+	requests := Factory.NewCounterVec(prometheus.CounterOpts{Name: "test_requests_total", Help: "Requests served."}, []string{"method", "status"})
+	requests.WithLabelValues("GET", "200").Inc()
+	requests.WithLabelValues("GET", "200").Inc()
+	requests.WithLabelValues("POST", "201").Add(3)
+
+	latency := Factory.NewHistogramVec(prometheus.HistogramOpts{Name: "test_query_duration_seconds", Help: "Query latency.", Buckets: []float64{0.1, 1}}, []string{"method"})
+	for _, value := range []float64{0.05, 0.1, 0.5, 3} {
+		latency.WithLabelValues("List").Observe(value)
+	}
+
+	families := scrape(t, Handler())
+
+	counter := families["test_requests_total"]
+	assert.Equal(t, dto.MetricType_COUNTER, counter.GetType())
+	assert.Equal(t, "Requests served.", counter.GetHelp())
+	assert.Len(t, counter.GetMetric(), 2)
+	assert.Equal(t, map[string]string{"method": "GET", "status": "200"}, labels(counter.GetMetric()[0]))
+	assert.Equal(t, 2.0, counter.GetMetric()[0].GetCounter().GetValue())
+	assert.Equal(t, 3.0, counter.GetMetric()[1].GetCounter().GetValue())
+
+	histogram := families["test_query_duration_seconds"].GetMetric()[0].GetHistogram()
+	assert.Equal(t, uint64(4), histogram.GetSampleCount())
+	assert.InDelta(t, 3.65, histogram.GetSampleSum(), 1e-9)
+	assert.Equal(t, uint64(2), histogram.GetBucket()[0].GetCumulativeCount())
+	assert.Equal(t, uint64(3), histogram.GetBucket()[1].GetCumulativeCount())
+
+	// Only the metrics of the application are served
+	assert.NotContains(t, families, "go_goroutines")
+}
+
+func TestGaugeFunc(t *testing.T) {
+	active := map[string]float64{"globex": 1, `a"b\c`: 2}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newGaugeFunc("employees_active", "Active employees.", func() []Sample {
+		var samples []Sample
+		for tenant, count := range active {
+			samples = append(samples, Sample{Labels: []string{tenant}, Value: count})
+		}
+		return samples
+	}, "tenant"))
+
+	// The samples are read on every scrape, and label values are escaped
+	families := scrape(t, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	gauge := families["employees_active"]
+	assert.Equal(t, dto.MetricType_GAUGE, gauge.GetType())
+	assert.Len(t, gauge.GetMetric(), 2)
+	assert.Equal(t, `a"b\c`, labels(gauge.GetMetric()[0])["tenant"])
+	assert.Equal(t, 2.0, gauge.GetMetric()[0].GetGauge().GetValue())
+
+	active["initech"] = 5
+	families = scrape(t, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	assert.Len(t, families["employees_active"].GetMetric(), 3)
+
+	// A sample with the wrong number of label values fails the scrape rather than being served
+	registry.MustRegister(newGaugeFunc("pool_open_connections", "Open connections.", func() []Sample {
+		return []Sample{{Labels: []string{"extra"}, Value: 4}}
+	}))
+	recorder := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "pool_open_connections")
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// APIKeyAuthenticator returns the principal of an API key, reporting whether the key is active
+type APIKeyAuthenticator func(ctx *gin.Context, key string) (policy.Principal, bool, *employeeerror.EmployeeError)
+
+// apiKeyAuthenticator looks up the X-API-Key of requests, API keys are refused when nil
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// SetAPIKeyAuthenticator lets Authenticate accept an X-API-Key header in place of a bearer token
+func SetAPIKeyAuthenticator(authenticator APIKeyAuthenticator) {
+	apiKeyAuthenticator = authenticator
+}
+
+// authenticateAPIKey authenticates a request made with an X-API-Key, reporting whether it was
+func authenticateAPIKey(ctx *gin.Context, key string) bool {
+	if apiKeyAuthenticator == nil {
+		utils.RespondWithError(ctx, http.StatusUnauthorized, "API keys are not accepted")
+		return false
+	}
+	principal, ok, err := apiKeyAuthenticator(ctx, key)
+	if err != nil {
+		utils.RespondWithError(ctx, err.Code, err.Message)
+		return false
+	}
+	if !ok {
+		utils.RespondWithError(ctx, http.StatusUnauthorized, "the API key is invalid, expired or revoked")
+		return false
+	}
+
+	ctx.Set(constants.SubjectKey, principal.Subject)
+	ctx.Set(constants.ActorKey, principal.Subject)
+	ctx.Set(constants.PrincipalKey, principal)
+	return true
+}
+
+// ValidateCreateAPIKeyRequest requires a name and at least one scope, each a known permission.
+// An expiry must be in the future.
+func ValidateCreateAPIKeyRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		var key models.APIKey
+		if err := ctx.ShouldBindBodyWith(&key, binding.JSON); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody)
+			return
+		}
+
+		if strings.TrimSpace(key.Name) == "" {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "API key name is missing")
+			return
+		}
+		if len(key.Scopes) == 0 {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "API key scopes are missing")
+			return
+		}
+		for _, scope := range key.Scopes {
+			if !slices.Contains(policy.Permissions, scope) {
+				utils.RespondWithError(ctx, http.StatusBadRequest, fmt.Sprintf("unknown scope %q, expected one of %s", scope, strings.Join(policy.Permissions, ", ")))
+				return
+			}
+		}
+		if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func ValidateAPIKeyID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		keyID := ctx.Param("id")
+		if !isValidID(&keyID) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "API key Id is invalid")
+			return
+		}
+
+		ctx.Next()
+	}
+}
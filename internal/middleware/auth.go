@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"assignment/internal/auth"
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	"assignment/internal/policy"
+	"assignment/internal/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errNoSubject = errors.New("the token has no sub")
+
+var (
+	// verifier checks the bearer tokens of requests, nil when authentication is disabled
+	verifier *auth.Verifier
+	// rolePolicy grants permissions to the roles of the tokens
+	rolePolicy *policy.Policy
+)
+
+// InitAuthentication sets up the verification of bearer tokens from the auth configuration
+func InitAuthentication() error {
+	cfg := config.GetConfig().Auth
+	if !cfg.Enabled {
+		utils.Logger.Warn("authentication is disabled, requests are not authenticated")
+		verifier = nil
+		return nil
+	}
+
+	var err error
+	if rolePolicy, err = policy.New(config.GetConfig().RBAC); err != nil {
+		return err
+	}
+	verifier, err = auth.NewVerifier(cfg)
+	return err
+}
+
+// Authenticate requires an `Authorization: Bearer` JWT. The subject of the token becomes the
+// actor of the request, and the subject and claims are stored in the context under
+// constants.SubjectKey and constants.ClaimsKey, and on the request context. The principal the
+// policy makes of the token is stored under constants.PrincipalKey.
+//
+// An X-API-Key header may be sent instead of the token, its principal is granted the scopes of
+// the key and its subject is constants.APIKeyActorPrefix followed by the ID of the key.
+//
+// The tenant of the request is then resolved and stored under constants.TenantKey, see
+// resolveTenant.
+func Authenticate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		if verifier == nil {
+			if resolveTenant(ctx, "") {
+				ctx.Next()
+			}
+			return
+		}
+
+		if key := ctx.GetHeader(constants.APIKey); key != "" {
+			if ctx.GetHeader(constants.Authorization) != "" {
+				utils.RespondWithError(ctx, http.StatusUnauthorized, "send either a bearer token or an API key")
+				return
+			}
+			if authenticateAPIKey(ctx, key) && resolveTenant(ctx, GetPrincipal(ctx).Tenant) {
+				ctx.Next()
+			}
+			return
+		}
+
+		scheme, token, _ := strings.Cut(ctx.GetHeader(constants.Authorization), " ")
+		token = strings.TrimSpace(token)
+		if !strings.EqualFold(scheme, constants.Bearer) || token == "" {
+			ctx.Header(constants.WWWAuthenticate, constants.Bearer)
+			utils.RespondWithError(ctx, http.StatusUnauthorized, "a bearer token is required")
+			return
+		}
+
+		claims, err := verifier.Verify(token)
+		if err == nil && claims.Subject() == "" {
+			err = errNoSubject
+		}
+		if err != nil {
+			ctx.Header(constants.WWWAuthenticate, constants.Bearer+` error="invalid_token"`)
+			utils.RespondWithError(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		principal := rolePolicy.Principal(claims)
+		if claim := config.GetConfig().Tenancy.Claim; claim != "" {
+			principal.Tenant = claims.String(claim)
+		}
+		ctx.Set(constants.SubjectKey, claims.Subject())
+		ctx.Set(constants.ClaimsKey, claims)
+		ctx.Set(constants.ActorKey, claims.Subject())
+		ctx.Set(constants.PrincipalKey, principal)
+		ctx.Request = ctx.Request.WithContext(auth.WithClaims(ctx.Request.Context(), claims))
+		if resolveTenant(ctx, principal.Tenant) {
+			ctx.Next()
+		}
+	}
+}
+
+// GetClaims returns the claims of the token a request was authenticated with, nil when
+// authentication is disabled
+func GetClaims(c *gin.Context) auth.Claims {
+	claims, _ := c.Get(constants.ClaimsKey)
+	value, _ := claims.(auth.Claims)
+	return value
+}
+
+// GetPrincipal returns the caller of a request and its permissions, an unrestricted principal
+// when authentication is disabled
+func GetPrincipal(c *gin.Context) policy.Principal {
+	if principal, ok := c.Get(constants.PrincipalKey); ok {
+		return principal.(policy.Principal)
+	}
+	return policy.Unrestricted()
+}
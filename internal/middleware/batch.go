@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+func ValidateBatchUpdateRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		var request models.BatchUpdateRequest
+		if err := ctx.ShouldBindBodyWith(&request, binding.JSON); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody)
+			return
+		}
+
+		if _, err := ParseBatchSelection(request.EmployeeSelection); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		if message := validateBatchUpdate(request.Update); message != "" {
+			utils.RespondWithError(ctx, http.StatusBadRequest, message)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// validateBatchUpdate returns why the fields of a batch update cannot be applied, or "" when
+// they can. The update applies to many employees, so it cannot name one of them.
+func validateBatchUpdate(update models.Employee) string {
+	if update.ID != "" {
+		return "update cannot set the employee id"
+	}
+	if update.Version != 0 {
+		return "update cannot set the employee version"
+	}
+	if update.Name == "" && update.Position == "" && update.Salary == nil && update.DepartmentID == nil && update.ManagerID == nil {
+		return "update has no fields to set"
+	}
+
+	if update.Salary != nil {
+		if err := update.Salary.Validate(); err != nil {
+			return "employee salary is invalid, " + err.Error()
+		}
+	}
+	if !isValidID(update.DepartmentID) {
+		return "employee department_id is invalid"
+	}
+	if !isValidID(update.ManagerID) {
+		return "employee manager_id is invalid"
+	}
+	return ""
+}
+
+func ValidateBatchDeleteRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		var request models.BatchDeleteRequest
+		if err := ctx.ShouldBindBodyWith(&request, binding.JSON); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody)
+			return
+		}
+
+		if _, err := ParseBatchSelection(request.EmployeeSelection); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ParseBatchSelection checks the employees named by a batch request, which gives either a list
+// of at most constants.MaxBatchSize IDs or a filter with at least one criterion. Repeated IDs
+// are only selected once.
+func ParseBatchSelection(selection models.EmployeeSelection) (models.BatchSelection, error) {
+	if selection.IDs != nil && selection.Filter != nil {
+		return models.BatchSelection{}, errors.New("ids and filter cannot be used together")
+	}
+
+	if selection.Filter != nil {
+		query, err := parseEmployeeFilter(*selection.Filter)
+		if err != nil {
+			return models.BatchSelection{}, err
+		}
+		return models.BatchSelection{Query: &query}, nil
+	}
+
+	if len(selection.IDs) == 0 {
+		return models.BatchSelection{}, errors.New("either ids or filter is required")
+	}
+	if len(selection.IDs) > constants.MaxBatchSize {
+		return models.BatchSelection{}, fmt.Errorf("a batch is limited to %d ids", constants.MaxBatchSize)
+	}
+	ids := make([]string, 0, len(selection.IDs))
+	seen := make(map[int]bool, len(selection.IDs))
+	for _, id := range selection.IDs {
+		if !isValidID(&id) {
+			return models.BatchSelection{}, fmt.Errorf("employee Id %q is invalid", id)
+		}
+		n, _ := strconv.Atoi(id)
+		if !seen[n] {
+			seen[n] = true
+			ids = append(ids, strconv.Itoa(n))
+		}
+	}
+	return models.BatchSelection{IDs: ids}, nil
+}
+
+// parseEmployeeFilter turns the filter of a batch request into the query of the employee
+// listing. An empty filter is rejected, as it would select every employee.
+func parseEmployeeFilter(filter models.EmployeeFilter) (models.EmployeeQuery, error) {
+	query := models.EmployeeQuery{
+		Position:     filter.Position,
+		NameContains: filter.NameContains,
+		SalaryMin:    filter.SalaryMin,
+		SalaryMax:    filter.SalaryMax,
+		DepartmentID: filter.DepartmentID,
+	}
+
+	if query.SalaryMin != nil && query.SalaryMax != nil && query.SalaryMin.Cmp(*query.SalaryMax) > 0 {
+		return query, errors.New("salary_min must not be greater than salary_max")
+	}
+	if filter.CreatedAfter != "" {
+		createdAfter, err := parseTimestamp(filter.CreatedAfter)
+		if err != nil {
+			return query, errors.New("created_after must be a date (2006-01-02) or an RFC 3339 timestamp")
+		}
+		query.CreatedAfter = &createdAfter
+	}
+	if !isValidID(query.DepartmentID) {
+		return query, errors.New("filter department_id is invalid")
+	}
+
+	if query.Position == "" && query.NameContains == "" && query.SalaryMin == nil && query.SalaryMax == nil &&
+		query.CreatedAfter == nil && query.DepartmentID == nil {
+		return query, errors.New("filter must have at least one criterion")
+	}
+	return query, nil
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBatchSelection(t *testing.T) {
+	department := "4"
+	salaryMin, salaryMax := decimal("1000"), decimal("900")
+	tooMany := make([]string, constants.MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i + 1)
+	}
+
+	for _, test := range []struct {
+		name      string
+		selection models.EmployeeSelection
+		expected  models.BatchSelection
+		err       string
+	}{
+		{name: "ids", selection: models.EmployeeSelection{IDs: []string{"3", "1", "003", "2", "1"}}, expected: models.BatchSelection{IDs: []string{"3", "1", "2"}}},
+		{
+			name:      "filter",
+			selection: models.EmployeeSelection{Filter: &models.EmployeeFilter{Position: "Engineer", DepartmentID: &department, CreatedAfter: "2024-01-31"}},
+			expected: models.BatchSelection{Query: &models.EmployeeQuery{
+				Position:     "Engineer",
+				DepartmentID: &department,
+				CreatedAfter: func() *time.Time { at := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC); return &at }(),
+			}},
+		},
+		{name: "nothing", selection: models.EmployeeSelection{}, err: "either ids or filter is required"},
+		{name: "empty ids", selection: models.EmployeeSelection{IDs: []string{}}, err: "either ids or filter is required"},
+		{name: "ids and filter", selection: models.EmployeeSelection{IDs: []string{"1"}, Filter: &models.EmployeeFilter{Position: "Engineer"}}, err: "ids and filter cannot be used together"},
+		{name: "invalid id", selection: models.EmployeeSelection{IDs: []string{"1", "0"}}, err: `employee Id "0" is invalid`},
+		{name: "too many ids", selection: models.EmployeeSelection{IDs: tooMany}, err: "a batch is limited to 1000 ids"},
+		{name: "empty filter", selection: models.EmployeeSelection{Filter: &models.EmployeeFilter{}}, err: "filter must have at least one criterion"},
+		{name: "inverted salary range", selection: models.EmployeeSelection{Filter: &models.EmployeeFilter{SalaryMin: &salaryMin, SalaryMax: &salaryMax}}, err: "salary_min must not be greater than salary_max"},
+		{name: "invalid created_after", selection: models.EmployeeSelection{Filter: &models.EmployeeFilter{CreatedAfter: "yesterday"}}, err: "created_after must be a date (2006-01-02) or an RFC 3339 timestamp"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			selection, err := ParseBatchSelection(test.selection)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, selection)
+		})
+	}
+}
+
+func TestValidateBatchUpdate(t *testing.T) {
+	invalidID := "x"
+	for _, test := range []struct {
+		name    string
+		update  models.Employee
+		message string
+	}{
+		{name: "position", update: models.Employee{Position: "Engineer"}},
+		{name: "salary", update: models.Employee{Salary: usd("1000.50")}},
+		{name: "no fields", update: models.Employee{}, message: "update has no fields to set"},
+		{name: "id", update: models.Employee{ID: "1", Position: "Engineer"}, message: "update cannot set the employee id"},
+		{name: "version", update: models.Employee{Version: 2, Position: "Engineer"}, message: "update cannot set the employee version"},
+		{name: "invalid salary", update: models.Employee{Salary: usd("1000.505")}, message: "employee salary is invalid, USD amounts have at most 2 decimal places"},
+		{name: "invalid department", update: models.Employee{DepartmentID: &invalidID}, message: "employee department_id is invalid"},
+		{name: "invalid manager", update: models.Employee{ManagerID: &invalidID}, message: "employee manager_id is invalid"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.message, validateBatchUpdate(test.update))
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseIdempotencyKey returns the Idempotency-Key of a request, "" when it has none, and the hash
+// identifying the request made with it: its actor, method, path, query, content type and body. A
+// retry must repeat the request exactly, with the same credentials, to be given the response of
+// the first attempt, so that a key is never replayed to another caller of the tenant.
+func ParseIdempotencyKey(ctx *gin.Context) (string, string, error) {
+	key := ctx.GetHeader(constants.IdempotencyKey)
+	if key == "" {
+		return "", "", nil
+	}
+	if len(key) > constants.MaxIdempotencyKeyLength {
+		return "", "", fmt.Errorf("%s is longer than %d characters", constants.IdempotencyKey, constants.MaxIdempotencyKeyLength)
+	}
+	for _, c := range key {
+		if c < 0x21 || c > 0x7e {
+			return "", "", fmt.Errorf("%s must only hold printable ASCII characters", constants.IdempotencyKey)
+		}
+	}
+
+	body, err := requestBody(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	hash := sha256.New()
+	for _, part := range []string{GetActor(ctx), ctx.Request.Method, ctx.Request.URL.Path, ctx.Request.URL.RawQuery, ctx.ContentType()} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return key, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// requestBody returns the body bound by ShouldBindBodyWith, or reads it and puts it back for
+// the handler. A body read here is limited like an import, the largest body the API accepts.
+func requestBody(ctx *gin.Context) ([]byte, error) {
+	if cached, ok := ctx.Get(gin.BodyBytesKey); ok {
+		if body, ok := cached.([]byte); ok {
+			return body, nil
+		}
+	}
+	if ctx.Request.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, constants.MaxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, err
+		}
+		return nil, errors.New(constants.InvalidBody)
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
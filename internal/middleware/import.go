@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importColumns are the columns accepted in the header of a CSV import
+var importColumns = []string{"name", "position", "salary", "currency", "department_id", "manager_id"}
+
+func ValidateImportEmployeesRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+
+		mode := ctx.DefaultQuery("mode", models.ImportModeAtomic)
+		if mode != models.ImportModeAtomic && mode != models.ImportModeBestEffort {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "mode must be atomic or best_effort")
+			return
+		}
+
+		if contentType := ctx.ContentType(); contentType != constants.TextCSV && contentType != constants.ApplicationNDJSON {
+			utils.RespondWithError(ctx, http.StatusUnsupportedMediaType, "import accepts "+constants.TextCSV+" or "+constants.ApplicationNDJSON)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ParseEmployeeImport reads the CSV or NDJSON body of a bulk import. Rows that pass the checks
+// of ValidateCreateEmployeeRequest are returned as employees to create, the others as results
+// carrying the reason they were rejected. An error means the file as a whole cannot be read.
+func ParseEmployeeImport(ctx *gin.Context) ([]models.ImportRow, []models.ImportResult, error) {
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, constants.MaxImportBytes)
+
+	var rows []models.ImportRow
+	var invalid []models.ImportResult
+	add := func(line int, employee models.Employee, message string) error {
+		if len(rows)+len(invalid) == constants.MaxImportRows {
+			return fmt.Errorf("an import is limited to %d rows", constants.MaxImportRows)
+		}
+		if message == "" {
+			message = validateNewEmployee(employee)
+		}
+		if message != "" {
+			invalid = append(invalid, models.ImportResult{Line: line, Error: message})
+			return nil
+		}
+		rows = append(rows, models.ImportRow{Line: line, Employee: employee})
+		return nil
+	}
+
+	var err error
+	if ctx.ContentType() == constants.TextCSV {
+		err = readCSVImport(body, add)
+	} else {
+		err = readNDJSONImport(body, add)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows)+len(invalid) == 0 {
+		return nil, nil, errors.New("the file has no rows")
+	}
+	return rows, invalid, nil
+}
+
+// readCSVImport reads a CSV file whose header names the columns, in any order, out of
+// importColumns. Each record is passed to add along with the line it starts on.
+func readCSVImport(body io.Reader, add func(int, models.Employee, string) error) error {
+	reader := csv.NewReader(body)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return errors.New("the file is empty")
+	}
+	if err != nil {
+		return err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheets often save CSV files with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(importColumns, name) {
+			return fmt.Errorf("unknown column %q, the columns are %s", name, strings.Join(importColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return fmt.Errorf("column %q appears more than once", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "position", "salary"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("column %q is missing", required)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			message := fmt.Sprintf("expected %d fields, found %d", len(header), len(record))
+			if err := add(parseErr.StartLine, models.Employee{}, message); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		employee, message := csvEmployee(record, columns)
+		if err := add(line, employee, message); err != nil {
+			return err
+		}
+	}
+}
+
+// csvEmployee reads the employee of a CSV record, a non-empty message tells why it cannot be read
+func csvEmployee(record []string, columns map[string]int) (models.Employee, string) {
+	value := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	employee := models.Employee{Name: value("name"), Position: value("position")}
+	if amount := value("salary"); amount != "" {
+		parsed, err := models.ParseDecimal(amount)
+		if err != nil {
+			return employee, "employee salary is invalid, " + err.Error()
+		}
+		salary := models.Money{Amount: parsed, Currency: constants.DefaultCurrency}
+		if currency := value("currency"); currency != "" {
+			salary.Currency = currency
+		}
+		employee.Salary = &salary
+	}
+	if departmentID := value("department_id"); departmentID != "" {
+		employee.DepartmentID = &departmentID
+	}
+	if managerID := value("manager_id"); managerID != "" {
+		employee.ManagerID = &managerID
+	}
+	return employee, ""
+}
+
+// readNDJSONImport reads one employee, in the body format of the create endpoint, per line.
+// Blank lines are skipped.
+func readNDJSONImport(body io.Reader, add func(int, models.Employee, string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), constants.MaxImportBytes)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var employee models.Employee
+		message := ""
+		if err := json.Unmarshal(text, &employee); err != nil {
+			message = constants.InvalidBody + ", " + err.Error()
+		}
+		if err := add(line, employee, message); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
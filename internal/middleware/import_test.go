@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// importContext returns the context of an import uploading body with the given content type
+func importContext(contentType, body string) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/employees:import", strings.NewReader(body))
+	ctx.Request.Header.Set(constants.ContentType, contentType)
+	return ctx
+}
+
+func TestParseEmployeeImport(t *testing.T) {
+	department := "3"
+	for _, test := range []struct {
+		name        string
+		contentType string
+		body        string
+		rows        []models.ImportRow
+		invalid     []models.ImportResult
+		err         string
+	}{
+		{
+			name:        "csv",
+			contentType: constants.TextCSV,
+			body:        "\ufeffName, salary,position,department_id\nJohn Doe,1000.5,Engineer,3\nJane Roe,900,Manager,\n",
+			rows: []models.ImportRow{
+				{Line: 2, Employee: models.Employee{Name: "John Doe", Position: "Engineer", Salary: usd("1000.5"), DepartmentID: &department}},
+				{Line: 3, Employee: models.Employee{Name: "Jane Roe", Position: "Manager", Salary: usd("900")}},
+			},
+		},
+		{
+			name:        "csv with invalid rows",
+			contentType: constants.TextCSV,
+			body:        "name,position,salary,currency\nJohn Doe,Engineer,lots,USD\nJane Roe,Manager\n,Manager,900,USD\nMax Mustermann,Engineer,1500.5,JPY\nErika Musterfrau,Engineer,1000,EUR\n",
+			rows: []models.ImportRow{
+				{Line: 6, Employee: models.Employee{Name: "Erika Musterfrau", Position: "Engineer", Salary: &models.Money{Amount: decimal("1000"), Currency: "EUR"}}},
+			},
+			invalid: []models.ImportResult{
+				{Line: 2, Error: `employee salary is invalid, "lots" is not a decimal number`},
+				{Line: 3, Error: "expected 4 fields, found 2"},
+				{Line: 4, Error: "employee name is missing"},
+				{Line: 5, Error: "employee salary is invalid, JPY amounts have at most 0 decimal places"},
+			},
+		},
+		{name: "csv without a required column", contentType: constants.TextCSV, body: "name,position\nJohn Doe,Engineer\n", err: `column "salary" is missing`},
+		{name: "csv with an unknown column", contentType: constants.TextCSV, body: "name,position,salary,email\n", err: `unknown column "email", the columns are name, position, salary, currency, department_id, manager_id`},
+		{name: "csv with a repeated column", contentType: constants.TextCSV, body: "name,position,salary,Name\n", err: `column "name" appears more than once`},
+		{name: "empty csv", contentType: constants.TextCSV, body: "", err: "the file is empty"},
+		{name: "csv without rows", contentType: constants.TextCSV, body: "name,position,salary\n", err: "the file has no rows"},
+		{
+			name:        "ndjson",
+			contentType: constants.ApplicationNDJSON,
+			body:        "{\"name\": \"John Doe\", \"position\": \"Engineer\", \"salary\": 1000.5}\n\n{\"name\": \"Jane Roe\"}\nnot json\n",
+			rows: []models.ImportRow{
+				{Line: 1, Employee: models.Employee{Name: "John Doe", Position: "Engineer", Salary: usd("1000.5")}},
+			},
+			invalid: []models.ImportResult{
+				{Line: 3, Error: "employee position is missing"},
+				{Line: 4, Error: constants.InvalidBody + ", invalid character 'o' in literal null (expecting 'u')"},
+			},
+		},
+		{name: "ndjson of blank lines", contentType: constants.ApplicationNDJSON, body: "\n\n", err: "the file has no rows"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rows, invalid, err := ParseEmployeeImport(importContext(test.contentType, test.body))
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.rows, rows)
+			assert.Equal(t, test.invalid, invalid)
+		})
+	}
+}
+
+func TestValidateImportEmployeesRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, test := range []struct {
+		name        string
+		query       string
+		contentType string
+		status      int
+	}{
+		{name: "atomic csv", contentType: constants.TextCSV, status: http.StatusOK},
+		{name: "best effort ndjson", query: "?mode=best_effort", contentType: constants.ApplicationNDJSON, status: http.StatusOK},
+		{name: "unknown mode", query: "?mode=partial", contentType: constants.TextCSV, status: http.StatusBadRequest},
+		{name: "json", contentType: constants.ApplicationJSON, status: http.StatusUnsupportedMediaType},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/v1/employees:import", ValidateImportEmployeesRequest(), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			request := httptest.NewRequest(http.MethodPost, "/v1/employees:import"+test.query, strings.NewReader("name,position,salary\n"))
+			request.Header.Set(constants.ContentType, test.contentType)
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			assert.Equal(t, test.status, response.Code, response.Body.String())
+		})
+	}
+}
+
+// decimal parses a decimal number of a test fixture
+func decimal(value string) models.Decimal {
+	d, err := models.ParseDecimal(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// usd returns a salary in US dollars
+func usd(amount string) *models.Money {
+	return &models.Money{Amount: decimal(amount), Currency: "USD"}
+}
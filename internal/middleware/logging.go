@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	"assignment/internal/utils"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// employeeRoutes prefixes the routes of an employee, whose :id is the ID of the employee
+var employeeRoutes = path.Join(constants.ForwardSlash, constants.Version, constants.Employee) + constants.ForwardSlash
+
+// LogRequests gives the request the logger returned by utils.Log, which adds the transaction-id,
+// the route and, on the routes of an employee, the employee ID to every entry. The actor and
+// tenant are added once the request is authenticated. The request is then written to the access
+// log when logging.access_log is set, without its query string which may hold names.
+func LogRequests() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		fields := []zap.Field{zap.String("txid", GetTransactionID(ctx))}
+		route := ctx.FullPath()
+		if route != "" {
+			fields = append(fields, zap.String("route", route))
+		}
+		if id := ctx.Param("id"); id != "" && strings.HasPrefix(route, employeeRoutes) {
+			fields = append(fields, zap.String("employee_id", id))
+		}
+		if span := trace.SpanContextFromContext(ctx.Request.Context()); span.IsValid() {
+			fields = append(fields, zap.String("trace_id", span.TraceID().String()))
+		}
+		ctx.Set(constants.LoggerKey, utils.Logger.With(fields...))
+
+		ctx.Next()
+
+		if !config.GetConfig().Logging.AccessLog {
+			return
+		}
+		status := ctx.Writer.Status()
+		accessFields := []zap.Field{
+			zap.String("method", ctx.Request.Method),
+			zap.String("path", ctx.Request.URL.Path),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", max(ctx.Writer.Size(), 0)),
+			zap.String("client_ip", ctx.ClientIP()),
+			zap.String("user_agent", ctx.Request.UserAgent()),
+		}
+		if status >= http.StatusInternalServerError {
+			utils.Log(ctx).Error("request served", accessFields...)
+			return
+		}
+		utils.Log(ctx).Info("request served", accessFields...)
+	}
+}
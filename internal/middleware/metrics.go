@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"assignment/internal/metrics"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequests = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by route, method and status.",
+	}, []string{"route", "method", "status"})
+	httpRequestDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of the HTTP requests served, by route, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+// unmatchedRoute labels the requests for a path that no route matches, so that scanners cannot
+// create a series per path they try
+const unmatchedRoute = "unmatched"
+
+// RecordMetrics counts the requests and measures how long they take, by route template rather
+// than by path so that the IDs in the paths do not make a series each
+func RecordMetrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := metricsRoute(ctx)
+		method := ctx.Request.Method
+		status := strconv.Itoa(ctx.Writer.Status())
+		httpRequests.WithLabelValues(route, method, status).Inc()
+		httpRequestDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// metricsRoute returns the route template of the request. The custom methods of the employees
+// share one route and are told apart by their name, unless the name is unknown.
+func metricsRoute(ctx *gin.Context) string {
+	route := ctx.FullPath()
+	if route == "" {
+		return unmatchedRoute
+	}
+	if action := ctx.Param("action"); action != "" && ctx.Writer.Status() != http.StatusNotFound {
+		route = strings.Replace(route, ":action", action, 1)
+	}
+	return route
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// employeePatchDocument is the document a PATCH of an employee applies to, the fields a client
+// can change. Patching any other member, such as id or version, is rejected.
+type employeePatchDocument struct {
+	Name         string        `json:"name"`
+	Position     string        `json:"position"`
+	Salary       *models.Money `json:"salary"`
+	DepartmentID *string       `json:"department_id"`
+	ManagerID    *string       `json:"manager_id"`
+}
+
+func ValidatePatchEmployeeRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		employeeID := ctx.Param("id")
+		if !isValidID(&employeeID) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "employee Id is invalid")
+			return
+		}
+
+		contentType := ctx.ContentType()
+		if contentType != constants.MergePatchJSON && contentType != constants.JSONPatchJSON {
+			utils.RespondWithError(ctx, http.StatusUnsupportedMediaType, "patch accepts "+constants.MergePatchJSON+" or "+constants.JSONPatchJSON)
+			return
+		}
+
+		body, err := ctx.GetRawData()
+		if err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody)
+			return
+		}
+		// Kept where ShouldBindBodyWith keeps it, for ApplyEmployeePatch to read again
+		ctx.Set(gin.BodyBytesKey, body)
+		if _, err := parseEmployeePatch(contentType, body); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody+", "+err.Error())
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// parseEmployeePatch reads a JSON Merge Patch or a JSON Patch, depending on the content type,
+// and returns the function applying it to a document
+func parseEmployeePatch(contentType string, body []byte) (func(interface{}) (interface{}, error), error) {
+	if contentType == constants.JSONPatchJSON {
+		operations, err := utils.ParseJSONPatch(body)
+		if err != nil {
+			return nil, err
+		}
+		return func(document interface{}) (interface{}, error) {
+			return utils.ApplyJSONPatch(document, operations)
+		}, nil
+	}
+
+	patch, err := utils.DecodeJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	// A merge patch that is not an object would replace the employee with a scalar or array
+	if _, ok := patch.(map[string]interface{}); !ok {
+		return nil, errors.New("a merge patch must be a JSON object")
+	}
+	return func(document interface{}) (interface{}, error) {
+		return utils.MergePatch(document, patch), nil
+	}, nil
+}
+
+// ApplyEmployeePatch applies the patch validated by ValidatePatchEmployeeRequest to the current
+// employee. A member set to null, or removed, is cleared, and the result is held to the rules
+// of a new employee, so that the name, position and salary cannot be cleared. The error tells
+// why the patch cannot be applied or why the patched employee is invalid.
+func ApplyEmployeePatch(ctx *gin.Context, current models.Employee) (models.Employee, error) {
+	body, _ := ctx.Get(gin.BodyBytesKey)
+	apply, err := parseEmployeePatch(ctx.ContentType(), body.([]byte))
+	if err != nil {
+		return models.Employee{}, err
+	}
+
+	data, err := json.Marshal(employeePatchDocument{
+		Name:         current.Name,
+		Position:     current.Position,
+		Salary:       current.Salary,
+		DepartmentID: current.DepartmentID,
+		ManagerID:    current.ManagerID,
+	})
+	if err != nil {
+		return models.Employee{}, err
+	}
+	document, err := utils.DecodeJSON(data)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	if document, err = apply(document); err != nil {
+		return models.Employee{}, err
+	}
+
+	// Money decodes itself and ignores unknown members, so the salary is checked here
+	if object, ok := document.(map[string]interface{}); ok {
+		if salary, ok := object["salary"].(map[string]interface{}); ok {
+			for name := range salary {
+				if name != "amount" && name != "currency" {
+					return models.Employee{}, fmt.Errorf("the patched employee is invalid, unknown field \"salary.%s\"", name)
+				}
+			}
+		}
+	}
+
+	if data, err = json.Marshal(document); err != nil {
+		return models.Employee{}, err
+	}
+	var patched employeePatchDocument
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return models.Employee{}, errors.New("the patched employee is invalid, " + strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	employee := models.Employee{
+		ID:           current.ID,
+		Name:         patched.Name,
+		Position:     patched.Position,
+		Salary:       patched.Salary,
+		DepartmentID: patched.DepartmentID,
+		ManagerID:    patched.ManagerID,
+		Version:      current.Version,
+	}
+	if message := validateNewEmployee(employee); message != "" {
+		return models.Employee{}, errors.New(message)
+	}
+	if employee.ManagerID != nil && mustAtoi(*employee.ManagerID) == mustAtoi(employee.ID) {
+		return models.Employee{}, errors.New("employee cannot be their own manager")
+	}
+	return employee, nil
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	"assignment/internal/policy"
+	"assignment/internal/utils"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// tenantIDPattern are the tenant IDs accepted, which leaves "*" to the background jobs working on
+// every tenant
+var tenantIDPattern = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9][A-Za-z0-9_-]{0,%d}$`, constants.MaxTenantIDLength-1))
+
+// resolveTenant stores the tenant of the request under constants.TenantKey and its actor under
+// constants.ActorKey, where the storage layer reads them, reporting whether there is a valid
+// tenant. The tenant the credentials belong to wins, a different X-Tenant-ID is
+// refused. Credentials without a tenant may pick one with X-Tenant-ID when tenancy.header is on
+// and they are granted policy.CrossTenant, as are the requests made while authentication is
+// disabled, and are given tenancy.default_tenant otherwise.
+func resolveTenant(ctx *gin.Context, claimed string) bool {
+	cfg := config.GetConfig().Tenancy
+	requested := strings.TrimSpace(ctx.GetHeader(constants.TenantID))
+
+	tenant := claimed
+	switch {
+	case claimed != "" && requested != "" && requested != claimed:
+		utils.RespondWithError(ctx, http.StatusForbidden, "the X-Tenant-ID header does not match the tenant of the credentials")
+		return false
+	case claimed == "" && requested != "":
+		if !cfg.Header || !GetPrincipal(ctx).Can(policy.CrossTenant) {
+			utils.RespondWithError(ctx, http.StatusForbidden, "the X-Tenant-ID header is not accepted")
+			return false
+		}
+		tenant = requested
+	case claimed == "":
+		tenant = defaultTenant()
+	}
+
+	if !tenantIDPattern.MatchString(tenant) {
+		utils.RespondWithError(ctx, http.StatusBadRequest, fmt.Sprintf("invalid tenant ID %q", tenant))
+		return false
+	}
+	actor := GetActor(ctx)
+	ctx.Set(constants.ActorKey, actor)
+	ctx.Set(constants.TenantKey, tenant)
+	utils.AddLogFields(ctx, zap.String("actor", actor), zap.String("tenant", tenant))
+	return true
+}
+
+// GetTenant returns the tenant a request is made for, whose records are the only ones it reaches
+func GetTenant(c *gin.Context) string {
+	if tenant := c.GetString(constants.TenantKey); tenant != "" {
+		return tenant
+	}
+	return defaultTenant()
+}
+
+func defaultTenant() string {
+	if tenant := config.GetConfig().Tenancy.DefaultTenant; tenant != "" {
+		return tenant
+	}
+	return constants.DefaultTenant
+}
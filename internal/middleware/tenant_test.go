@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecret = "0123456789abcdef0123456789abcdef"
+
+// testToken returns an HS256 token of alice with the claims added
+func testToken(claims map[string]interface{}) string {
+	payload := map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+	for name, value := range claims {
+		payload[name] = value
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	body, _ := json.Marshal(payload)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// tenantRouter returns a router answering the tenant of authenticated requests
+func tenantRouter(t *testing.T, authEnabled bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	config.SetConfig(config.GlobalConfig{
+		Auth: config.Auth{Enabled: authEnabled, Algorithms: []string{"HS256"}, HMACSecret: testSecret},
+		RBAC: config.RBAC{RolesClaim: "roles", Roles: map[string][]string{
+			"viewer":   {"employees:read"},
+			"operator": {"employees:read", "tenants:cross"},
+		}},
+		Tenancy: config.Tenancy{Claim: "tenant_id", Header: true, DefaultTenant: "default"},
+	})
+	assert.NoError(t, InitAuthentication())
+
+	router := gin.New()
+	router.GET("/tenant", Authenticate(), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, GetTenant(ctx))
+	})
+	return router
+}
+
+func TestAuthenticate_Tenant(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		authEnabled bool
+		claims      map[string]interface{}
+		header      string
+		status      int
+		tenant      string
+	}{
+		{name: "token without tenant asking for another tenant", authEnabled: true, claims: map[string]interface{}{"roles": "viewer"}, header: "acme", status: http.StatusForbidden},
+		{name: "token without tenant", authEnabled: true, claims: map[string]interface{}{"roles": "viewer"}, status: http.StatusOK, tenant: "default"},
+		{name: "token granted tenants:cross", authEnabled: true, claims: map[string]interface{}{"roles": "operator"}, header: "acme", status: http.StatusOK, tenant: "acme"},
+		{name: "token of a tenant", authEnabled: true, claims: map[string]interface{}{"roles": "viewer", "tenant_id": "acme"}, status: http.StatusOK, tenant: "acme"},
+		{name: "token of a tenant asking for another tenant", authEnabled: true, claims: map[string]interface{}{"roles": "operator", "tenant_id": "acme"}, header: "globex", status: http.StatusForbidden},
+		{name: "authentication disabled", header: "acme", status: http.StatusOK, tenant: "acme"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			router := tenantRouter(t, test.authEnabled)
+			request := httptest.NewRequest(http.MethodGet, "/tenant", nil)
+			if test.authEnabled {
+				request.Header.Set(constants.Authorization, constants.Bearer+" "+testToken(test.claims))
+			}
+			if test.header != "" {
+				request.Header.Set(constants.TenantID, test.header)
+			}
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			assert.Equal(t, test.status, response.Code, response.Body.String())
+			if test.status == http.StatusOK {
+				assert.Equal(t, test.tenant, response.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/tracing"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace starts the server span of the request, continuing the trace of the W3C traceparent
+// header of the caller when there is one. A request starting a trace is traced under its
+// transaction-id, both being 128 bit IDs, so that the trace of a transaction-id found in the
+// logs is found too. The traceparent of the span is returned to the caller.
+func Trace() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		txid := GetTransactionID(ctx)
+
+		propagator := otel.GetTextMapPropagator()
+		traced := propagator.Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+		if !trace.SpanContextFromContext(traced).IsValid() {
+			traced = tracing.ContextWithTraceID(traced, trace.TraceID(uuid.MustParse(txid)))
+		}
+		traced, span := tracing.Tracer().Start(traced, ctx.Request.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(ctx.Request.Method),
+				semconv.URLPath(ctx.Request.URL.Path),
+				attribute.String("transaction.id", txid),
+			),
+		)
+		ctx.Request = ctx.Request.WithContext(traced)
+		propagator.Inject(traced, propagation.HeaderCarrier(ctx.Writer.Header()))
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		if route := ctx.FullPath(); route != "" {
+			span.SetName(ctx.Request.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if tenant := ctx.GetString(constants.TenantKey); tenant != "" {
+			span.SetAttributes(attribute.String("tenant.id", tenant))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	"assignment/internal/tracing"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	assert.NoError(t, tracing.Init(config.Tracing{Enabled: true, Exporter: tracing.ExporterFile, File: filepath.Join(t.TempDir(), "traces.jsonl"), SampleRatio: 1}))
+	defer tracing.Shutdown(context.Background())
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(Trace())
+	router.GET("/v1/employees", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, trace.SpanContextFromContext(ctx).TraceID().String())
+	})
+
+	// A caller's traceparent is continued, and the traceparent of the server span returned
+	req := httptest.NewRequest(http.MethodGet, "/v1/employees", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", w.Body.String())
+	assert.True(t, strings.HasPrefix(w.Header().Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+	assert.NotContains(t, w.Header().Get("traceparent"), "00f067aa0ba902b7")
+
+	// Without one, the trace is started under the transaction-id
+	req = httptest.NewRequest(http.MethodGet, "/v1/employees", nil)
+	req.Header.Set(constants.TransactionID, "0f8fad5b-d9cb-469f-a165-70867728950e")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "0f8fad5bd9cb469fa16570867728950e", w.Body.String())
+}
@@ -4,7 +4,14 @@ import (
 	"assignment/internal/constants"
 	"assignment/internal/models"
 	"assignment/internal/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -23,6 +30,18 @@ func GetTransactionID(c *gin.Context) string {
 	return transactionID
 }
 
+// GetActor returns who makes the request, as recorded in the audit trail. It is the actor stored
+// in the context under constants.ActorKey when there is one, the X-Actor header otherwise.
+func GetActor(c *gin.Context) string {
+	if actor := c.GetString(constants.ActorKey); actor != "" {
+		return actor
+	}
+	if actor := strings.TrimSpace(c.GetHeader(constants.Actor)); actor != "" {
+		return actor
+	}
+	return constants.UnknownActor
+}
+
 func ValidateCreateEmployeeRequest() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		_ = GetTransactionID(ctx)
@@ -34,41 +53,137 @@ func ValidateCreateEmployeeRequest() gin.HandlerFunc {
 			return
 		}
 
-		if employee.Name == "" {
-			utils.RespondWithError(ctx, http.StatusBadRequest, "employee name is missing")
+		if message := validateNewEmployee(employee); message != "" {
+			utils.RespondWithError(ctx, http.StatusBadRequest, message)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// validateNewEmployee returns why an employee cannot be created, or "" when it can. The rows of
+// a bulk import are held to the same rules.
+func validateNewEmployee(employee models.Employee) string {
+	if employee.Name == "" {
+		return "employee name is missing"
+	}
+
+	if employee.Position == "" {
+		return "employee position is missing"
+	}
+
+	if employee.Salary == nil {
+		return "employee salary is missing"
+	} else if err := employee.Salary.Validate(); err != nil {
+		return "employee salary is invalid, " + err.Error()
+	}
+
+	if !isValidID(employee.DepartmentID) {
+		return "employee department_id is invalid"
+	}
+
+	if !isValidID(employee.ManagerID) {
+		return "employee manager_id is invalid"
+	}
+	return ""
+}
+
+func ValidateSalaryChangeRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		employeeID := ctx.Param("id")
+		if !isValidID(&employeeID) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "employee Id is invalid")
+			return
+		}
+
+		// validate the body params
+		var change models.SalaryChange
+		err := ctx.ShouldBindBodyWith(&change, binding.JSON)
+		if err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody)
+			return
 		}
 
-		if employee.Position == "" {
-			utils.RespondWithError(ctx, http.StatusBadRequest, "employee position is missing")
+		if change.Amount == nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "amount is missing")
+			return
+		}
+		salary := models.Money{Amount: *change.Amount, Currency: change.Currency}
+		if salary.Currency == "" {
+			salary.Currency = constants.DefaultCurrency
+		}
+		if err := salary.Validate(); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
 		}
 
-		if employee.Salary == nil {
-			utils.RespondWithError(ctx, http.StatusBadRequest, "employee salary is missing")
+		if len(change.Reason) > 255 {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "reason must be at most 255 characters")
+			return
 		}
 
 		ctx.Next()
 	}
 }
 
-func ValidateUpdateEmployeeRequest() gin.HandlerFunc {
+// ValidateReplaceEmployeeRequest checks the body of PUT /v1/employees/:id, which replaces every
+// field of the employee, so it is held to the rules of a new employee. Unknown members are
+// rejected rather than ignored, as a misspelt optional field would otherwise clear it.
+func ValidateReplaceEmployeeRequest() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		_ = GetTransactionID(ctx)
+		employeeID := ctx.Param("id")
+		if !isValidID(&employeeID) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "employee Id is invalid")
+			return
+		}
+
 		// validate the body params
 		var employee models.Employee
-		err := ctx.ShouldBindBodyWith(&employee, binding.JSON)
-		if err != nil {
-			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody)
+		if err := bindStrictJSON(ctx, &employee); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody+", "+strings.TrimPrefix(err.Error(), "json: "))
+			return
+		}
+
+		// The ID comes from the path, the body may only repeat it
+		if employee.ID != "" && (!isValidID(&employee.ID) || mustAtoi(employee.ID) != mustAtoi(employeeID)) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "employee Id in the body does not match the path")
 			return
 		}
 
-		if employee.ID == "" {
-			utils.RespondWithError(ctx, http.StatusBadRequest, "employee Id is missing")
+		if message := validateNewEmployee(employee); message != "" {
+			utils.RespondWithError(ctx, http.StatusBadRequest, message)
+			return
+		}
+		if employee.ManagerID != nil && mustAtoi(*employee.ManagerID) == mustAtoi(employeeID) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "employee cannot be their own manager")
+			return
 		}
 
 		ctx.Next()
 	}
 }
 
+// bindStrictJSON binds the JSON body like ShouldBindBodyWith, and fails on members obj has no
+// field for
+func bindStrictJSON(ctx *gin.Context, obj interface{}) error {
+	if err := ctx.ShouldBindBodyWith(obj, binding.JSON); err != nil {
+		return err
+	}
+	body, _ := ctx.Get(gin.BodyBytesKey)
+	decoder := json.NewDecoder(bytes.NewReader(body.([]byte)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(obj)
+}
+
+// mustAtoi converts an ID already checked by isValidID
+func mustAtoi(id string) int {
+	n, _ := strconv.Atoi(id)
+	return n
+}
+
 func ValidateEmployeeID() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		_ = GetTransactionID(ctx)
@@ -82,3 +197,286 @@ func ValidateEmployeeID() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// isValidID reports whether an optional reference to another record is a positive integer
+func isValidID(id *string) bool {
+	if id == nil {
+		return true
+	}
+	n, err := strconv.Atoi(*id)
+	return err == nil && n > 0
+}
+
+func ValidateCreateDepartmentRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		// validate the body params
+		var department models.Department
+		err := ctx.ShouldBindBodyWith(&department, binding.JSON)
+		if err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody)
+			return
+		}
+
+		if department.Name == "" {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "department name is missing")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func ValidateUpdateDepartmentRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		departmentID := ctx.Param("id")
+		if !isValidID(&departmentID) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "department Id is invalid")
+			return
+		}
+
+		// validate the body params
+		var department models.Department
+		err := ctx.ShouldBindBodyWith(&department, binding.JSON)
+		if err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, constants.InvalidBody)
+			return
+		}
+
+		if department.Name == "" {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "department name is missing")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func ValidateDepartmentID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		departmentID := ctx.Param("id")
+		if departmentID == "" || departmentID == ":" {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "department Id is missing the request")
+			return
+		}
+
+		if !isValidID(&departmentID) {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "department Id is invalid")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ParseEmployeeQuery reads the paging, filter and sort query parameters of the employee listing.
+// page defaults to 1 and pagesize to constants.DefaultPageSize, a cursor replaces the page.
+func ParseEmployeeQuery(ctx *gin.Context) (models.EmployeeQuery, error) {
+	query := models.EmployeeQuery{
+		Position:     ctx.Query("position"),
+		NameContains: ctx.Query("q"),
+		Cursor:       ctx.Query("cursor"),
+	}
+
+	if query.Cursor != "" && ctx.Query("page") != "" {
+		return query, fmt.Errorf("page and cursor cannot be used together")
+	}
+
+	var err error
+	if query.Page, query.PageSize, err = parsePaging(ctx); err != nil {
+		return query, err
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target **models.Decimal
+	}{{"salary_min", &query.SalaryMin}, {"salary_max", &query.SalaryMax}} {
+		if value := ctx.Query(bound.name); value != "" {
+			salary, err := models.ParseDecimal(value)
+			if err != nil {
+				return query, fmt.Errorf("%s must be a decimal number", bound.name)
+			}
+			*bound.target = &salary
+		}
+	}
+	if query.SalaryMin != nil && query.SalaryMax != nil && query.SalaryMin.Cmp(*query.SalaryMax) > 0 {
+		return query, fmt.Errorf("salary_min must not be greater than salary_max")
+	}
+
+	if value := ctx.Query("created_after"); value != "" {
+		createdAfter, err := parseTimestamp(value)
+		if err != nil {
+			return query, fmt.Errorf("created_after must be a date (2006-01-02) or an RFC 3339 timestamp")
+		}
+		query.CreatedAfter = &createdAfter
+	}
+
+	if value := ctx.Query("include_deleted"); value != "" {
+		includeDeleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("include_deleted must be true or false")
+		}
+		query.IncludeDeleted = includeDeleted
+	}
+
+	if value := ctx.Query("sort"); value != "" {
+		sort, err := parseSort(value, models.EmployeeSortFields)
+		if err != nil {
+			return query, err
+		}
+		query.Sort = sort
+	}
+
+	return query, nil
+}
+
+// parsePaging reads the page and pagesize query parameters, which default to 1 and
+// constants.DefaultPageSize
+func parsePaging(ctx *gin.Context) (int, int, error) {
+	page, pageSize := 1, constants.DefaultPageSize
+
+	if value := ctx.Query("page"); value != "" {
+		var err error
+		page, err = strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive number")
+		}
+	}
+
+	if value := ctx.Query("pagesize"); value != "" {
+		var err error
+		pageSize, err = strconv.Atoi(value)
+		if err != nil || pageSize < 1 || pageSize > constants.MaxPageSize {
+			return 0, 0, fmt.Errorf("pagesize must be a number between 1 and %d", constants.MaxPageSize)
+		}
+	}
+
+	return page, pageSize, nil
+}
+
+// ParseAsOf reads the as_of query parameter, the point in time a resource is resolved at,
+// which defaults to now
+func ParseAsOf(ctx *gin.Context) (time.Time, error) {
+	value := ctx.Query("as_of")
+	if value == "" {
+		return time.Now(), nil
+	}
+	asOf, err := parseTimestamp(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("as_of must be a date (2006-01-02) or an RFC 3339 timestamp")
+	}
+	return asOf, nil
+}
+
+// parseTimestamp accepts either a date or an RFC 3339 timestamp
+func parseTimestamp(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// parseSort parses a comma separated list of fields, each optionally prefixed with - for
+// descending order, and rejects any field that is not in allowed
+func parseSort(value string, allowed []string) ([]models.SortField, error) {
+	var fields []models.SortField
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(value, ",") {
+		field := models.SortField{Field: strings.TrimSpace(key)}
+		if strings.HasPrefix(field.Field, "-") {
+			field.Field = strings.TrimPrefix(field.Field, "-")
+			field.Descending = true
+		}
+		if !slices.Contains(allowed, field.Field) {
+			return nil, fmt.Errorf("cannot sort by %q, sortable fields are %s", field.Field, strings.Join(allowed, ", "))
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("sort field %q is repeated", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func ValidateListEmployeesRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		if _, err := ParseEmployeeQuery(ctx); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func ValidateExportEmployeesRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		switch ctx.DefaultQuery("format", models.ExportFormatCSV) {
+		case models.ExportFormatCSV, models.ExportFormatNDJSON, models.ExportFormatXLSX:
+		default:
+			utils.RespondWithError(ctx, http.StatusBadRequest, "format must be csv, ndjson or xlsx")
+			return
+		}
+		if _, err := ParseEmployeeQuery(ctx); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ParseAuditQuery reads the paging and filter query parameters of the audit trail.
+// from is inclusive and to is exclusive.
+func ParseAuditQuery(ctx *gin.Context) (models.AuditQuery, error) {
+	query := models.AuditQuery{
+		Actor: ctx.Query("actor"),
+	}
+
+	var err error
+	if query.Page, query.PageSize, err = parsePaging(ctx); err != nil {
+		return query, err
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		if value := ctx.Query(bound.name); value != "" {
+			t, err := parseTimestamp(value)
+			if err != nil {
+				return query, fmt.Errorf("%s must be a date (2006-01-02) or an RFC 3339 timestamp", bound.name)
+			}
+			*bound.target = &t
+		}
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return query, fmt.Errorf("from must be before to")
+	}
+
+	if value := ctx.Query("employee_id"); value != "" {
+		if !isValidID(&value) {
+			return query, fmt.Errorf("employee_id is invalid")
+		}
+		query.EmployeeID = &value
+	}
+
+	return query, nil
+}
+
+func ValidateListAuditRequest() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_ = GetTransactionID(ctx)
+		if _, err := ParseAuditQuery(ctx); err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx.Next()
+	}
+}
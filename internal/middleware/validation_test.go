@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"assignment/internal/constants"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEmployeeRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The handler after the validation records that the request reached it
+	reached := false
+	handler := func(ctx *gin.Context) {
+		reached = true
+		ctx.Status(http.StatusOK)
+	}
+	router := gin.New()
+	router.POST("/v1/employees", ValidateCreateEmployeeRequest(), handler)
+	router.PUT("/v1/employees/:id", ValidateReplaceEmployeeRequest(), handler)
+
+	valid := `"name": "Jane Roe", "position": "Engineer", "salary": {"amount": 1000, "currency": "USD"}`
+	for _, test := range []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		message string
+	}{
+		{name: "create", method: http.MethodPost, target: "/v1/employees", body: `{` + valid + `, "department_id": "1", "manager_id": "2"}`},
+		{name: "create with an invalid department", method: http.MethodPost, target: "/v1/employees", body: `{` + valid + `, "department_id": "x"}`, message: "employee department_id is invalid"},
+		{name: "create with an invalid manager", method: http.MethodPost, target: "/v1/employees", body: `{` + valid + `, "manager_id": "0"}`, message: "employee manager_id is invalid"},
+		{name: "create with both invalid", method: http.MethodPost, target: "/v1/employees", body: `{` + valid + `, "department_id": "x", "manager_id": "0"}`, message: "employee department_id is invalid"},
+		{name: "create without a name and with an invalid department", method: http.MethodPost, target: "/v1/employees", body: `{"position": "Engineer", "salary": {"amount": 1000, "currency": "USD"}, "department_id": "x"}`, message: "employee name is missing"},
+		{name: "replace", method: http.MethodPut, target: "/v1/employees/3", body: `{` + valid + `, "department_id": "1", "manager_id": "2"}`},
+		{name: "replace with an invalid department", method: http.MethodPut, target: "/v1/employees/3", body: `{` + valid + `, "department_id": "-1"}`, message: "employee department_id is invalid"},
+		{name: "replace with an invalid manager", method: http.MethodPut, target: "/v1/employees/3", body: `{` + valid + `, "manager_id": "x"}`, message: "employee manager_id is invalid"},
+		{name: "replace with its own manager", method: http.MethodPut, target: "/v1/employees/3", body: `{` + valid + `, "manager_id": "3"}`, message: "employee cannot be their own manager"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			reached = false
+			request := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			request.Header.Set(constants.ContentType, constants.ApplicationJSON)
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			if test.message == "" {
+				assert.Equal(t, http.StatusOK, response.Code, response.Body.String())
+				assert.True(t, reached)
+				return
+			}
+			// A single error is written and the chain stops there
+			var body struct {
+				Code    int
+				Message string
+			}
+			assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body), response.Body.String())
+			assert.Equal(t, http.StatusBadRequest, response.Code)
+			assert.Equal(t, http.StatusBadRequest, body.Code)
+			assert.Equal(t, test.message, body.Message)
+			assert.False(t, reached)
+		})
+	}
+}
@@ -1,13 +1,299 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
-// Employee struct defines the structure of an employee record
+// Employee struct defines the structure of an employee record.
+// Version is incremented on every write and served as the ETag of the employee.
+// DeletedAt is set once the employee is soft deleted.
 type Employee struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Position      string     `json:"position"`
+	Salary        *Money     `json:"salary"`
+	DepartmentID  *string    `json:"department_id"`
+	ManagerID     *string    `json:"manager_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUpdatedAt time.Time  `json:"last_updated_at"`
+	Version       int        `json:"version"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Department struct defines the structure of a department record
+type Department struct {
 	ID            string    `json:"id"`
 	Name          string    `json:"name"`
-	Position      string    `json:"position"`
-	Salary        *float64  `json:"salary"`
+	Description   string    `json:"description"`
 	CreatedAt     time.Time `json:"created_at"`
 	LastUpdatedAt time.Time `json:"last_updated_at"`
 }
+
+// EmployeeReport is an employee reporting directly (level 1) or indirectly to another employee
+type EmployeeReport struct {
+	Employee
+	Level int `json:"level"`
+}
+
+// OrgChartNode is an employee together with everyone reporting to them
+type OrgChartNode struct {
+	Employee
+	Reports []*OrgChartNode `json:"reports"`
+}
+
+// EmployeeSortFields are the fields ListEmployee can order by
+var EmployeeSortFields = []string{"id", "name", "position", "salary", "created_at", "last_updated_at"}
+
+// SortField is one key of a sort order such as sort=-salary,name
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// EmployeeQuery selects the employees returned by ListEmployee and their order.
+// Filters left at their zero value are not applied. When Cursor is set the page starts after
+// the cursor and Page is ignored. Soft-deleted employees are left out unless IncludeDeleted is set.
+type EmployeeQuery struct {
+	Position       string
+	SalaryMin      *Decimal
+	SalaryMax      *Decimal
+	CreatedAfter   *time.Time
+	NameContains   string
+	DepartmentID   *string
+	IncludeDeleted bool
+	Sort           []SortField
+	Cursor         string
+	Page           int
+	PageSize       int
+}
+
+// EmployeeList is one page of the employees matching an EmployeeQuery.
+// NextCursor continues the listing after this page and is only set when HasMore is true.
+type EmployeeList struct {
+	Employees  []Employee `json:"employees"`
+	TotalCount int        `json:"total_count"`
+	Page       int        `json:"page,omitempty"`
+	PageSize   int        `json:"pagesize"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	HasMore    bool       `json:"has_more"`
+}
+
+// Actions recorded in the audit trail
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// AuditEntry is one change of an employee. Before is null for a create and After is null
+// for a purge.
+type AuditEntry struct {
+	ID            int64           `json:"id"`
+	EmployeeID    string          `json:"employee_id"`
+	Action        string          `json:"action"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	Actor         string          `json:"actor"`
+	TransactionID string          `json:"transaction_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// TenantID is the tenant of the employee, which the entry is only shown to
+	TenantID string `json:"-"`
+}
+
+// AuditQuery selects the entries returned by ListAudit, newest first.
+// Filters left at their zero value are not applied, From is inclusive and To is exclusive.
+type AuditQuery struct {
+	EmployeeID *string
+	Actor      string
+	From       *time.Time
+	To         *time.Time
+	Page       int
+	PageSize   int
+}
+
+// AuditList is one page of the entries matching an AuditQuery
+type AuditList struct {
+	Entries    []AuditEntry `json:"entries"`
+	TotalCount int          `json:"total_count"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"pagesize"`
+}
+
+// SalaryChange is one entry of the salary history of an employee. The change takes effect at
+// EffectiveFrom, which is in the future for a scheduled raise.
+type SalaryChange struct {
+	ID            int64     `json:"id"`
+	EmployeeID    string    `json:"employee_id"`
+	Amount        *Decimal  `json:"amount"`
+	Currency      string    `json:"currency"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Reason        string    `json:"reason"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Compensation is the salary history of an employee resolved at a point in time.
+// Current is the latest change in effect, nil when none is in effect yet, History lists the
+// changes in effect newest first and Scheduled the future changes soonest first.
+type Compensation struct {
+	EmployeeID string         `json:"employee_id"`
+	AsOf       time.Time      `json:"as_of"`
+	Current    *SalaryChange  `json:"current"`
+	History    []SalaryChange `json:"history"`
+	Scheduled  []SalaryChange `json:"scheduled"`
+}
+
+// Reasons recorded for the salary changes made by the employee endpoints
+const (
+	SalaryReasonHire   = "hire"
+	SalaryReasonUpdate = "update"
+)
+
+// Modes of a bulk import. An atomic import creates every row or none of them, a best effort
+// import creates the valid rows and reports the others.
+const (
+	ImportModeAtomic     = "atomic"
+	ImportModeBestEffort = "best_effort"
+)
+
+// Formats of an employee export
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatXLSX   = "xlsx"
+)
+
+// ImportRow is an employee read from an import file, Line is where it starts in the file
+type ImportRow struct {
+	Line     int
+	Employee Employee
+}
+
+// ImportResult is the outcome of one row of an import, either the ID of the created employee
+// or the reason the row was not imported
+type ImportResult struct {
+	Line       int    `json:"line"`
+	EmployeeID string `json:"employee_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ImportReport is the per-row report of a bulk import. Committed is false when an atomic
+// import was rolled back, in which case no row was created.
+type ImportReport struct {
+	Mode      string         `json:"mode"`
+	Committed bool           `json:"committed"`
+	Total     int            `json:"total"`
+	Created   int            `json:"created"`
+	Failed    int            `json:"failed"`
+	Rows      []ImportResult `json:"rows"`
+}
+
+// EmployeeFilter selects employees with the criteria of the employee listing, a batch
+// operation given a filter applies to every active employee matching all of its criteria
+type EmployeeFilter struct {
+	Position     string   `json:"position,omitempty"`
+	NameContains string   `json:"q,omitempty"`
+	SalaryMin    *Decimal `json:"salary_min,omitempty"`
+	SalaryMax    *Decimal `json:"salary_max,omitempty"`
+	CreatedAfter string   `json:"created_after,omitempty"`
+	DepartmentID *string  `json:"department_id,omitempty"`
+}
+
+// EmployeeSelection names the employees of a batch operation, either by ID or with a filter
+type EmployeeSelection struct {
+	IDs    []string        `json:"ids,omitempty"`
+	Filter *EmployeeFilter `json:"filter,omitempty"`
+}
+
+// BatchSelection is an EmployeeSelection as read by the database layer, with Query set when
+// the employees are selected by a filter rather than by ID
+type BatchSelection struct {
+	IDs   []string
+	Query *EmployeeQuery
+}
+
+// BatchUpdateRequest applies the fields set in Update to every selected employee
+type BatchUpdateRequest struct {
+	EmployeeSelection
+	Update Employee `json:"update"`
+	DryRun bool     `json:"dry_run"`
+}
+
+// BatchDeleteRequest soft deletes every selected employee
+type BatchDeleteRequest struct {
+	EmployeeSelection
+	DryRun bool `json:"dry_run"`
+}
+
+// BatchResult lists the employees changed by a batch operation, as they are after the change.
+// Nothing is committed when DryRun is set.
+type BatchResult struct {
+	DryRun    bool       `json:"dry_run"`
+	Affected  int        `json:"affected"`
+	Employees []Employee `json:"employees"`
+}
+
+// IdempotentResponse is what is kept of a request made with an Idempotency-Key. StatusCode is 0
+// while the request holding the key is still in progress.
+type IdempotentResponse struct {
+	Key         string
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// APIKey is a key a service calls the API with, granted the permissions listed in Scopes. Key is
+// only set in the response creating or rotating it, as only the hash of the key is stored.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Owner      string     `json:"owner"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Key        string     `json:"key,omitempty"`
+	KeyHash    string     `json:"-"`
+	// TenantID is the tenant the key belongs to, and the tenant of the requests made with it
+	TenantID string `json:"-"`
+}
+
+// Outcomes of the readiness checks
+const (
+	Ready    = "ready"
+	NotReady = "not ready"
+	CheckOK  = "ok"
+)
+
+// Readiness is the outcome of the readiness checks, each check naming why it failed
+type Readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// PoolStats are the statistics of the connection pool of the database
+type PoolStats struct {
+	MaxOpenConnections  int     `json:"max_open_connections"`
+	OpenConnections     int     `json:"open_connections"`
+	InUse               int     `json:"in_use"`
+	Idle                int     `json:"idle"`
+	WaitCount           int64   `json:"wait_count"`
+	WaitDurationSeconds float64 `json:"wait_duration_seconds"`
+}
+
+// Status describes the running instance. Pool is only set for a PostgreSQL database.
+type Status struct {
+	Version       string     `json:"version"`
+	Commit        string     `json:"commit"`
+	StartedAt     time.Time  `json:"started_at"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
+	Database      string     `json:"database"`
+	Pool          *PoolStats `json:"pool,omitempty"`
+}
@@ -0,0 +1,296 @@
+package models
+
+import (
+	"assignment/internal/constants"
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decimalPlaces is the number of decimal places kept by a Decimal, enough for every ISO 4217
+// currency, and decimalIntegerDigits the number of digits before the decimal point that fit
+// the NUMERIC(17, 4) salary columns
+const (
+	decimalPlaces        = 4
+	decimalIntegerDigits = 13
+)
+
+var decimalUnit = [decimalPlaces + 1]int64{1, 10, 100, 1000, 10000}
+
+// Decimal is an exact decimal number with up to four decimal places. Unlike a float64 it
+// represents amounts such as 0.10 exactly, so sums and comparisons carry no rounding artifacts.
+// The zero value is 0.
+type Decimal struct {
+	// units is the number times 10^decimalPlaces
+	units int64
+}
+
+// ParseDecimal parses a plain decimal number such as -1234.5, exponents are not accepted
+func ParseDecimal(value string) (Decimal, error) {
+	digits := strings.TrimPrefix(value, "-")
+	negative := len(digits) < len(value)
+	integer, fraction, hasPoint := strings.Cut(digits, ".")
+	if integer == "" || (hasPoint && fraction == "") || !isDigits(integer) || !isDigits(fraction) {
+		return Decimal{}, fmt.Errorf("%q is not a decimal number", value)
+	}
+
+	integer = strings.TrimLeft(integer, "0")
+	if len(integer) > decimalIntegerDigits {
+		return Decimal{}, fmt.Errorf("%q is too large, at most %d digits are allowed before the decimal point", value, decimalIntegerDigits)
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > decimalPlaces {
+		return Decimal{}, fmt.Errorf("%q has more than %d decimal places", value, decimalPlaces)
+	}
+
+	units, _ := strconv.ParseInt(integer+fraction+strings.Repeat("0", decimalPlaces-len(fraction)), 10, 64)
+	if negative {
+		units = -units
+	}
+	return Decimal{units: units}, nil
+}
+
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the number without trailing zeros, e.g. 1234.5
+func (d Decimal) String() string {
+	return strings.TrimSuffix(strings.TrimRight(d.StringFixed(decimalPlaces), "0"), ".")
+}
+
+// StringFixed formats the number with places decimal places, at most four. Places must not be
+// smaller than Places, digits past places are dropped.
+func (d Decimal) StringFixed(places int) string {
+	places = min(places, decimalPlaces)
+	units := d.units
+	sign := ""
+	if units < 0 {
+		sign, units = "-", -units
+	}
+	integer, fraction := units/decimalUnit[decimalPlaces], units%decimalUnit[decimalPlaces]
+	if places <= 0 {
+		return fmt.Sprintf("%s%d", sign, integer)
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, integer, places, fraction/decimalUnit[decimalPlaces-places])
+}
+
+// Places returns the number of decimal places needed to write the number exactly
+func (d Decimal) Places() int {
+	places := decimalPlaces
+	for units := d.units; places > 0 && units%10 == 0; units /= 10 {
+		places--
+	}
+	return places
+}
+
+// Cmp compares two numbers, returning -1, 0 or 1
+func (d Decimal) Cmp(other Decimal) int {
+	switch {
+	case d.units < other.units:
+		return -1
+	case d.units > other.units:
+		return 1
+	}
+	return 0
+}
+
+// IsNegative reports whether the number is below zero
+func (d Decimal) IsNegative() bool {
+	return d.units < 0
+}
+
+// MarshalJSON writes the number as a JSON number, exactly as String formats it
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding a decimal number
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return fmt.Errorf("%s is not a decimal number", data)
+		}
+		text = unquoted
+	}
+	parsed, err := ParseDecimal(text)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value passes the number to the database as its exact decimal text
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads a NUMERIC column, which the pgx driver returns as text
+func (d *Decimal) Scan(src interface{}) error {
+	var text string
+	switch value := src.(type) {
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	case int64:
+		text = strconv.FormatInt(value, 10)
+	case float64:
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Errorf("cannot scan %T into a Decimal", src)
+	}
+	parsed, err := ParseDecimal(text)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// currencyCodes are the active ISO 4217 currency codes
+var currencyCodes = strings.Fields(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BRL BSD BTN BWP
+	BYN BZD CAD CDF CHF CLF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP
+	GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS
+	KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR
+	MWK MXN MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF
+	SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD
+	TZS UAH UGX USD UYU UYW UZS VED VES VND VUV WST XAF XCD XOF XPF YER ZAR ZMW ZWL`)
+
+// currencyExponents lists the currencies that do not use two decimal places
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyExponent returns the number of decimal places used by an ISO 4217 currency,
+// ok is false for an unknown code
+func CurrencyExponent(code string) (exponent int, ok bool) {
+	for _, known := range currencyCodes {
+		if code == known {
+			if exponent, ok := currencyExponents[code]; ok {
+				return exponent, true
+			}
+			return 2, true
+		}
+	}
+	return 0, false
+}
+
+// Money is an exact amount of an ISO 4217 currency.
+// In JSON it is written as {"amount": 1234.50, "currency": "USD"}, a bare number is read as an
+// amount of constants.DefaultCurrency.
+type Money struct {
+	Amount   Decimal
+	Currency string
+}
+
+// Validate rejects unknown currencies, negative amounts and amounts more precise than the
+// currency allows, e.g. cents of JPY
+func (m Money) Validate() error {
+	exponent, ok := CurrencyExponent(m.Currency)
+	if !ok {
+		return fmt.Errorf("%q is not an ISO 4217 currency code", m.Currency)
+	}
+	if m.Amount.IsNegative() {
+		return errors.New("amount must not be negative")
+	}
+	if m.Amount.Places() > exponent {
+		return fmt.Errorf("%s amounts have at most %d decimal places", m.Currency, exponent)
+	}
+	return nil
+}
+
+// Equal reports whether both the amount and the currency are the same
+func (m Money) Equal(other Money) bool {
+	return m.Currency == other.Currency && m.Amount.Cmp(other.Amount) == 0
+}
+
+// String formats the amount with the decimal places of its currency, e.g. 1234.50 USD
+func (m Money) String() string {
+	return m.AmountString() + " " + m.Currency
+}
+
+// AmountString formats the amount with the decimal places of its currency
+func (m Money) AmountString() string {
+	exponent, ok := CurrencyExponent(m.Currency)
+	if !ok || exponent < m.Amount.Places() {
+		return m.Amount.String()
+	}
+	return m.Amount.StringFixed(exponent)
+}
+
+type moneyJSON struct {
+	Amount   *json.RawMessage `json:"amount"`
+	Currency string           `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount := json.RawMessage(m.AmountString())
+	return json.Marshal(moneyJSON{Amount: &amount, Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+
+	money := Money{Currency: constants.DefaultCurrency}
+	if len(data) > 0 && data[0] != '{' {
+		if err := money.Amount.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		*m = money
+		return nil
+	}
+
+	var fields moneyJSON
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields.Amount == nil {
+		return errors.New("amount is missing")
+	}
+	if err := money.Amount.UnmarshalJSON(*fields.Amount); err != nil {
+		return err
+	}
+	if fields.Currency != "" {
+		money.Currency = fields.Currency
+	}
+	*m = money
+	return nil
+}
+
+// MarshalJSON writes the amount of the change with the decimals of its currency, as Money does
+func (c SalaryChange) MarshalJSON() ([]byte, error) {
+	// salaryChange has the fields of SalaryChange without its MarshalJSON
+	type salaryChange SalaryChange
+	var amount *json.RawMessage
+	if c.Amount != nil {
+		formatted := json.RawMessage(Money{Amount: *c.Amount, Currency: c.Currency}.AmountString())
+		amount = &formatted
+	}
+	return json.Marshal(struct {
+		salaryChange
+		Amount *json.RawMessage `json:"amount"`
+	}{salaryChange: salaryChange(c), Amount: amount})
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	for value, expected := range map[string]string{
+		"0":         "0",
+		"1234.50":   "1234.5",
+		"-0.1":      "-0.1",
+		"007.0100":  "7.01",
+		"0.0001":    "0.0001",
+		"123456789": "123456789",
+	} {
+		d, err := ParseDecimal(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, d.String(), value)
+	}
+
+	for _, value := range []string{"", "-", ".5", "5.", "1e3", "1.00001", "12345678901234", "abc", "1,5"} {
+		_, err := ParseDecimal(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestDecimal_Exact(t *testing.T) {
+	// 0.1 + 0.2 is not 0.3 as a float64, the Decimal keeps it exact
+	a, _ := ParseDecimal("0.1")
+	b, _ := ParseDecimal("0.2")
+	sum := Decimal{units: a.units + b.units}
+	expected, _ := ParseDecimal("0.3")
+	assert.Zero(t, sum.Cmp(expected))
+
+	d, _ := ParseDecimal("1234.5")
+	assert.Equal(t, "1234.50", d.StringFixed(2))
+	assert.Equal(t, 1, d.Places())
+
+	var scanned Decimal
+	assert.NoError(t, scanned.Scan("1234.5000"))
+	assert.Zero(t, scanned.Cmp(d))
+	value, _ := scanned.Value()
+	assert.Equal(t, "1234.5", value)
+}
+
+func TestMoney_Validate(t *testing.T) {
+	amount := func(value string) Decimal {
+		d, _ := ParseDecimal(value)
+		return d
+	}
+
+	assert.NoError(t, Money{Amount: amount("1234.56"), Currency: "USD"}.Validate())
+	assert.NoError(t, Money{Amount: amount("1500"), Currency: "JPY"}.Validate())
+	assert.NoError(t, Money{Amount: amount("12.345"), Currency: "KWD"}.Validate())
+
+	assert.Error(t, Money{Amount: amount("1234.567"), Currency: "USD"}.Validate())
+	assert.Error(t, Money{Amount: amount("1500.5"), Currency: "JPY"}.Validate())
+	assert.Error(t, Money{Amount: amount("-1"), Currency: "USD"}.Validate())
+	assert.Error(t, Money{Amount: amount("1"), Currency: "usd"}.Validate())
+	assert.Error(t, Money{Amount: amount("1"), Currency: "XYZ"}.Validate())
+}
+
+func TestMoney_JSON(t *testing.T) {
+	var money Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": 1234.5, "currency": "EUR"}`), &money))
+	assert.Equal(t, "1234.50 EUR", money.String())
+
+	encoded, err := json.Marshal(money)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 1234.50, "currency": "EUR"}`, string(encoded))
+
+	// Amounts may be sent as strings, and a bare amount is in the default currency
+	assert.NoError(t, json.Unmarshal([]byte(`"0.10"`), &money))
+	assert.Equal(t, "0.10 USD", money.String())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"currency": "EUR"}`), &money))
+	assert.Error(t, json.Unmarshal([]byte(`1e6`), &money))
+}
+
+func TestDecimal_UnmarshalJSON(t *testing.T) {
+	var decimal Decimal
+	assert.NoError(t, decimal.UnmarshalJSON([]byte(`"12.5"`)))
+	assert.Equal(t, "12.5", decimal.String())
+	assert.NoError(t, decimal.UnmarshalJSON([]byte(`7`)))
+	assert.Equal(t, "7", decimal.String())
+
+	// Exactly one pair of quotes may surround the number
+	for _, data := range []string{`"123`, `123"`, `""5""`, `"5""`, `""`, `"`} {
+		assert.Error(t, decimal.UnmarshalJSON([]byte(data)), data)
+		var money Money
+		assert.Error(t, money.UnmarshalJSON([]byte(data)), data)
+	}
+}
+
+func TestSalaryChange_JSON(t *testing.T) {
+	for amount, expected := range map[string]string{"1100": "1100.00", "1000.5": "1000.50", "0.125": "0.125"} {
+		decimal, err := ParseDecimal(amount)
+		assert.NoError(t, err)
+		encoded, err := json.Marshal(SalaryChange{ID: 1, EmployeeID: "7", Amount: &decimal, Currency: "USD"})
+		assert.NoError(t, err)
+		var fields map[string]json.RawMessage
+		assert.NoError(t, json.Unmarshal(encoded, &fields))
+		assert.Equal(t, expected, string(fields["amount"]))
+		assert.Equal(t, `"USD"`, string(fields["currency"]))
+	}
+
+	encoded, err := json.Marshal(SalaryChange{Currency: "JPY"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(encoded), `"amount":null`)
+}
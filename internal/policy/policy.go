@@ -0,0 +1,116 @@
+// Package policy decides what the caller of a request may do, from the roles of its token and the
+// permissions the configuration grants each role.
+package policy
+
+import (
+	"assignment/internal/auth"
+	"assignment/internal/config"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// permissions a role may be granted
+const (
+	// read the employees, without their salary, the departments and the organisation
+	ReadEmployees = "employees:read"
+	// create, change and delete employees, including their salary
+	WriteEmployees = "employees:write"
+	// read the salary of every employee
+	ReadSalaries = "salaries:read"
+	// read the salary of the employees reporting to the caller, directly or not
+	ReadReportSalaries = "salaries:read:reports"
+	// create, change and delete departments
+	WriteDepartments = "departments:write"
+	// read the audit trail, which holds every past salary
+	ReadAudit = "audit:read"
+	// permanently remove deleted employees
+	PurgeEmployees = "employees:purge"
+	// create, list, rotate and revoke API keys
+	ManageAPIKeys = "api_keys:manage"
+	// pick the tenant of a request with the X-Tenant-ID header, when the credentials name none
+	CrossTenant = "tenants:cross"
+)
+
+// Permissions are every permission a role may be granted
+var Permissions = []string{ReadEmployees, WriteEmployees, ReadSalaries, ReadReportSalaries, WriteDepartments, ReadAudit, PurgeEmployees, ManageAPIKeys, CrossTenant}
+
+// Policy maps the roles found in tokens to the permissions they grant
+type Policy struct {
+	roles           map[string][]string
+	rolesClaim      string
+	employeeIDClaim string
+}
+
+// New returns the policy of the rbac configuration, failing on a permission it does not know
+func New(cfg config.RBAC) (*Policy, error) {
+	if cfg.RolesClaim == "" {
+		return nil, fmt.Errorf("rbac.roles_claim is empty")
+	}
+	roles := make([]string, 0, len(cfg.Roles))
+	for role := range cfg.Roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		for _, permission := range cfg.Roles[role] {
+			if !slices.Contains(Permissions, permission) {
+				return nil, fmt.Errorf("rbac.roles.%s grants unknown permission %q, expected one of %s", role, permission, strings.Join(Permissions, ", "))
+			}
+		}
+	}
+	return &Policy{roles: cfg.Roles, rolesClaim: cfg.RolesClaim, employeeIDClaim: cfg.EmployeeIDClaim}, nil
+}
+
+// Principal returns who a token was issued to and the permissions granted by its roles. Roles
+// missing from the configuration grant nothing.
+func (policy *Policy) Principal(claims auth.Claims) Principal {
+	principal := Principal{
+		Subject:     claims.Subject(),
+		Roles:       claims.Strings(policy.rolesClaim),
+		permissions: map[string]bool{},
+	}
+	if policy.employeeIDClaim != "" {
+		principal.EmployeeID = claims.String(policy.employeeIDClaim)
+	}
+	for _, role := range principal.Roles {
+		for _, permission := range policy.roles[role] {
+			principal.permissions[permission] = true
+		}
+	}
+	return principal
+}
+
+// Principal is the caller of a request and what it may do
+type Principal struct {
+	Subject string
+	// EmployeeID is the employee the caller is, whose reports a manager sees the salary of
+	EmployeeID string
+	// Tenant is the tenant the credentials of the caller belong to, empty when they name none
+	Tenant string
+	Roles  []string
+	// permissions are nil for an unrestricted caller
+	permissions map[string]bool
+}
+
+// Unrestricted returns the principal of requests made while authentication is disabled, which
+// may do anything
+func Unrestricted() Principal {
+	return Principal{}
+}
+
+// NewPrincipal returns a principal granted exactly the permissions, such as the scopes of an API
+// key
+func NewPrincipal(subject string, permissions []string) Principal {
+	principal := Principal{Subject: subject, permissions: make(map[string]bool, len(permissions))}
+	for _, permission := range permissions {
+		principal.permissions[permission] = true
+	}
+	return principal
+}
+
+// Can reports whether the principal was granted the permission
+func (principal Principal) Can(permission string) bool {
+	return principal.permissions == nil || principal.permissions[permission]
+}
@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"assignment/internal/auth"
+	"assignment/internal/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() config.RBAC {
+	return config.RBAC{
+		RolesClaim:      "roles",
+		EmployeeIDClaim: "employee_id",
+		Roles: map[string][]string{
+			"viewer":  {ReadEmployees},
+			"manager": {ReadEmployees, ReadReportSalaries},
+			"hr":      {ReadEmployees, WriteEmployees, ReadSalaries},
+		},
+	}
+}
+
+func TestNew_UnknownPermission(t *testing.T) {
+	cfg := testConfig()
+	cfg.Roles["viewer"] = []string{ReadEmployees, "employees:fly"}
+
+	_, err := New(cfg)
+	assert.ErrorContains(t, err, `rbac.roles.viewer grants unknown permission "employees:fly"`)
+}
+
+func TestPolicy_Principal(t *testing.T) {
+	policy, err := New(testConfig())
+	assert.NoError(t, err)
+
+	// Roles may be a single string or an array, and their permissions add up
+	principal := policy.Principal(auth.Claims{"sub": "alice", "roles": []interface{}{"viewer", "manager"}, "employee_id": "7"})
+	assert.Equal(t, "alice", principal.Subject)
+	assert.Equal(t, "7", principal.EmployeeID)
+	assert.True(t, principal.Can(ReadEmployees))
+	assert.True(t, principal.Can(ReadReportSalaries))
+	assert.False(t, principal.Can(ReadSalaries))
+	assert.False(t, principal.Can(WriteEmployees))
+
+	principal = policy.Principal(auth.Claims{"sub": "bob", "roles": "hr"})
+	assert.True(t, principal.Can(WriteEmployees))
+	assert.False(t, principal.Can(PurgeEmployees))
+
+	// A role missing from the configuration, or no role at all, grants nothing
+	for _, claims := range []auth.Claims{{"sub": "eve", "roles": "root"}, {"sub": "eve"}} {
+		principal = policy.Principal(claims)
+		for _, permission := range Permissions {
+			assert.False(t, principal.Can(permission), permission)
+		}
+	}
+
+	assert.True(t, Unrestricted().Can(PurgeEmployees))
+}
+
+func TestNewPrincipal(t *testing.T) {
+	principal := NewPrincipal("api-key:1", []string{ReadEmployees, ReadSalaries})
+	assert.Equal(t, "api-key:1", principal.Subject)
+	assert.True(t, principal.Can(ReadSalaries))
+	assert.False(t, principal.Can(WriteEmployees))
+
+	// A principal without permissions is not unrestricted
+	assert.False(t, NewPrincipal("api-key:2", nil).Can(ReadEmployees))
+}
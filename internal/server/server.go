@@ -3,10 +3,13 @@ package server
 import (
 	"assignment/internal/config"
 	"assignment/internal/constants"
+	"assignment/internal/metrics"
 	"assignment/internal/middleware"
 	"assignment/internal/service"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
 	"context"
-	"log"
+	"errors"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,11 +18,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // Registering the CreateEmployee EndPoints
 func registerCreateEmployeeEndPoints(handler gin.IRoutes) {
-	handler.POST(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee}, constants.ForwardSlash), service.CreateEmployee())
+	handler.POST(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee}, constants.ForwardSlash), service.Idempotent(service.CreateEmployee()))
 }
 
 // Registering GetEmployeeByID EndPoints
@@ -32,9 +36,14 @@ func registerDeleteEmployeeEndPoints(handler gin.IRoutes) {
 	handler.DELETE(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id"}, constants.ForwardSlash), service.DeleteEmployee())
 }
 
-// Registering the UpdateEmployee EndPoints
-func registerUpdateEmployeeEndPoints(handler gin.IRoutes) {
-	handler.PUT(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee}, constants.ForwardSlash), service.UpdateEmployee())
+// Registering the ReplaceEmployee EndPoints
+func registerReplaceEmployeeEndPoints(handler gin.IRoutes) {
+	handler.PUT(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id"}, constants.ForwardSlash), service.ReplaceEmployee())
+}
+
+// Registering the PatchEmployee EndPoints
+func registerPatchEmployeeEndPoints(handler gin.IRoutes) {
+	handler.PATCH(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id"}, constants.ForwardSlash), service.PatchEmployee())
 }
 
 // Registering the ListEmployee EndPoints
@@ -42,23 +51,204 @@ func registerListEmployeeEndPoints(handler gin.IRoutes) {
 	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee}, constants.ForwardSlash), service.ListEmployees())
 }
 
+// Registering the GetEmployeeReports and GetEmployeeChain EndPoints
+func registerEmployeeHierarchyEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id", constants.Reports}, constants.ForwardSlash), service.GetEmployeeReports())
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id", constants.Chain}, constants.ForwardSlash), service.GetEmployeeChain())
+}
+
+// Registering the RestoreEmployee EndPoints
+func registerRestoreEmployeeEndPoints(handler gin.IRoutes) {
+	handler.POST(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id", constants.Restore}, constants.ForwardSlash), service.RestoreEmployee())
+}
+
+// Registering the GetEmployeeHistory EndPoints
+func registerEmployeeHistoryEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id", constants.History}, constants.ForwardSlash), service.GetEmployeeHistory())
+}
+
+// Registering the GetCompensation EndPoints
+func registerGetCompensationEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id", constants.Compensation}, constants.ForwardSlash), service.GetCompensation())
+}
+
+// Registering the ScheduleSalaryChange EndPoints
+func registerScheduleSalaryChangeEndPoints(handler gin.IRoutes) {
+	handler.POST(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee, constants.ForwardSlash, ":id", constants.Compensation}, constants.ForwardSlash), service.ScheduleSalaryChange())
+}
+
+// Registering the ListAudit EndPoints
+func registerListAuditEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Audit}, constants.ForwardSlash), service.ListAudit())
+}
+
+// Registering the custom method EndPoints such as POST /v1/employees:purge.
+// gin cannot route a literal colon, so the custom method arrives in the :action parameter,
+// colon included, and is dispatched to the handlers registered for it and the HTTP method.
+func registerEmployeeActionEndPoints(handler gin.IRoutes, method string, actions map[string]gin.HandlersChain) {
+	handler.Handle(method, constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Employee}, constants.ForwardSlash)+":action", dispatchAction(actions))
+}
+
+// dispatchAction runs the handlers registered for the :action parameter, stopping when one aborts
+func dispatchAction(actions map[string]gin.HandlersChain) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		handlers, ok := actions[ctx.Param("action")]
+		if !ok {
+			_ = middleware.GetTransactionID(ctx)
+			utils.RespondWithError(ctx, http.StatusNotFound, "unknown method "+ctx.Param("action"))
+			return
+		}
+		for _, handler := range handlers {
+			if handler(ctx); ctx.IsAborted() {
+				return
+			}
+		}
+	}
+}
+
+// Registering the GetOrgChart EndPoints
+func registerOrgChartEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.OrgChart}, constants.ForwardSlash), service.GetOrgChart())
+}
+
+// Registering the CreateDepartment EndPoints
+func registerCreateDepartmentEndPoints(handler gin.IRoutes) {
+	handler.POST(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Department}, constants.ForwardSlash), service.CreateDepartment())
+}
+
+// Registering the GetDepartmentByID, DeleteDepartment and ListDepartmentEmployees EndPoints
+func registerDepartmentByIDEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Department, constants.ForwardSlash, ":id"}, constants.ForwardSlash), service.GetDepartmentByID())
+	handler.DELETE(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Department, constants.ForwardSlash, ":id"}, constants.ForwardSlash), service.DeleteDepartment())
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Department, constants.ForwardSlash, ":id", constants.Employee}, constants.ForwardSlash), service.ListDepartmentEmployees())
+}
+
+// Registering the UpdateDepartment EndPoints
+func registerUpdateDepartmentEndPoints(handler gin.IRoutes) {
+	handler.PUT(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Department, constants.ForwardSlash, ":id"}, constants.ForwardSlash), service.UpdateDepartment())
+}
+
+// Registering the ListDepartments EndPoints
+func registerListDepartmentEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Department}, constants.ForwardSlash), service.ListDepartments())
+}
+
+// Registering the CreateAPIKey EndPoints
+func registerCreateAPIKeyEndPoints(handler gin.IRoutes) {
+	handler.POST(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.APIKeys}, constants.ForwardSlash), service.CreateAPIKey())
+}
+
+// Registering the ListAPIKeys EndPoints
+func registerListAPIKeyEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.APIKeys}, constants.ForwardSlash), service.ListAPIKeys())
+}
+
+// Registering the RotateAPIKey and RevokeAPIKey EndPoints
+func registerAPIKeyByIDEndPoints(handler gin.IRoutes) {
+	handler.POST(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.APIKeys, constants.ForwardSlash, ":id", constants.Rotate}, constants.ForwardSlash), service.RotateAPIKey())
+	handler.DELETE(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.APIKeys, constants.ForwardSlash, ":id"}, constants.ForwardSlash), service.RevokeAPIKey())
+}
+
+// Registering the liveness and readiness EndPoints, probed without authentication
+func registerHealthEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+constants.Healthz, service.Healthz())
+	handler.GET(constants.ForwardSlash+constants.Readyz, service.Readyz())
+}
+
+// Registering the GetStatus EndPoints
+func registerStatusEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+strings.Join([]string{constants.ForwardSlash, constants.Status}, constants.ForwardSlash), service.GetStatus())
+}
+
+// Registering the Prometheus metrics EndPoint, scraped without authentication
+func registerMetricsEndPoints(handler gin.IRoutes) {
+	handler.GET(constants.ForwardSlash+constants.Metrics, gin.WrapH(metrics.Handler()))
+}
+
 func Start() {
 	plainHandler := gin.New()
+	cfg := config.GetConfig()
+
+	if tracing.Enabled() {
+		// The span of a request is kept in the context of its http.Request, which the handlers
+		// and the database calls given the gin.Context must reach
+		plainHandler.ContextWithFallback = true
+		plainHandler.Use(middleware.Trace())
+	}
+	// Every request logs with its transaction-id, and is written to the access log
+	plainHandler.Use(middleware.LogRequests())
+	if cfg.Metrics.Enabled {
+		plainHandler.Use(middleware.RecordMetrics())
+		registerMetricsEndPoints(plainHandler)
+	}
+
+	healthServiceHandler := plainHandler.Group(constants.ForwardSlash).Use(gin.Recovery())
+	registerHealthEndPoints(healthServiceHandler)
 
-	createEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.ValidateCreateEmployeeRequest())
+	statusServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate())
+	registerStatusEndPoints(statusServiceHandler)
+
+	createEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateCreateEmployeeRequest())
 	registerCreateEmployeeEndPoints(createEmployeeServiceHandler)
 
-	GetAndDeleteEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.ValidateEmployeeID())
+	GetAndDeleteEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateEmployeeID())
 	registerGetEmployeeByIDEndPoints(GetAndDeleteEmployeeServiceHandler)
 	registerDeleteEmployeeEndPoints(GetAndDeleteEmployeeServiceHandler)
+	registerEmployeeHierarchyEndPoints(GetAndDeleteEmployeeServiceHandler)
+	registerRestoreEmployeeEndPoints(GetAndDeleteEmployeeServiceHandler)
+	registerEmployeeHistoryEndPoints(GetAndDeleteEmployeeServiceHandler)
+	registerGetCompensationEndPoints(GetAndDeleteEmployeeServiceHandler)
+
+	replaceEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateReplaceEmployeeRequest())
+	registerReplaceEmployeeEndPoints(replaceEmployeeServiceHandler)
 
-	updateEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.ValidateUpdateEmployeeRequest())
-	registerUpdateEmployeeEndPoints(updateEmployeeServiceHandler)
+	patchEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidatePatchEmployeeRequest())
+	registerPatchEmployeeEndPoints(patchEmployeeServiceHandler)
 
-	listEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery())
+	listEmployeeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateListEmployeesRequest())
 	registerListEmployeeEndPoints(listEmployeeServiceHandler)
 
-	cfg := config.GetConfig()
+	salaryChangeServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateSalaryChangeRequest())
+	registerScheduleSalaryChangeEndPoints(salaryChangeServiceHandler)
+
+	listAuditServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateListAuditRequest())
+	registerListAuditEndPoints(listAuditServiceHandler)
+
+	orgChartServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate())
+	registerOrgChartEndPoints(orgChartServiceHandler)
+
+	employeeActionServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate())
+	registerEmployeeActionEndPoints(employeeActionServiceHandler, http.MethodPost, map[string]gin.HandlersChain{
+		constants.PurgeAction:       {service.PurgeEmployees()},
+		constants.ImportAction:      {middleware.ValidateImportEmployeesRequest(), service.Idempotent(service.ImportEmployees())},
+		constants.BatchUpdateAction: {middleware.ValidateBatchUpdateRequest(), service.Idempotent(service.BatchUpdateEmployees())},
+		constants.BatchDeleteAction: {middleware.ValidateBatchDeleteRequest(), service.Idempotent(service.BatchDeleteEmployees())},
+	})
+	registerEmployeeActionEndPoints(employeeActionServiceHandler, http.MethodGet, map[string]gin.HandlersChain{
+		constants.ExportAction: {middleware.ValidateExportEmployeesRequest(), service.ExportEmployees()},
+	})
+
+	createDepartmentServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateCreateDepartmentRequest())
+	registerCreateDepartmentEndPoints(createDepartmentServiceHandler)
+
+	departmentByIDServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateDepartmentID())
+	registerDepartmentByIDEndPoints(departmentByIDServiceHandler)
+
+	updateDepartmentServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateUpdateDepartmentRequest())
+	registerUpdateDepartmentEndPoints(updateDepartmentServiceHandler)
+
+	listDepartmentServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate())
+	registerListDepartmentEndPoints(listDepartmentServiceHandler)
+
+	createAPIKeyServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateCreateAPIKeyRequest())
+	registerCreateAPIKeyEndPoints(createAPIKeyServiceHandler)
+
+	listAPIKeyServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate())
+	registerListAPIKeyEndPoints(listAPIKeyServiceHandler)
+
+	apiKeyByIDServiceHandler := plainHandler.Group(constants.ForwardSlash + constants.Version).Use(gin.Recovery()).Use(middleware.Authenticate()).Use(middleware.ValidateAPIKeyID())
+	registerAPIKeyByIDEndPoints(apiKeyByIDServiceHandler)
+
 	srv := &http.Server{
 		Handler:      plainHandler,
 		Addr:         cfg.Server.Address,
@@ -68,9 +258,10 @@ func Start() {
 
 	// Start Server
 	go func() {
-		log.Println("Starting Server")
-		if err := srv.ListenAndServe(); err != nil {
-			log.Fatal(err)
+		utils.Logger.Info("Starting Server")
+		// ErrServerClosed is returned once waitForShutdown stops the server, which then finishes
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.Logger.Fatal("Unable to serve requests", zap.Error(err))
 		}
 	}()
 
@@ -97,6 +288,14 @@ func waitForShutdown(srv *http.Server) {
 	// Block until we receive our signal.
 	<-interruptChan
 
+	// Failing the readiness check while the server still accepts connections, so that the load
+	// balancer stops routing requests here before the listener is closed
+	service.BeginShutdown()
+	if delay := config.GetConfig().Health.ShutdownDelaySeconds; delay > 0 {
+		utils.Logger.Info("Not ready anymore, shutting down", zap.Int("delay_seconds", delay))
+		time.Sleep(time.Duration(delay) * time.Second)
+	}
+
 	// Create a deadline to wait for.
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 
@@ -115,6 +314,12 @@ func waitForShutdown(srv *http.Server) {
 
 	srv.Shutdown(ctx)
 
-	log.Println("Shutting down")
+	// Exporting the spans of the last requests
+	if err := tracing.Shutdown(ctx); err != nil {
+		utils.Logger.Error("Unable to export the remaining spans", zap.Error(err))
+	}
+
+	utils.Logger.Info("Shutting down")
+	_ = utils.Logger.Sync()
 	os.Exit(0)
 }
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"assignment/internal/constants"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatchAction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Each handler adds its name to the X-Handlers header, the reject handler aborts the chain
+	handler := func(name string) gin.HandlerFunc {
+		return func(ctx *gin.Context) {
+			ctx.Writer.Header().Add("X-Handlers", name)
+		}
+	}
+	reject := func(ctx *gin.Context) {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+	}
+	respond := func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	}
+
+	router := gin.New()
+	group := router.Group(constants.ForwardSlash + constants.Version)
+	registerEmployeeActionEndPoints(group, http.MethodPost, map[string]gin.HandlersChain{
+		constants.PurgeAction:       {handler("purge"), respond},
+		constants.BatchUpdateAction: {handler("validate"), handler("batchUpdate"), respond},
+		constants.BatchDeleteAction: {handler("validate"), reject, handler("batchDelete"), respond},
+	})
+	registerEmployeeActionEndPoints(group, http.MethodGet, map[string]gin.HandlersChain{
+		constants.ExportAction: {handler("export"), respond},
+	})
+
+	for _, test := range []struct {
+		name     string
+		method   string
+		action   string
+		status   int
+		handlers []string
+	}{
+		{name: "action", method: http.MethodPost, action: constants.PurgeAction, status: http.StatusOK, handlers: []string{"purge"}},
+		{name: "chain of handlers", method: http.MethodPost, action: constants.BatchUpdateAction, status: http.StatusOK, handlers: []string{"validate", "batchUpdate"}},
+		{name: "chain stopped by an abort", method: http.MethodPost, action: constants.BatchDeleteAction, status: http.StatusBadRequest, handlers: []string{"validate"}},
+		{name: "action of another method", method: http.MethodGet, action: constants.ExportAction, status: http.StatusOK, handlers: []string{"export"}},
+		{name: "action registered for another method", method: http.MethodPost, action: constants.ExportAction, status: http.StatusNotFound},
+		{name: "unknown action", method: http.MethodPost, action: ":rename", status: http.StatusNotFound},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "/v1/employees"+test.action, nil)
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			assert.Equal(t, test.status, response.Code)
+			assert.Equal(t, test.handlers, response.Header().Values("X-Handlers"))
+			if test.status == http.StatusNotFound {
+				assert.Contains(t, response.Body.String(), "unknown method "+test.action)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"assignment/internal/auth"
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+)
+
+// Creates an API key, the key is only ever shown in this response
+func CreateAPIKey() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for API key creation")
+		var key models.APIKey
+		if err := ctx.ShouldBindBodyWith(&key, binding.JSON); err == nil {
+			created, err := employeeClient.createAPIKey(ctx, key)
+			if err != nil {
+				utils.RespondWithError(ctx, err.Code, err.Message)
+				return
+			}
+			ctx.JSON(http.StatusCreated, created)
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{"Unable to marshal the request body": err.Error()})
+		}
+	}
+}
+
+func (service *EmployeeService) createAPIKey(ctx *gin.Context, request models.APIKey) (models.APIKey, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "createAPIKey")()
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	if err := authorize(ctx, policy.ManageAPIKeys); err != nil {
+		return models.APIKey{}, err
+	}
+	if err := authorizeScopes(ctx, request.Scopes); err != nil {
+		return models.APIKey{}, err
+	}
+
+	key := models.APIKey{
+		Name:      strings.TrimSpace(request.Name),
+		Owner:     strings.TrimSpace(request.Owner),
+		Scopes:    request.Scopes,
+		ExpiresAt: request.ExpiresAt,
+	}
+	if key.Owner == "" {
+		key.Owner = middleware.GetActor(ctx)
+	}
+	var err error
+	if key.Key, key.Prefix, key.KeyHash, err = auth.GenerateAPIKey(); err != nil {
+		utils.Log(ctx).Error("Error generating API key", zap.Error(err))
+		return models.APIKey{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to create API key",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("calling db layer for API key creation")
+	return service.repo.CreateAPIKey(ctx, key)
+}
+
+// Lists the API keys, without the keys themselves
+func ListAPIKeys() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		keys, err := employeeClient.listAPIKeys(ctx)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, keys)
+	}
+}
+
+func (service *EmployeeService) listAPIKeys(ctx *gin.Context) ([]models.APIKey, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "listAPIKeys")()
+
+	if err := authorize(ctx, policy.ManageAPIKeys); err != nil {
+		return nil, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to list API keys")
+	return service.repo.ListAPIKeys(ctx)
+}
+
+// Replaces an API key with a new one, shown only in this response, keeping its name and scopes
+func RotateAPIKey() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		key, err := employeeClient.rotateAPIKey(ctx, ctx.Param("id"))
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, key)
+	}
+}
+
+func (service *EmployeeService) rotateAPIKey(ctx *gin.Context, keyId string) (models.APIKey, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "rotateAPIKey")()
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	if err := authorize(ctx, policy.ManageAPIKeys); err != nil {
+		return models.APIKey{}, err
+	}
+
+	// The caller gets the new key, so it must hold the scopes of the key like when creating one
+	utils.Log(ctx).Info("calling db layer for to list API keys")
+	keys, err := service.repo.ListAPIKeys(ctx)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	for _, key := range keys {
+		if key.ID == keyId {
+			if err := authorizeScopes(ctx, key.Scopes); err != nil {
+				return models.APIKey{}, err
+			}
+		}
+	}
+
+	var rotated models.APIKey
+	var genErr error
+	if rotated.Key, rotated.Prefix, rotated.KeyHash, genErr = auth.GenerateAPIKey(); genErr != nil {
+		utils.Log(ctx).Error("Error generating API key", zap.Error(genErr))
+		return models.APIKey{}, &employeeerror.EmployeeError{
+			Code:    http.StatusInternalServerError,
+			Message: "Unable to rotate API key",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("calling db layer for API key rotation")
+	return service.repo.RotateAPIKey(ctx, keyId, rotated)
+}
+
+// Revokes an API key, which stops working at once
+func RevokeAPIKey() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		if err := employeeClient.revokeAPIKey(ctx, ctx.Param("id")); err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+
+		utils.Log(ctx).Info("user has successfully revoked an API key")
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (service *EmployeeService) revokeAPIKey(ctx *gin.Context, keyId string) *employeeerror.EmployeeError {
+	defer tracing.Trace(ctx, "revokeAPIKey")()
+
+	if err := authorize(ctx, policy.ManageAPIKeys); err != nil {
+		return err
+	}
+
+	utils.Log(ctx).Info("calling db layer for API key revocation")
+	return service.repo.RevokeAPIKey(ctx, keyId)
+}
+
+// AuthenticateAPIKey returns the principal of an active API key, granted the scopes of the key
+// within its tenant, for middleware.Authenticate
+func AuthenticateAPIKey(ctx *gin.Context, key string) (policy.Principal, bool, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "authenticateAPIKey")()
+
+	utils.Log(ctx).Info("calling db layer for to check an API key")
+	stored, ok, err := employeeClient.repo.UseAPIKey(ctx, auth.HashAPIKey(key))
+	if err != nil || !ok {
+		return policy.Principal{}, false, err
+	}
+	principal := policy.NewPrincipal(constants.APIKeyActorPrefix+stored.ID, stored.Scopes)
+	principal.Tenant = stored.TenantID
+	return principal, true, nil
+}
+
+// authorizeScopes refuses to hand out a key granted a permission the caller does not hold
+func authorizeScopes(ctx *gin.Context, scopes []string) *employeeerror.EmployeeError {
+	for _, scope := range scopes {
+		if err := authorize(ctx, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
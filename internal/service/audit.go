@@ -0,0 +1,95 @@
+package service
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Lists the changes of an employee, newest first. The history of a purged employee is kept.
+func GetEmployeeHistory() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for employee history")
+
+		query, err := middleware.ParseAuditQuery(ctx)
+		if err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		history, employeeErr := employeeClient.getEmployeeHistory(ctx, ctx.Param("id"), query)
+		if employeeErr != nil {
+			utils.RespondWithError(ctx, employeeErr.Code, employeeErr.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, history)
+	}
+}
+
+func (service *EmployeeService) getEmployeeHistory(ctx *gin.Context, employeeId string, query models.AuditQuery) (models.AuditList, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "getEmployeeHistory")()
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	if err := authorize(ctx, policy.ReadAudit); err != nil {
+		return models.AuditList{}, err
+	}
+
+	if _, err := strconv.Atoi(employeeId); err != nil {
+		return models.AuditList{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid employee ID",
+			Trace:   txid,
+		}
+	}
+	query.EmployeeID = &employeeId
+
+	utils.Log(ctx).Info("calling db layer for employee history")
+	history, err := service.repo.ListAudit(ctx, query)
+	if err != nil {
+		return models.AuditList{}, err
+	}
+
+	// Employees created before the audit trail existed may have no history yet
+	if history.TotalCount == 0 {
+		if _, err := service.repo.GetEmployeeByID(ctx, employeeId); err != nil {
+			return models.AuditList{}, err
+		}
+	}
+	return history, nil
+}
+
+// Lists the changes of all employees, newest first, filtered by actor and time range
+func ListAudit() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for audit trail")
+
+		// The query has already been validated by ValidateListAuditRequest
+		query, _ := middleware.ParseAuditQuery(ctx)
+
+		audit, err := employeeClient.listAudit(ctx, query)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, audit)
+	}
+}
+
+func (service *EmployeeService) listAudit(ctx *gin.Context, query models.AuditQuery) (models.AuditList, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "listAudit")()
+
+	if err := authorize(ctx, policy.ReadAudit); err != nil {
+		return models.AuditList{}, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for audit trail")
+	return service.repo.ListAudit(ctx, query)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+)
+
+// Applies the same update to every employee selected by ID or by a filter, in one transaction.
+// With dry_run the employees are returned as they would be, and nothing is committed.
+func BatchUpdateEmployees() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for batch employee update")
+
+		// The body has already been validated by ValidateBatchUpdateRequest
+		var request models.BatchUpdateRequest
+		_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+		result, err := employeeClient.batchUpdateEmployees(ctx, request)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func (service *EmployeeService) batchUpdateEmployees(ctx *gin.Context, request models.BatchUpdateRequest) (models.BatchResult, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "batchUpdateEmployees")()
+
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return models.BatchResult{}, err
+	}
+
+	selection, _ := middleware.ParseBatchSelection(request.EmployeeSelection)
+	if err := checkBatchSelection(ctx, selection); err != nil {
+		return models.BatchResult{}, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for batch employee update", zap.Bool("dry_run", request.DryRun))
+	employees, err := service.repo.BatchUpdateEmployees(ctx, selection, request.Update, request.DryRun)
+	if err != nil {
+		return models.BatchResult{}, err
+	}
+	if err := service.redactEmployeeList(ctx, employees); err != nil {
+		return models.BatchResult{}, err
+	}
+	return models.BatchResult{DryRun: request.DryRun, Affected: len(employees), Employees: employees}, nil
+}
+
+// Soft deletes every employee selected by ID or by a filter, in one transaction.
+// With dry_run the employees are returned as they would be, and nothing is committed.
+func BatchDeleteEmployees() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for batch employee delete")
+
+		// The body has already been validated by ValidateBatchDeleteRequest
+		var request models.BatchDeleteRequest
+		_ = ctx.ShouldBindBodyWith(&request, binding.JSON)
+
+		result, err := employeeClient.batchDeleteEmployees(ctx, request)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func (service *EmployeeService) batchDeleteEmployees(ctx *gin.Context, request models.BatchDeleteRequest) (models.BatchResult, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "batchDeleteEmployees")()
+
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return models.BatchResult{}, err
+	}
+
+	selection, _ := middleware.ParseBatchSelection(request.EmployeeSelection)
+	if err := checkBatchSelection(ctx, selection); err != nil {
+		return models.BatchResult{}, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for batch employee delete", zap.Bool("dry_run", request.DryRun))
+	employees, err := service.repo.BatchDeleteEmployees(ctx, selection, request.DryRun)
+	if err != nil {
+		return models.BatchResult{}, err
+	}
+	if err := service.redactEmployeeList(ctx, employees); err != nil {
+		return models.BatchResult{}, err
+	}
+	return models.BatchResult{DryRun: request.DryRun, Affected: len(employees), Employees: employees}, nil
+}
+
+// checkBatchSelection holds a selection by filter to the rules of a listing, so that a batch
+// cannot probe the salaries the caller may not see
+func checkBatchSelection(ctx *gin.Context, selection models.BatchSelection) *employeeerror.EmployeeError {
+	if selection.Query == nil {
+		return nil
+	}
+	return checkSalaryQuery(ctx, *selection.Query)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestEmployees creates engineers of the given names and returns their IDs
+func createTestEmployees(t *testing.T, service *EmployeeService, names ...string) []string {
+	salary, err := models.ParseDecimal("1000")
+	assert.NoError(t, err)
+	ids := make([]string, len(names))
+	for i, name := range names {
+		employee := models.Employee{Name: name, Position: "Engineer", Salary: &models.Money{Amount: salary, Currency: "USD"}}
+		id, createErr := service.repo.CreateEmployee(newTestContext(http.MethodPost, "/v1/employees", "", ""), employee)
+		assert.Nil(t, createErr)
+		ids[i] = id
+	}
+	return ids
+}
+
+func TestBatchUpdateEmployees_DryRun(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		service := newTestService(t)
+		ids := createTestEmployees(t, service, "John Doe", "Jane Roe", "Max Mustermann")
+		ctx := newTestContext(http.MethodPost, "/v1/employees:batchUpdate", "", "")
+
+		result, err := service.batchUpdateEmployees(ctx, models.BatchUpdateRequest{
+			EmployeeSelection: models.EmployeeSelection{IDs: []string{ids[0], ids[2]}},
+			Update:            models.Employee{Position: "Manager"},
+			DryRun:            dryRun,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, dryRun, result.DryRun)
+		assert.Equal(t, 2, result.Affected)
+		for _, employee := range result.Employees {
+			assert.Equal(t, "Manager", employee.Position)
+		}
+
+		// A dry run returns the employees as they would be, and leaves them as they are
+		expected := map[bool]string{true: "Engineer", false: "Manager"}[dryRun]
+		for _, id := range []string{ids[0], ids[2]} {
+			employee, err := service.repo.GetEmployeeByID(ctx, id)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, employee.Position, "dry_run=%v", dryRun)
+		}
+		employee, _ := service.repo.GetEmployeeByID(ctx, ids[1])
+		assert.Equal(t, "Engineer", employee.Position)
+	}
+}
+
+func TestBatchDeleteEmployees_DryRun(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		service := newTestService(t)
+		createTestEmployees(t, service, "John Doe", "Jane Roe")
+		ctx := newTestContext(http.MethodPost, "/v1/employees:batchDelete", "", "")
+
+		result, err := service.batchDeleteEmployees(ctx, models.BatchDeleteRequest{
+			EmployeeSelection: models.EmployeeSelection{Filter: &models.EmployeeFilter{Position: "Engineer"}},
+			DryRun:            dryRun,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, models.BatchResult{DryRun: dryRun, Affected: 2, Employees: result.Employees}, result)
+
+		list, err := service.repo.ListEmployee(ctx, models.EmployeeQuery{Page: 1, PageSize: 10})
+		assert.Nil(t, err)
+		assert.Equal(t, map[bool]int{true: 2, false: 0}[dryRun], list.TotalCount, "dry_run=%v", dryRun)
+	}
+}
+
+func TestBatchUpdateEmployees_NotFound(t *testing.T) {
+	service := newTestService(t)
+	ids := createTestEmployees(t, service, "John Doe")
+	ctx := newTestContext(http.MethodPost, "/v1/employees:batchUpdate", "", "")
+
+	_, err := service.batchUpdateEmployees(ctx, models.BatchUpdateRequest{
+		EmployeeSelection: models.EmployeeSelection{IDs: []string{ids[0], "42"}},
+		Update:            models.Employee{Position: "Manager"},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+
+	// The whole batch is rolled back
+	employee, _ := service.repo.GetEmployeeByID(ctx, ids[0])
+	assert.Equal(t, "Engineer", employee.Position)
+}
+
+func TestBatchEmployees_SalaryVisibility(t *testing.T) {
+	service := newTestService(t)
+	ids := createTestEmployees(t, service, "John Doe", "Jane Roe")
+	// writer may change employees but not see their salaries
+	writer := func(action string) *gin.Context {
+		ctx := newTestContext(http.MethodPost, "/v1/employees"+action, "", "")
+		ctx.Set(constants.PrincipalKey, policy.NewPrincipal("bob", []string{policy.ReadEmployees, policy.WriteEmployees}))
+		return ctx
+	}
+
+	// A filter on salaries is refused, so that a batch cannot probe them
+	salaryMin, _ := models.ParseDecimal("900")
+	bySalary := models.EmployeeSelection{Filter: &models.EmployeeFilter{SalaryMin: &salaryMin}}
+	_, err := service.batchUpdateEmployees(writer(":batchUpdate"), models.BatchUpdateRequest{EmployeeSelection: bySalary, Update: models.Employee{Position: "Manager"}, DryRun: true})
+	assert.Equal(t, http.StatusForbidden, err.Code)
+	_, err = service.batchDeleteEmployees(writer(":batchDelete"), models.BatchDeleteRequest{EmployeeSelection: bySalary, DryRun: true})
+	assert.Equal(t, http.StatusForbidden, err.Code)
+
+	// The employees returned, dry run or not, have their salaries redacted
+	byID := models.EmployeeSelection{IDs: ids}
+	updated, err := service.batchUpdateEmployees(writer(":batchUpdate"), models.BatchUpdateRequest{EmployeeSelection: byID, Update: models.Employee{Position: "Manager"}, DryRun: true})
+	assert.Nil(t, err)
+	deleted, err := service.batchDeleteEmployees(writer(":batchDelete"), models.BatchDeleteRequest{EmployeeSelection: byID})
+	assert.Nil(t, err)
+	for _, result := range []models.BatchResult{updated, deleted} {
+		assert.Len(t, result.Employees, 2)
+		for _, employee := range result.Employees {
+			assert.Nil(t, employee.Salary)
+		}
+	}
+}
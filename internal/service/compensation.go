@@ -0,0 +1,132 @@
+package service
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Retrieves the salary history of an employee with the salary in effect now, or at ?as_of=
+func GetCompensation() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for employee compensation")
+
+		asOf, err := middleware.ParseAsOf(ctx)
+		if err != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		compensation, employeeErr := employeeClient.getCompensation(ctx, ctx.Param("id"), asOf)
+		if employeeErr != nil {
+			utils.RespondWithError(ctx, employeeErr.Code, employeeErr.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, compensation)
+	}
+}
+
+func (service *EmployeeService) getCompensation(ctx *gin.Context, employeeId string, asOf time.Time) (models.Compensation, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "getCompensation")()
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return models.Compensation{}, err
+	}
+
+	visible, err := service.salaryVisibility(ctx)
+	if err != nil {
+		return models.Compensation{}, err
+	}
+	if !visible(employeeId) {
+		return models.Compensation{}, &employeeerror.EmployeeError{
+			Code:    http.StatusForbidden,
+			Message: "the caller may not see the salary of this employee",
+			Trace:   txid,
+		}
+	}
+
+	utils.Log(ctx).Info("calling db layer for employee compensation")
+	return service.repo.GetCompensation(ctx, employeeId, asOf)
+}
+
+// Records a salary change, a future effective_from schedules it
+func ScheduleSalaryChange() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for salary change")
+
+		var change models.SalaryChange
+		if err := ctx.ShouldBindBodyWith(&change, binding.JSON); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"Unable to marshal the request body": err.Error()})
+			return
+		}
+		change.EmployeeID = ctx.Param("id")
+
+		recorded, err := employeeClient.scheduleSalaryChange(ctx, change)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, recorded)
+	}
+}
+
+func (service *EmployeeService) scheduleSalaryChange(ctx *gin.Context, change models.SalaryChange) (models.SalaryChange, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "scheduleSalaryChange")()
+
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return models.SalaryChange{}, err
+	}
+
+	// A change without an effective date takes effect straight away
+	if change.EffectiveFrom.IsZero() {
+		change.EffectiveFrom = time.Now()
+	}
+	if change.Currency == "" {
+		change.Currency = constants.DefaultCurrency
+	}
+
+	utils.Log(ctx).Info("calling db layer for salary change")
+	return service.repo.ScheduleSalaryChange(ctx, change)
+}
+
+// RunSalaryScheduler applies the scheduled salary changes that have become due at the
+// configured interval until ctx is done. It returns straight away when no interval is configured.
+func RunSalaryScheduler(ctx context.Context) {
+	interval := config.GetConfig().Compensation.ApplyIntervalMinutes
+	if interval < 1 {
+		utils.Logger.Info("scheduled salary changes are disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+	defer ticker.Stop()
+	for {
+		// Each run is audited as a transaction of its own, made by the system
+		applied, err := employeeClient.repo.ApplySalaryChanges(ctx, time.Now(), constants.SystemActor, uuid.New().String())
+		if err != nil {
+			utils.Logger.Error("applying scheduled salary changes failed", zap.Error(err))
+		} else {
+			utils.Logger.Info("applied scheduled salary changes", zap.Int64("employees", applied))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Adds a new department to the database
+func CreateDepartment() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for department creation")
+		var department models.Department
+		if err := ctx.ShouldBindBodyWith(&department, binding.JSON); err == nil {
+			departmentID, err := employeeClient.createDepartment(ctx, department)
+			if err != nil {
+				utils.RespondWithError(ctx, err.Code, err.Message)
+				return
+			}
+			ctx.JSON(http.StatusOK, map[string]string{
+				"department_id": departmentID,
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{"Unable to marshal the request body": err.Error()})
+		}
+	}
+}
+
+func (service *EmployeeService) createDepartment(ctx *gin.Context, department models.Department) (string, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "createDepartment")()
+
+	if err := authorize(ctx, policy.WriteDepartments); err != nil {
+		return "", err
+	}
+
+	utils.Log(ctx).Info("calling db layer for department creation")
+	return service.repo.CreateDepartment(ctx, department)
+}
+
+// Retrieves a department by ID
+func GetDepartmentByID() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		department, err := employeeClient.getDepartmentByID(ctx, ctx.Param("id"))
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, department)
+	}
+}
+
+func (service *EmployeeService) getDepartmentByID(ctx *gin.Context, departmentId string) (models.Department, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "getDepartmentByID")()
+
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return models.Department{}, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to get department details")
+	return service.repo.GetDepartmentByID(ctx, departmentId)
+}
+
+// Updates the name and description of a department, the ID is taken from the URL
+func UpdateDepartment() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for updating department details")
+		var department models.Department
+		if err := ctx.ShouldBindBodyWith(&department, binding.JSON); err == nil {
+			department.ID = ctx.Param("id")
+			updated, err := employeeClient.updateDepartment(ctx, department)
+			if err != nil {
+				utils.RespondWithError(ctx, err.Code, err.Message)
+				return
+			}
+			ctx.JSON(http.StatusOK, updated)
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{"Unable to marshal the request body": err.Error()})
+		}
+	}
+}
+
+func (service *EmployeeService) updateDepartment(ctx *gin.Context, department models.Department) (models.Department, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "updateDepartment")()
+
+	if err := authorize(ctx, policy.WriteDepartments); err != nil {
+		return models.Department{}, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for department updation")
+	return service.repo.UpdateDepartment(ctx, department)
+}
+
+// Deletes a department, departments that still have employees are rejected
+func DeleteDepartment() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		err := employeeClient.deleteDepartment(ctx, ctx.Param("id"))
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+
+		utils.Log(ctx).Info("user has successfully deleted a department")
+		ctx.Writer.WriteHeader(http.StatusOK)
+	}
+}
+
+func (service *EmployeeService) deleteDepartment(ctx *gin.Context, departmentId string) *employeeerror.EmployeeError {
+	defer tracing.Trace(ctx, "deleteDepartment")()
+
+	if err := authorize(ctx, policy.WriteDepartments); err != nil {
+		return err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to check if department Id exists")
+	if _, err := service.repo.GetDepartmentByID(ctx, departmentId); err != nil {
+		return err
+	}
+
+	utils.Log(ctx).Info("calling db layer for department deletion")
+	return service.repo.DeleteDepartment(ctx, departmentId)
+}
+
+// List Departments
+func ListDepartments() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		_ = middleware.GetTransactionID(ctx)
+
+		page, _ := strconv.Atoi(ctx.Query("page"))
+		pagesize, _ := strconv.Atoi(ctx.Query("pagesize"))
+
+		departments, err := employeeClient.listDepartments(ctx, page, pagesize)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, departments)
+	}
+}
+
+func (service *EmployeeService) listDepartments(ctx *gin.Context, page, pagesize int) ([]models.Department, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "listDepartments")()
+
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return nil, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to list departments")
+	return service.repo.ListDepartments(ctx, page, pagesize)
+}
+
+// Lists the employees of a department, with the same paging, filters and sorting as ListEmployees
+func ListDepartmentEmployees() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		query, parseErr := middleware.ParseEmployeeQuery(ctx)
+		if parseErr != nil {
+			utils.RespondWithError(ctx, http.StatusBadRequest, parseErr.Error())
+			return
+		}
+
+		employeeDetails, err := employeeClient.listDepartmentEmployees(ctx, ctx.Param("id"), query)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, employeeDetails)
+	}
+}
+
+func (service *EmployeeService) listDepartmentEmployees(ctx *gin.Context, departmentId string, query models.EmployeeQuery) (models.EmployeeList, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "listDepartmentEmployees")()
+
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return models.EmployeeList{}, err
+	}
+	if err := checkSalaryQuery(ctx, query); err != nil {
+		return models.EmployeeList{}, err
+	}
+	if err := checkDeletedQuery(ctx, query); err != nil {
+		return models.EmployeeList{}, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to check if department Id exists")
+	if _, err := service.repo.GetDepartmentByID(ctx, departmentId); err != nil {
+		return models.EmployeeList{}, err
+	}
+
+	query.DepartmentID = &departmentId
+	utils.Log(ctx).Info("calling db layer for to list department employees")
+	employees, err := service.repo.ListEmployee(ctx, query)
+	if err != nil {
+		return models.EmployeeList{}, err
+	}
+	if err := service.redactEmployeeList(ctx, employees.Employees); err != nil {
+		return models.EmployeeList{}, err
+	}
+	return employees, nil
+}
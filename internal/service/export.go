@@ -0,0 +1,236 @@
+package service
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// exportColumns are the columns of the CSV and XLSX exports
+var exportColumns = []string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "created_at", "last_updated_at", "version", "deleted_at"}
+
+// Streams every employee matching the listing filters as CSV, NDJSON or XLSX. The sort order
+// of the listing applies, its paging does not.
+func ExportEmployees() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for employee export")
+
+		format := ctx.DefaultQuery("format", models.ExportFormatCSV)
+		query, _ := middleware.ParseEmployeeQuery(ctx)
+
+		// Nothing is sent before the first employee is read, so that an early failure can
+		// still be answered with an error
+		var exporter employeeExporter
+		start := func() {
+			exporter = newEmployeeExporter(format, ctx.Writer)
+			ctx.Header(constants.ContentType, exportContentType(format))
+			ctx.Header(constants.ContentDisposition, fmt.Sprintf(`attachment; filename="employees-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+		}
+
+		err := employeeClient.exportEmployees(ctx, query, func(employee models.Employee) error {
+			if exporter == nil {
+				start()
+			}
+			return exporter.Write(employee)
+		})
+		if err != nil {
+			if exporter == nil {
+				utils.RespondWithError(ctx, err.Code, err.Message)
+				return
+			}
+			// The status line is gone already, dropping the connection tells the client
+			// that the file is incomplete
+			utils.Log(ctx).Info("employee export failed part way")
+			abortConnection(ctx)
+			return
+		}
+
+		if exporter == nil {
+			start()
+		}
+		if err := exporter.Close(); err != nil {
+			utils.Log(ctx).Error("unable to complete employee export", zap.Error(err))
+			abortConnection(ctx)
+		}
+	}
+}
+
+func (service *EmployeeService) exportEmployees(ctx *gin.Context, query models.EmployeeQuery, write func(models.Employee) error) *employeeerror.EmployeeError {
+	defer tracing.Trace(ctx, "exportEmployees")()
+
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return err
+	}
+	if err := checkSalaryQuery(ctx, query); err != nil {
+		return err
+	}
+	if err := checkDeletedQuery(ctx, query); err != nil {
+		return err
+	}
+	visible, err := service.salaryVisibility(ctx)
+	if err != nil {
+		return err
+	}
+
+	utils.Log(ctx).Info("calling db layer for employee export")
+	return service.repo.ExportEmployees(ctx, query, func(employee models.Employee) error {
+		if !visible(employee.ID) {
+			employee.Salary = nil
+		}
+		return write(employee)
+	})
+}
+
+// abortConnection closes the connection of a response that cannot be completed
+func abortConnection(ctx *gin.Context) {
+	ctx.Abort()
+	if conn, _, err := ctx.Writer.Hijack(); err == nil {
+		conn.Close()
+	}
+}
+
+func exportContentType(format string) string {
+	switch format {
+	case models.ExportFormatNDJSON:
+		return constants.ApplicationNDJSON
+	case models.ExportFormatXLSX:
+		return constants.ApplicationXLSX
+	}
+	return constants.TextCSV + "; charset=utf-8"
+}
+
+// employeeExporter writes employees in one of the export formats
+type employeeExporter interface {
+	Write(models.Employee) error
+	// Close completes the file, it writes the header of an export without employees
+	Close() error
+}
+
+func newEmployeeExporter(format string, w io.Writer) employeeExporter {
+	switch format {
+	case models.ExportFormatNDJSON:
+		return ndjsonExporter{encoder: json.NewEncoder(w)}
+	case models.ExportFormatXLSX:
+		return xlsxExporter{workbook: utils.NewXLSXWriter(w, exportColumns)}
+	}
+	return &csvExporter{writer: csv.NewWriter(w)}
+}
+
+// exportRecord returns the values of exportColumns for an employee, as text
+func exportRecord(employee models.Employee) []string {
+	var salary, currency string
+	if employee.Salary != nil {
+		salary, currency = employee.Salary.AmountString(), employee.Salary.Currency
+	}
+	optional := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+	var deletedAt string
+	if employee.DeletedAt != nil {
+		deletedAt = employee.DeletedAt.Format(time.RFC3339)
+	}
+	return []string{
+		employee.ID,
+		escapeFormula(employee.Name),
+		escapeFormula(employee.Position),
+		salary,
+		currency,
+		optional(employee.DepartmentID),
+		optional(employee.ManagerID),
+		employee.CreatedAt.Format(time.RFC3339),
+		employee.LastUpdatedAt.Format(time.RFC3339),
+		strconv.Itoa(employee.Version),
+		deletedAt,
+	}
+}
+
+// escapeFormula prefixes text a spreadsheet would take for a formula with ', so that a name such
+// as =HYPERLINK(...) is shown as written rather than evaluated when the export is opened
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type csvExporter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvExporter) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	return e.writer.Write(exportColumns)
+}
+
+func (e *csvExporter) Write(employee models.Employee) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.writer.Write(exportRecord(employee))
+}
+
+func (e *csvExporter) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type ndjsonExporter struct {
+	encoder *json.Encoder
+}
+
+func (e ndjsonExporter) Write(employee models.Employee) error {
+	return e.encoder.Encode(employee)
+}
+
+func (e ndjsonExporter) Close() error {
+	return nil
+}
+
+type xlsxExporter struct {
+	workbook *utils.XLSXWriter
+}
+
+func (e xlsxExporter) Write(employee models.Employee) error {
+	record := exportRecord(employee)
+	cells := make([]interface{}, len(record))
+	for i, value := range record {
+		cells[i] = value
+	}
+	// Numbers stay numbers, so that the spreadsheet can sum salaries
+	if id, err := strconv.Atoi(employee.ID); err == nil {
+		cells[0] = id
+	}
+	if employee.Salary != nil {
+		cells[3] = utils.XLSXNumber(employee.Salary.Amount.String())
+	}
+	cells[9] = employee.Version
+	return e.workbook.WriteRow(cells...)
+}
+
+func (e xlsxExporter) Close() error {
+	return e.workbook.Close()
+}
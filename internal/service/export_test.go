@@ -0,0 +1,54 @@
+package service
+
+import (
+	"archive/zip"
+	"assignment/internal/models"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmployeeExporter_EscapesFormulas(t *testing.T) {
+	employee := models.Employee{ID: "1", Name: `=HYPERLINK("http://evil.example","x")`, Position: "@SUM(A1:A9)", CreatedAt: time.Unix(0, 0).UTC(), LastUpdatedAt: time.Unix(0, 0).UTC(), Version: 1}
+	export := func(format string) string {
+		var buf bytes.Buffer
+		exporter := newEmployeeExporter(format, &buf)
+		assert.NoError(t, exporter.Write(employee))
+		assert.NoError(t, exporter.Close())
+		return buf.String()
+	}
+
+	// CSV and XLSX cells are opened by spreadsheets, so they are escaped
+	assert.Contains(t, export(models.ExportFormatCSV), "\n1,\"'=HYPERLINK(\"\"http://evil.example\"\",\"\"x\"\")\",'@SUM(A1:A9),")
+
+	xlsx := []byte(export(models.ExportFormatXLSX))
+	archive, err := zip.NewReader(bytes.NewReader(xlsx), int64(len(xlsx)))
+	assert.NoError(t, err)
+	sheet, err := archive.Open("xl/worksheets/sheet1.xml")
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(sheet)
+	assert.Contains(t, string(content), `<t xml:space="preserve">&#39;=HYPERLINK(`)
+	assert.Contains(t, string(content), `<t xml:space="preserve">&#39;@SUM(A1:A9)</t>`)
+
+	// NDJSON is read by programs, which are given the values as they are
+	assert.Contains(t, export(models.ExportFormatNDJSON), `"name":"=HYPERLINK(`)
+}
+
+func TestEscapeFormula(t *testing.T) {
+	for value, expected := range map[string]string{
+		"":           "",
+		"Jane Roe":   "Jane Roe",
+		"=1+1":       "'=1+1",
+		"+1":         "'+1",
+		"-1":         "'-1",
+		"@A1":        "'@A1",
+		"\tEngineer": "'\tEngineer",
+		"\r=1":       "'\r=1",
+		"Jane=Roe":   "Jane=Roe",
+	} {
+		assert.Equal(t, expected, escapeFormula(value), "%q", value)
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/models"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Version and Commit of the build, set with
+// go build -ldflags "-X assignment/internal/service.Version=1.4.0 -X assignment/internal/service.Commit=$(git rev-parse HEAD)".
+// Commit defaults to the revision go build records when building from a git checkout.
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// defaultDBTimeout bounds the ping of the readiness check when health.db_timeout_millis is not set
+const defaultDBTimeout = time.Second
+
+var (
+	startedAt    = time.Now()
+	shuttingDown atomic.Bool
+)
+
+// BeginShutdown makes the readiness check fail, so that no new request is routed to the instance
+// while it drains the requests in flight
+func BeginShutdown() {
+	shuttingDown.Store(true)
+}
+
+// Reports that the process is alive, without checking its dependencies
+func Healthz() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"status": models.CheckOK})
+	}
+}
+
+// Reports whether the instance can serve requests: the database answers, its schema is up to
+// date and the instance is not shutting down
+func Readyz() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		readiness := employeeClient.readiness(ctx)
+		if readiness.Status != models.Ready {
+			ctx.JSON(http.StatusServiceUnavailable, readiness)
+			return
+		}
+		ctx.JSON(http.StatusOK, readiness)
+	}
+}
+
+func (service *EmployeeService) readiness(ctx *gin.Context) models.Readiness {
+	defer tracing.Trace(ctx, "readiness")()
+
+	readiness := models.Readiness{Status: models.Ready, Checks: map[string]string{
+		"database":   models.CheckOK,
+		"migrations": models.CheckOK,
+		"shutdown":   models.CheckOK,
+	}}
+	fail := func(check, reason string) {
+		readiness.Status = models.NotReady
+		readiness.Checks[check] = reason
+	}
+
+	if shuttingDown.Load() {
+		fail("shutdown", "shutting down")
+	}
+
+	timeout := defaultDBTimeout
+	if millis := config.GetConfig().Health.DBTimeoutMillis; millis > 0 {
+		timeout = time.Duration(millis) * time.Millisecond
+	}
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+	defer cancel()
+
+	if err := service.repo.Ping(checkCtx); err != nil {
+		utils.Log(ctx).Warn("readiness check failed, the database does not answer", zap.Error(err))
+		fail("database", "unreachable")
+		fail("migrations", "unknown")
+		return readiness
+	}
+	pending, err := service.repo.PendingMigrations(checkCtx)
+	if err != nil {
+		utils.Log(ctx).Warn("readiness check failed, unable to read the applied migrations", zap.Error(err))
+		fail("migrations", "unknown")
+	} else if pending > 0 {
+		fail("migrations", fmt.Sprintf("%d migration(s) pending", pending))
+	}
+	return readiness
+}
+
+// Describes the running instance: its build, uptime and connection pool
+func GetStatus() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for the status of the instance")
+		ctx.JSON(http.StatusOK, employeeClient.getStatus(ctx))
+	}
+}
+
+func (service *EmployeeService) getStatus(ctx *gin.Context) models.Status {
+	defer tracing.Trace(ctx, "getStatus")()
+
+	status := models.Status{
+		Version:       Version,
+		Commit:        buildCommit(),
+		StartedAt:     startedAt.UTC(),
+		UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+		Database:      "in-memory",
+	}
+	if pool, ok := service.repo.PoolStats(); ok {
+		status.Database = "postgres"
+		status.Pool = &pool
+	}
+	return status
+}
+
+// buildCommit returns Commit, or the revision recorded by go build, suffixed with -dirty when
+// the checkout had local changes
+func buildCommit() string {
+	if Commit != "" {
+		return Commit
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	revision, modified := "unknown", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Lists the employees reporting to an employee, only direct reports unless ?depth= is given.
+// depth=all returns every level below the employee.
+func GetEmployeeReports() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for employee reports")
+
+		depth := 1
+		if value := ctx.Query("depth"); value == "all" {
+			depth = 0
+		} else if value != "" {
+			var err error
+			depth, err = strconv.Atoi(value)
+			if err != nil || depth < 1 {
+				utils.RespondWithError(ctx, http.StatusBadRequest, "depth must be a positive number or all")
+				return
+			}
+		}
+
+		reports, err := employeeClient.getEmployeeReports(ctx, ctx.Param("id"), depth)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, reports)
+	}
+}
+
+func (service *EmployeeService) getEmployeeReports(ctx *gin.Context, employeeId string, depth int) ([]models.EmployeeReport, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "getEmployeeReports")()
+
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return nil, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to check if employee Id exists")
+	if _, err := service.repo.GetEmployeeByID(ctx, employeeId); err != nil {
+		return nil, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to get employee reports")
+	reports, err := service.repo.ListReports(ctx, employeeId, depth)
+	if err != nil {
+		return nil, err
+	}
+	redacted := make([]*models.Employee, len(reports))
+	for i := range reports {
+		redacted[i] = &reports[i].Employee
+	}
+	if err := service.redactSalaries(ctx, redacted...); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// Returns the employee and their managers up to the root of the organisation
+func GetEmployeeChain() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for employee management chain")
+
+		chain, err := employeeClient.getEmployeeChain(ctx, ctx.Param("id"))
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, chain)
+	}
+}
+
+func (service *EmployeeService) getEmployeeChain(ctx *gin.Context, employeeId string) ([]models.Employee, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "getEmployeeChain")()
+
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return nil, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to get employee management chain")
+	chain, err := service.repo.GetManagementChain(ctx, employeeId)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.redactEmployeeList(ctx, chain); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// Returns the whole organisation as a nested tree
+func GetOrgChart() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		_ = middleware.GetTransactionID(ctx)
+		utils.Log(ctx).Info("received request for org chart")
+
+		orgChart, err := employeeClient.getOrgChart(ctx)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, orgChart)
+	}
+}
+
+func (service *EmployeeService) getOrgChart(ctx *gin.Context) ([]*models.OrgChartNode, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "getOrgChart")()
+
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return nil, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to get org chart")
+	orgChart, err := service.repo.GetOrgChart(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var redacted []*models.Employee
+	var collect func(nodes []*models.OrgChartNode)
+	collect = func(nodes []*models.OrgChartNode) {
+		for _, node := range nodes {
+			redacted = append(redacted, &node.Employee)
+			collect(node.Reports)
+		}
+	}
+	collect(orgChart)
+	if err := service.redactSalaries(ctx, redacted...); err != nil {
+		return nil, err
+	}
+	return orgChart, nil
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// responseRecorder keeps a copy of the body written by a handler, to be replayed to its retries
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
+
+func (recorder *responseRecorder) WriteString(data string) (int, error) {
+	recorder.body.WriteString(data)
+	return recorder.ResponseWriter.WriteString(data)
+}
+
+// Idempotent runs handler once per Idempotency-Key. A retry of the request with the same key is
+// given the response of the first attempt, a different request with the key is refused. The
+// response is kept for the configured TTL, except a server error, after which a retry runs the
+// request again. Requests without the header are handled as usual.
+func Idempotent(handler func(ctx *gin.Context)) func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		ttl := config.GetConfig().Idempotency.TTLMinutes
+		if ttl < 1 {
+			handler(ctx)
+			return
+		}
+
+		_ = middleware.GetTransactionID(ctx)
+		key, hash, err := middleware.ParseIdempotencyKey(ctx)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				utils.RespondWithError(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit))
+				return
+			}
+			utils.RespondWithError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		if key == "" {
+			handler(ctx)
+			return
+		}
+
+		stored, replayErr := employeeClient.reserveIdempotencyKey(ctx, models.IdempotentResponse{
+			Key:         key,
+			RequestHash: hash,
+			ExpiresAt:   time.Now().Add(time.Duration(ttl) * time.Minute),
+		})
+		if replayErr != nil {
+			utils.RespondWithError(ctx, replayErr.Code, replayErr.Message)
+			return
+		}
+		if stored != nil {
+			if stored.ETag != "" {
+				ctx.Header(constants.ETag, stored.ETag)
+			}
+			ctx.Header(constants.IdempotentReplayed, "true")
+			ctx.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			ctx.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		saved := false
+		// A handler that panics leaves the key to be retried
+		defer func() {
+			ctx.Writer = recorder.ResponseWriter
+			if !saved {
+				employeeClient.releaseIdempotencyKey(ctx, key)
+			}
+		}()
+		handler(ctx)
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+		employeeClient.saveIdempotentResponse(ctx, models.IdempotentResponse{
+			Key:         key,
+			RequestHash: hash,
+			StatusCode:  recorder.Status(),
+			ContentType: recorder.Header().Get(constants.ContentType),
+			ETag:        recorder.Header().Get(constants.ETag),
+			Body:        recorder.body.Bytes(),
+		})
+		saved = true
+	}
+}
+
+// reserveIdempotencyKey takes the key for the request, or returns the response to replay when
+// an identical request already completed with it
+func (service *EmployeeService) reserveIdempotencyKey(ctx *gin.Context, reservation models.IdempotentResponse) (*models.IdempotentResponse, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "reserveIdempotencyKey")()
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	utils.Log(ctx).Info("calling db layer to reserve idempotency key", zap.String("idempotency_key", reservation.Key))
+	stored, reserved, err := service.repo.ReserveIdempotencyKey(ctx, reservation)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+	if stored.RequestHash != reservation.RequestHash {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: constants.IdempotencyKey + " was already used for a different request",
+			Trace:   txid,
+		}
+	}
+	if stored.StatusCode == 0 {
+		return nil, &employeeerror.EmployeeError{
+			Code:    http.StatusConflict,
+			Message: "a request with this " + constants.IdempotencyKey + " is still in progress, retry later",
+			Trace:   txid,
+		}
+	}
+	utils.Log(ctx).Info("replaying the response for idempotency key", zap.String("idempotency_key", reservation.Key))
+	return &stored, nil
+}
+
+// saveIdempotentResponse stores the response of the request. The response has already been
+// sent, so failing to store it is only logged and the key is released instead.
+func (service *EmployeeService) saveIdempotentResponse(ctx *gin.Context, response models.IdempotentResponse) {
+	defer tracing.Trace(ctx, "saveIdempotentResponse")()
+
+	if err := service.repo.SaveIdempotentResponse(ctx, response); err != nil {
+		utils.Log(ctx).Error("unable to save the response for idempotency key", zap.String("idempotency_key", response.Key), zap.String("error", err.Message))
+		service.releaseIdempotencyKey(ctx, response.Key)
+	}
+}
+
+func (service *EmployeeService) releaseIdempotencyKey(ctx *gin.Context, key string) {
+	defer tracing.Trace(ctx, "releaseIdempotencyKey")()
+
+	if err := service.repo.ReleaseIdempotencyKey(ctx, key); err != nil {
+		utils.Log(ctx).Error("unable to release idempotency key", zap.String("idempotency_key", key), zap.String("error", err.Message))
+	}
+}
+
+// RunIdempotencyKeyPurge removes the expired responses of idempotency keys at the configured
+// interval until ctx is done. It returns straight away when the purge is disabled.
+func RunIdempotencyKeyPurge(ctx context.Context) {
+	idempotency := config.GetConfig().Idempotency
+	if idempotency.TTLMinutes < 1 || idempotency.PurgeIntervalMinutes < 1 {
+		utils.Logger.Info("purge of expired idempotency keys is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(idempotency.PurgeIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+	for {
+		purged, err := employeeClient.repo.PurgeIdempotencyKeys(ctx, time.Now())
+		if err != nil {
+			utils.Logger.Error("purge of expired idempotency keys failed", zap.Error(err))
+		} else {
+			utils.Logger.Info("purge removed expired idempotency keys", zap.Int64("purged", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// idempotentRequest is a request sent to the idempotent handler and the response expected
+type idempotentRequest struct {
+	body     string
+	key      string
+	actor    string
+	status   int
+	replayed bool
+	// call is the call of the handler the response comes from
+	call int
+}
+
+func TestIdempotent(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		requests []idempotentRequest
+		calls    int
+	}{
+		{
+			name: "retry",
+			requests: []idempotentRequest{
+				{body: `{"name": "a"}`, key: "k1", status: http.StatusCreated, call: 1},
+				{body: `{"name": "a"}`, key: "k1", status: http.StatusCreated, replayed: true, call: 1},
+			},
+			calls: 1,
+		},
+		{
+			name: "different request with the key",
+			requests: []idempotentRequest{
+				{body: `{"name": "a"}`, key: "k1", status: http.StatusCreated, call: 1},
+				{body: `{"name": "b"}`, key: "k1", status: http.StatusUnprocessableEntity},
+			},
+			calls: 1,
+		},
+		{
+			name: "another caller with the key",
+			requests: []idempotentRequest{
+				{body: `{"name": "a"}`, key: "k1", actor: "alice", status: http.StatusCreated, call: 1},
+				{body: `{"name": "a"}`, key: "k1", actor: "bob", status: http.StatusUnprocessableEntity},
+			},
+			calls: 1,
+		},
+		{
+			name: "different keys",
+			requests: []idempotentRequest{
+				{body: `{"name": "a"}`, key: "k1", status: http.StatusCreated, call: 1},
+				{body: `{"name": "a"}`, key: "k2", status: http.StatusCreated, call: 2},
+			},
+			calls: 2,
+		},
+		{
+			name: "without a key",
+			requests: []idempotentRequest{
+				{body: `{"name": "a"}`, status: http.StatusCreated, call: 1},
+				{body: `{"name": "a"}`, status: http.StatusCreated, call: 2},
+			},
+			calls: 2,
+		},
+		{
+			name: "server error",
+			requests: []idempotentRequest{
+				{body: `{"name": "fail"}`, key: "k1", status: http.StatusInternalServerError, call: 1},
+				{body: `{"name": "fail"}`, key: "k1", status: http.StatusInternalServerError, call: 2},
+			},
+			calls: 2,
+		},
+		{
+			name: "client error",
+			requests: []idempotentRequest{
+				{body: `{"name": "reject"}`, key: "k1", status: http.StatusBadRequest, call: 1},
+				{body: `{"name": "reject"}`, key: "k1", status: http.StatusBadRequest, replayed: true, call: 1},
+			},
+			calls: 1,
+		},
+		{
+			name: "retry while the request is in progress",
+			requests: []idempotentRequest{
+				// The handler retries the request before it responds, which is refused
+				{body: `{"name": "retry"}`, key: "k1", status: http.StatusCreated, call: 1},
+			},
+			calls: 1,
+		},
+		{
+			name: "invalid key",
+			requests: []idempotentRequest{
+				{body: `{"name": "a"}`, key: "ké1", status: http.StatusBadRequest},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			newTestService(t)
+			config.SetConfig(config.GlobalConfig{Database: config.Database{InMemory: true}, Idempotency: config.Idempotency{TTLMinutes: 60}})
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			send := func(request idempotentRequest) *httptest.ResponseRecorder {
+				httpRequest := httptest.NewRequest(http.MethodPost, "/v1/employees", strings.NewReader(request.body))
+				httpRequest.Header.Set(constants.ContentType, constants.ApplicationJSON)
+				if request.key != "" {
+					httpRequest.Header.Set(constants.IdempotencyKey, request.key)
+				}
+				if request.actor != "" {
+					httpRequest.Header.Set(constants.Actor, request.actor)
+				}
+				response := httptest.NewRecorder()
+				router.ServeHTTP(response, httpRequest)
+				return response
+			}
+
+			calls := 0
+			router.POST("/v1/employees", Idempotent(func(ctx *gin.Context) {
+				calls++
+				var body struct{ Name string }
+				_ = ctx.ShouldBindJSON(&body)
+				switch body.Name {
+				case "fail":
+					ctx.JSON(http.StatusInternalServerError, gin.H{"call": calls})
+					return
+				case "reject":
+					ctx.JSON(http.StatusBadRequest, gin.H{"call": calls})
+					return
+				case "retry":
+					retry := send(idempotentRequest{body: `{"name": "retry"}`, key: ctx.GetHeader(constants.IdempotencyKey)})
+					assert.Equal(t, http.StatusConflict, retry.Code)
+				}
+				ctx.Header(constants.ETag, fmt.Sprintf(`"%d"`, calls))
+				ctx.JSON(http.StatusCreated, gin.H{"call": calls})
+			}))
+
+			for i, request := range test.requests {
+				response := send(request)
+				assert.Equal(t, request.status, response.Code, "request %d: %s", i, response.Body.String())
+				if request.call != 0 {
+					assert.JSONEq(t, fmt.Sprintf(`{"call": %d}`, request.call), response.Body.String(), "request %d", i)
+				}
+				if request.replayed {
+					assert.Equal(t, "true", response.Header().Get(constants.IdempotentReplayed), "request %d", i)
+					if request.status == http.StatusCreated {
+						assert.Equal(t, fmt.Sprintf(`"%d"`, request.call), response.Header().Get(constants.ETag), "request %d", i)
+					}
+				} else {
+					assert.Empty(t, response.Header().Get(constants.IdempotentReplayed), "request %d", i)
+				}
+			}
+			assert.Equal(t, test.calls, calls)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Creates the employees of a CSV or NDJSON upload and reports the outcome of every row.
+// ?mode=atomic, the default, creates every row or none of them, ?mode=best_effort creates the
+// valid rows and reports the others.
+func ImportEmployees() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for employee import")
+
+		report, err := employeeClient.importEmployees(ctx, ctx.DefaultQuery("mode", models.ImportModeAtomic))
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		// A rolled back import is reported along with the rows that caused it
+		if !report.Committed {
+			ctx.JSON(http.StatusUnprocessableEntity, report)
+			return
+		}
+		ctx.JSON(http.StatusOK, report)
+	}
+}
+
+func (service *EmployeeService) importEmployees(ctx *gin.Context, mode string) (models.ImportReport, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "importEmployees")()
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return models.ImportReport{}, err
+	}
+
+	rows, invalid, err := middleware.ParseEmployeeImport(ctx)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return models.ImportReport{}, &employeeerror.EmployeeError{
+				Code:    http.StatusRequestEntityTooLarge,
+				Message: fmt.Sprintf("import file is larger than %d bytes", tooLarge.Limit),
+				Trace:   txid,
+			}
+		}
+		return models.ImportReport{}, &employeeerror.EmployeeError{
+			Code:    http.StatusBadRequest,
+			Message: "import file is invalid, " + err.Error(),
+			Trace:   txid,
+		}
+	}
+
+	report := models.ImportReport{Mode: mode, Total: len(rows) + len(invalid), Rows: invalid}
+	atomic := mode == models.ImportModeAtomic
+	// An atomic import with invalid rows does not reach the database
+	if len(rows) > 0 && (!atomic || len(invalid) == 0) {
+		utils.Log(ctx).Info("calling db layer for employee import", zap.Int("rows", len(rows)))
+		results, err := service.repo.ImportEmployees(ctx, rows, atomic)
+		if err != nil {
+			return models.ImportReport{}, err
+		}
+		report.Rows = append(report.Rows, results...)
+	}
+
+	slices.SortFunc(report.Rows, func(a, b models.ImportResult) int {
+		return cmp.Compare(a.Line, b.Line)
+	})
+	for _, row := range report.Rows {
+		if row.Error != "" {
+			report.Failed++
+		} else {
+			report.Created++
+		}
+	}
+	report.Committed = !atomic || report.Failed == 0
+	return report, nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	"assignment/internal/db"
+	"assignment/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestService returns a service on an empty in-memory store
+func newTestService(t *testing.T) *EmployeeService {
+	config.SetConfig(config.GlobalConfig{Database: config.Database{InMemory: true}})
+	repo, err := db.New()
+	assert.NoError(t, err)
+	return NewEmployeeService(repo)
+}
+
+// newTestContext returns the context of a request, made while authentication is disabled
+func newTestContext(method, target, contentType, body string) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	ctx.Request.Header.Set(constants.TransactionID, "test-transaction-id")
+	if contentType != "" {
+		ctx.Request.Header.Set(constants.ContentType, contentType)
+	}
+	return ctx
+}
+
+func TestImportEmployees_Modes(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		mode    string
+		body    string
+		report  models.ImportReport
+		created int
+	}{
+		{
+			name:    "atomic",
+			mode:    models.ImportModeAtomic,
+			body:    "name,position,salary\nJohn Doe,Engineer,1000\nJane Roe,Manager,900\n",
+			report:  models.ImportReport{Committed: true, Total: 2, Created: 2, Rows: []models.ImportResult{{Line: 2, EmployeeID: "1"}, {Line: 3, EmployeeID: "2"}}},
+			created: 2,
+		},
+		{
+			name:   "atomic with an invalid row",
+			mode:   models.ImportModeAtomic,
+			body:   "name,position,salary\nJohn Doe,Engineer,1000\nJane Roe,Manager,\n",
+			report: models.ImportReport{Total: 2, Failed: 1, Rows: []models.ImportResult{{Line: 3, Error: "employee salary is missing"}}},
+		},
+		{
+			name:   "atomic with a row rejected by the store",
+			mode:   models.ImportModeAtomic,
+			body:   "name,position,salary,department_id\nJohn Doe,Engineer,1000,\nJane Roe,Manager,900,9\n",
+			report: models.ImportReport{Total: 2, Failed: 1, Rows: []models.ImportResult{{Line: 3, Error: "Department not found"}}},
+		},
+		{
+			name:    "best effort with an invalid row",
+			mode:    models.ImportModeBestEffort,
+			body:    "name,position,salary\nJane Roe,Manager,\nJohn Doe,Engineer,1000\n",
+			report:  models.ImportReport{Committed: true, Total: 2, Created: 1, Failed: 1, Rows: []models.ImportResult{{Line: 2, Error: "employee salary is missing"}, {Line: 3, EmployeeID: "1"}}},
+			created: 1,
+		},
+		{
+			name:    "best effort with a row rejected by the store",
+			mode:    models.ImportModeBestEffort,
+			body:    "name,position,salary,department_id\nJohn Doe,Engineer,1000,\nJane Roe,Manager,900,9\n",
+			report:  models.ImportReport{Committed: true, Total: 2, Created: 1, Failed: 1, Rows: []models.ImportResult{{Line: 2, EmployeeID: "1"}, {Line: 3, Error: "Department not found"}}},
+			created: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			service := newTestService(t)
+			ctx := newTestContext(http.MethodPost, "/v1/employees:import?mode="+test.mode, constants.TextCSV, test.body)
+
+			report, err := service.importEmployees(ctx, test.mode)
+			assert.Nil(t, err)
+			test.report.Mode = test.mode
+			assert.Equal(t, test.report, report)
+
+			list, err := service.repo.ListEmployee(ctx, models.EmployeeQuery{Page: 1, PageSize: 10})
+			assert.Nil(t, err)
+			assert.Equal(t, test.created, list.TotalCount)
+		})
+	}
+}
+
+func TestImportEmployees_InvalidFile(t *testing.T) {
+	service := newTestService(t)
+	ctx := newTestContext(http.MethodPost, "/v1/employees:import", constants.TextCSV, "name,position\n")
+
+	_, err := service.importEmployees(ctx, models.ImportModeAtomic)
+	assert.Equal(t, http.StatusBadRequest, err.Code)
+	assert.Equal(t, `import file is invalid, column "salary" is missing`, err.Message)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// patchedFields are the fields of an employee a patch can change, salary as text
+type patchedFields struct {
+	Name         string
+	Position     string
+	Salary       string
+	DepartmentID *string
+	ManagerID    *string
+}
+
+func fieldsOf(employee models.Employee) patchedFields {
+	fields := patchedFields{Name: employee.Name, Position: employee.Position, DepartmentID: employee.DepartmentID, ManagerID: employee.ManagerID}
+	if employee.Salary != nil {
+		fields.Salary = employee.Salary.String()
+	}
+	return fields
+}
+
+func TestPatchEmployee(t *testing.T) {
+	department, manager := "1", "1"
+	current := patchedFields{Name: "Jane Roe", Position: "Engineer", Salary: "1000.00 USD", DepartmentID: &department, ManagerID: &manager}
+	with := func(change func(*patchedFields)) patchedFields {
+		fields := current
+		change(&fields)
+		return fields
+	}
+
+	for _, test := range []struct {
+		name        string
+		contentType string
+		patch       string
+		expected    patchedFields
+		err         string
+	}{
+		{
+			name:        "merge patch of a field",
+			contentType: constants.MergePatchJSON,
+			patch:       `{"position": "Manager"}`,
+			expected:    with(func(f *patchedFields) { f.Position = "Manager" }),
+		},
+		{
+			name:        "merge patch clearing a field with null",
+			contentType: constants.MergePatchJSON,
+			patch:       `{"manager_id": null, "department_id": null}`,
+			expected:    with(func(f *patchedFields) { f.ManagerID, f.DepartmentID = nil, nil }),
+		},
+		{
+			name:        "merge patch of a member of the salary",
+			contentType: constants.MergePatchJSON,
+			patch:       `{"salary": {"amount": 1200.5}}`,
+			expected:    with(func(f *patchedFields) { f.Salary = "1200.50 USD" }),
+		},
+		{name: "merge patch clearing a required field", contentType: constants.MergePatchJSON, patch: `{"salary": null}`, err: "employee salary is missing"},
+		{name: "merge patch clearing the name", contentType: constants.MergePatchJSON, patch: `{"name": null}`, err: "employee name is missing"},
+		{name: "merge patch of the id", contentType: constants.MergePatchJSON, patch: `{"id": "9"}`, err: `the patched employee is invalid, unknown field "id"`},
+		{name: "merge patch of an unknown salary member", contentType: constants.MergePatchJSON, patch: `{"salary": {"bonus": 5}}`, err: `the patched employee is invalid, unknown field "salary.bonus"`},
+		{name: "merge patch making the employee its own manager", contentType: constants.MergePatchJSON, patch: `{"manager_id": "2"}`, err: "employee cannot be their own manager"},
+		{
+			name:        "json patch",
+			contentType: constants.JSONPatchJSON,
+			patch:       `[{"op": "replace", "path": "/position", "value": "Manager"}, {"op": "remove", "path": "/manager_id"}]`,
+			expected:    with(func(f *patchedFields) { f.Position, f.ManagerID = "Manager", nil }),
+		},
+		{
+			name:        "json patch setting a field to null",
+			contentType: constants.JSONPatchJSON,
+			patch:       `[{"op": "test", "path": "/name", "value": "Jane Roe"}, {"op": "replace", "path": "/department_id", "value": null}]`,
+			expected:    with(func(f *patchedFields) { f.DepartmentID = nil }),
+		},
+		{name: "json patch sent as a merge patch", contentType: constants.MergePatchJSON, patch: `[{"op": "remove", "path": "/position"}]`, err: "a merge patch must be a JSON object"},
+		{name: "json patch removing a required field", contentType: constants.JSONPatchJSON, patch: `[{"op": "remove", "path": "/position"}]`, err: "employee position is missing"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			service := newTestService(t)
+			setup := newTestContext(http.MethodPost, "/v1/departments", "", "")
+			_, err := service.repo.CreateDepartment(setup, models.Department{Name: "R&D"})
+			assert.Nil(t, err)
+			createTestEmployees(t, service, "John Doe")
+			salary, _ := models.ParseDecimal("1000")
+			id, err := service.repo.CreateEmployee(setup, models.Employee{Name: current.Name, Position: current.Position,
+				Salary: &models.Money{Amount: salary, Currency: "USD"}, DepartmentID: &department, ManagerID: &manager})
+			assert.Nil(t, err)
+
+			ctx := newTestContext(http.MethodPatch, "/v1/employees/"+id, test.contentType, "")
+			ctx.Request.Header.Set(constants.IfMatch, utils.ETag(1))
+			ctx.Set(gin.BodyBytesKey, []byte(test.patch))
+			patched, err := service.patchEmployee(ctx, id)
+			stored, _ := service.repo.GetEmployeeByID(ctx, id)
+			if test.err != "" {
+				assert.Equal(t, http.StatusUnprocessableEntity, err.Code)
+				assert.Equal(t, test.err, err.Message)
+				assert.Equal(t, current, fieldsOf(stored))
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, fieldsOf(patched))
+			assert.Equal(t, test.expected, fieldsOf(stored))
+			assert.Equal(t, 2, stored.Version)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/db"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// authorize refuses the request unless its caller was granted the permission
+func authorize(ctx *gin.Context, permission string) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	principal := middleware.GetPrincipal(ctx)
+	if principal.Can(permission) {
+		return nil
+	}
+	utils.Log(ctx).Info("permission is not granted", zap.String("permission", string(permission)))
+	return &employeeerror.EmployeeError{
+		Code:    http.StatusForbidden,
+		Message: "the " + permission + " permission is required",
+		Trace:   txid,
+	}
+}
+
+// salaryVisibility returns whether the caller may see the salary of an employee, given its ID.
+// A caller granted policy.ReadReportSalaries sees the salary of the employees reporting to it,
+// directly or not, which are looked up once.
+func (service *EmployeeService) salaryVisibility(ctx *gin.Context) (func(employeeID string) bool, *employeeerror.EmployeeError) {
+	principal := middleware.GetPrincipal(ctx)
+	if principal.Can(policy.ReadSalaries) {
+		return func(string) bool { return true }, nil
+	}
+	if !principal.Can(policy.ReadReportSalaries) || principal.EmployeeID == "" {
+		return func(string) bool { return false }, nil
+	}
+
+	utils.Log(ctx).Info("calling db layer for to get the reports of the caller")
+	reports, err := service.repo.ListReports(ctx, principal.EmployeeID, 0)
+	if err != nil {
+		return nil, err
+	}
+	visible := make(map[string]bool, len(reports))
+	for _, report := range reports {
+		visible[report.ID] = true
+	}
+	return func(employeeID string) bool {
+		// IDs are compared as numbers, so "007" is the report "7"
+		id, err := strconv.Atoi(employeeID)
+		return err == nil && visible[strconv.Itoa(id)]
+	}, nil
+}
+
+// redactSalaries clears the salary of the employees the caller may not see
+func (service *EmployeeService) redactSalaries(ctx *gin.Context, employees ...*models.Employee) *employeeerror.EmployeeError {
+	visible, err := service.salaryVisibility(ctx)
+	if err != nil {
+		return err
+	}
+	for _, employee := range employees {
+		if !visible(employee.ID) {
+			employee.Salary = nil
+		}
+	}
+	return nil
+}
+
+// checkSalaryQuery refuses a filter or an order on salaries to a caller who may not see every
+// salary, as the employees it selects would tell the salaries apart. A page following a cursor
+// without a sort order is read in the order of the cursor.
+func checkSalaryQuery(ctx *gin.Context, query models.EmployeeQuery) *employeeerror.EmployeeError {
+	sort := query.Sort
+	if len(sort) == 0 && query.Cursor != "" {
+		// An invalid cursor is refused by the database layer
+		sort, _ = db.CursorSort(query.Cursor)
+	}
+	bySalary := query.SalaryMin != nil || query.SalaryMax != nil
+	for _, field := range sort {
+		bySalary = bySalary || field.Field == "salary"
+	}
+	if !bySalary {
+		return nil
+	}
+	return authorize(ctx, policy.ReadSalaries)
+}
+
+// checkDeletedQuery refuses soft-deleted employees to a caller who may not purge them, as they
+// are only kept to be restored or purged
+func checkDeletedQuery(ctx *gin.Context, query models.EmployeeQuery) *employeeerror.EmployeeError {
+	if !query.IncludeDeleted {
+		return nil
+	}
+	return authorize(ctx, policy.PurgeEmployees)
+}
+
+// redactEmployeeList clears the salary of the listed employees the caller may not see
+func (service *EmployeeService) redactEmployeeList(ctx *gin.Context, employees []models.Employee) *employeeerror.EmployeeError {
+	redacted := make([]*models.Employee, len(employees))
+	for i := range employees {
+		redacted[i] = &employees[i]
+	}
+	return service.redactSalaries(ctx, redacted...)
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"assignment/internal/constants"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListEmployees_CursorSortedBySalary(t *testing.T) {
+	service := newTestService(t)
+	createTestEmployees(t, service, "John Doe", "Jane Roe", "Max Mustermann")
+
+	bySalary := []models.SortField{{Field: "salary", Descending: true}}
+	first, err := service.listEmployees(newTestContext(http.MethodGet, "/v1/employees", "", ""), models.EmployeeQuery{Sort: bySalary, Page: 1, PageSize: 1})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, first.NextCursor)
+
+	// The cursor is read in the salary order it was issued for, which a viewer may not use
+	viewer := newTestContext(http.MethodGet, "/v1/employees", "", "")
+	viewer.Set(constants.PrincipalKey, policy.NewPrincipal("bob", []string{policy.ReadEmployees}))
+	_, err = service.listEmployees(viewer, models.EmployeeQuery{Cursor: first.NextCursor, PageSize: 1})
+	assert.Equal(t, http.StatusForbidden, err.Code)
+
+	next, err := service.listEmployees(newTestContext(http.MethodGet, "/v1/employees", "", ""), models.EmployeeQuery{Cursor: first.NextCursor, PageSize: 1})
+	assert.Nil(t, err)
+	assert.Len(t, next.Employees, 1)
+	assert.NotEqual(t, first.Employees[0].ID, next.Employees[0].ID)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	employeeerror "assignment/internal/errors"
+	"assignment/internal/middleware"
+	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
+	"assignment/internal/utils"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Restores a soft-deleted employee
+func RestoreEmployee() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for employee restore")
+
+		employee, err := employeeClient.restoreEmployee(ctx, ctx.Param("id"))
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.Header(constants.ETag, utils.ETag(employee.Version))
+		ctx.JSON(http.StatusOK, employee)
+	}
+}
+
+func (service *EmployeeService) restoreEmployee(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "restoreEmployee")()
+
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return models.Employee{}, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for employee restore")
+	return service.repo.RestoreEmployee(ctx, employeeId)
+}
+
+// Permanently removes the employees soft deleted for longer than the retention period.
+// ?older_than_days= overrides the configured period.
+func PurgeEmployees() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		_ = middleware.GetTransactionID(ctx)
+		utils.Log(ctx).Info("received request for employee purge")
+
+		days := config.GetConfig().Retention.DeletedEmployeeDays
+		if value := ctx.Query("older_than_days"); value != "" {
+			var err error
+			days, err = strconv.Atoi(value)
+			if err != nil || days < 1 {
+				utils.RespondWithError(ctx, http.StatusBadRequest, "older_than_days must be a positive number")
+				return
+			}
+		}
+		if days < 1 {
+			utils.RespondWithError(ctx, http.StatusBadRequest, "no retention period is configured, pass older_than_days")
+			return
+		}
+
+		purged, err := employeeClient.purgeEmployees(ctx, days)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		ctx.JSON(http.StatusOK, map[string]int64{
+			"purged": purged,
+		})
+	}
+}
+
+// purgeEmployees purges the employees of the tenant of the request only, the background job
+// purges every tenant
+func (service *EmployeeService) purgeEmployees(ctx *gin.Context, days int) (int64, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "purgeEmployees")()
+
+	if err := authorize(ctx, policy.PurgeEmployees); err != nil {
+		return 0, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for employee purge")
+	return service.repo.PurgeTenantEmployees(ctx, retentionCutoff(days))
+}
+
+// retentionCutoff is the deletion time before which soft-deleted employees are purged
+func retentionCutoff(days int) time.Time {
+	return time.Now().AddDate(0, 0, -days)
+}
+
+// RunEmployeePurge purges the employees past the configured retention period at the configured
+// interval until ctx is done. It returns straight away when no retention period is configured.
+func RunEmployeePurge(ctx context.Context) {
+	retention := config.GetConfig().Retention
+	if retention.DeletedEmployeeDays < 1 || retention.PurgeIntervalMinutes < 1 {
+		utils.Logger.Info("retention purge of deleted employees is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(retention.PurgeIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+	for {
+		// Each run is audited as a transaction of its own, made by the system
+		purged, err := employeeClient.repo.PurgeEmployees(ctx, retentionCutoff(retention.DeletedEmployeeDays), constants.SystemActor, uuid.New().String())
+		if err != nil {
+			utils.Logger.Error("retention purge of deleted employees failed", zap.Error(err))
+		} else {
+			utils.Logger.Info("retention purge removed deleted employees", zap.Int64("purged", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -6,13 +6,15 @@ import (
 	employeeerror "assignment/internal/errors"
 	"assignment/internal/middleware"
 	"assignment/internal/models"
+	"assignment/internal/policy"
+	"assignment/internal/tracing"
 	"assignment/internal/utils"
 	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
 )
 
 var (
@@ -34,16 +36,18 @@ func NewEmployeeService(conn db.EmployeeDBService) *EmployeeService {
 func CreateEmployee() func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
 
-		txid := ctx.Request.Header.Get(constants.TransactionID)
-		utils.Logger.Info(fmt.Sprintf("received request for employee creation, txid : %v", txid))
+		utils.Log(ctx).Info("received request for employee creation")
 		var employee models.Employee
 		if err := ctx.ShouldBindBodyWith(&employee, binding.JSON); err == nil {
-			utils.Logger.Info(fmt.Sprintf("user request for employee creation is unmarshalled successfully, txid : %v", txid))
+			utils.Log(ctx).Info("user request for employee creation is unmarshalled successfully")
 			employeeID, err := employeeClient.createEmployee(ctx, employee)
 			if err != nil {
 				utils.RespondWithError(ctx, err.Code, err.Message)
 				return
 			}
+			utils.AddLogFields(ctx, zap.String("employee_id", employeeID))
+			// New employees start at version 1
+			ctx.Header(constants.ETag, utils.ETag(1))
 			ctx.JSON(http.StatusOK, map[string]string{
 				"employee_id": employeeID,
 			})
@@ -56,9 +60,12 @@ func CreateEmployee() func(ctx *gin.Context) {
 }
 
 func (service *EmployeeService) createEmployee(ctx *gin.Context, employee models.Employee) (string, *employeeerror.EmployeeError) {
-	txid := ctx.Request.Header.Get(constants.TransactionID)
+	defer tracing.Trace(ctx, "createEmployee")()
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return "", err
+	}
 
-	utils.Logger.Info(fmt.Sprintf("calling db layer for employee creation, txid : %v", txid))
+	utils.Log(ctx).Info("calling db layer for employee creation")
 	employeeID, err := service.repo.CreateEmployee(ctx, employee)
 	if err != nil {
 		return "", err
@@ -69,7 +76,6 @@ func (service *EmployeeService) createEmployee(ctx *gin.Context, employee models
 // Deletes an employee from the database or store by ID
 func DeleteEmployee() func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
-		txid := ctx.Request.Header.Get(constants.TransactionID)
 		employeeId := ctx.Param("id")
 		err := employeeClient.deleteEmployee(ctx, employeeId)
 		if err != nil {
@@ -77,116 +83,212 @@ func DeleteEmployee() func(ctx *gin.Context) {
 			return
 		}
 
-		utils.Logger.Info(fmt.Sprintf("user has successfully deleted a note, txid : %v", txid))
+		utils.Log(ctx).Info("employee soft-deleted successfully")
 		ctx.Writer.WriteHeader(http.StatusOK)
 	}
 }
 
 func (service *EmployeeService) deleteEmployee(ctx *gin.Context, employeeId string) *employeeerror.EmployeeError {
-	txid := ctx.Request.Header.Get(constants.TransactionID)
+	defer tracing.Trace(ctx, "deleteEmployee")()
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return err
+	}
 
-	utils.Logger.Info(fmt.Sprintf("calling db layer for to check if employee Id exists, txid : %v", txid))
-	_, err := service.repo.GetEmployeeByID(ctx, employeeId)
+	utils.Log(ctx).Info("calling db layer for to check if employee Id exists")
+	employee, err := service.repo.GetEmployeeByID(ctx, employeeId)
 	if err != nil {
 		return err
 	}
+	if err := checkIfMatch(ctx, employee.Version); err != nil {
+		return err
+	}
 
-	utils.Logger.Info(fmt.Sprintf("calling db layer for employee deletion, txid : %v", txid))
-	err = service.repo.DeleteEmployee(ctx, employeeId)
+	utils.Log(ctx).Info("calling db layer for employee deletion")
+	err = service.repo.DeleteEmployee(ctx, employeeId, employee.Version)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// checkIfMatch requires an If-Match header listing the ETag of the current version, so that
+// a write based on a stale read is refused instead of silently overwriting the newer data
+func checkIfMatch(ctx *gin.Context, version int) *employeeerror.EmployeeError {
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+
+	ifMatch := ctx.Request.Header.Get(constants.IfMatch)
+	if ifMatch == "" {
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusPreconditionRequired,
+			Message: "If-Match header is required, send the ETag returned when the employee was fetched",
+			Trace:   txid,
+		}
+	}
+	if !utils.ETagMatches(ifMatch, version, false) {
+		return &employeeerror.EmployeeError{
+			Code:    http.StatusPreconditionFailed,
+			Message: "Employee was modified by someone else, fetch it again to get its current ETag",
+			Trace:   txid,
+		}
+	}
+	return nil
+}
+
 // Retrieves an employee from the database or store by ID
 func GetEmployeeByID() func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
-		txid := ctx.Request.Header.Get(constants.TransactionID)
 		employeeId := ctx.Param("id")
-		utils.Logger.Info(fmt.Sprintf("calling service layer for to get the employee details, txid : %v", txid))
+		utils.Log(ctx).Info("calling service layer for to get the employee details")
 		employeeDetails, err := employeeClient.getEmployeeByID(ctx, employeeId)
 		if err != nil {
 			utils.RespondWithError(ctx, err.Code, err.Message)
 			return
 		}
-		ctx.JSON(http.StatusOK, map[string]string{
+		ctx.Header(constants.ETag, utils.ETag(employeeDetails.Version))
+		if ifNoneMatch := ctx.Request.Header.Get(constants.IfNoneMatch); ifNoneMatch != "" && utils.ETagMatches(ifNoneMatch, employeeDetails.Version, true) {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+		response := map[string]interface{}{
 			"employee_id":   fmt.Sprintf("%v", employeeDetails.ID),
 			"employee_name": employeeDetails.Name,
 			"position":      employeeDetails.Position,
-		})
+		}
+		// The salary is left out for callers who may not see it
+		if employeeDetails.Salary != nil {
+			response["salary"] = employeeDetails.Salary
+		}
+		if employeeDetails.DepartmentID != nil {
+			response["department_id"] = *employeeDetails.DepartmentID
+		}
+		if employeeDetails.ManagerID != nil {
+			response["manager_id"] = *employeeDetails.ManagerID
+		}
+		ctx.JSON(http.StatusOK, response)
 	}
 }
 
 func (service *EmployeeService) getEmployeeByID(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
-	txid := ctx.Request.Header.Get(constants.TransactionID)
+	defer tracing.Trace(ctx, "getEmployeeByID")()
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return models.Employee{}, err
+	}
 
-	utils.Logger.Info(fmt.Sprintf("calling db layer for to get employee details, txid : %v", txid))
+	utils.Log(ctx).Info("calling db layer for to get employee details")
 
 	employeeDetails, err := service.repo.GetEmployeeByID(ctx, employeeId)
 	if err != nil {
 		return models.Employee{}, err
 	}
+	if err := service.redactSalaries(ctx, &employeeDetails); err != nil {
+		return models.Employee{}, err
+	}
 	return employeeDetails, nil
 }
 
-// Updates the details of an existing employee
-func UpdateEmployee() func(ctx *gin.Context) {
+// Replaces every field of an existing employee, the ID is taken from the path.
+// Optional fields left out of the body, such as manager_id, are cleared.
+func ReplaceEmployee() func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
-		txid := ctx.Request.Header.Get(constants.TransactionID)
-
-		utils.Logger.Info(fmt.Sprintf("received request for updating employee details, txid : %v", txid))
+		utils.Log(ctx).Info("received request for replacing employee details")
+		// The body has already been validated by ValidateReplaceEmployeeRequest
 		var employee models.Employee
-		if err := ctx.ShouldBindBodyWith(&employee, binding.JSON); err == nil {
-			utils.Logger.Info(fmt.Sprintf("user request for employee updation is unmarshalled successfully, txid : %v", txid))
-			employeeDetails, err := employeeClient.updateEmployee(ctx, employee)
-			if err != nil {
-				utils.RespondWithError(ctx, err.Code, err.Message)
-				return
-			}
-			ctx.JSON(http.StatusOK, map[string]string{
-				"employee_id":   fmt.Sprintf("%v", employeeDetails.ID),
-				"employee_name": employeeDetails.Name,
-				"position":      employeeDetails.Position,
-			})
-			ctx.Writer.WriteHeader(http.StatusOK)
+		_ = ctx.ShouldBindBodyWith(&employee, binding.JSON)
+		employee.ID = ctx.Param("id")
 
-		} else {
-			ctx.JSON(http.StatusBadRequest, gin.H{"Unable to marshal the request body": err.Error()})
+		employeeDetails, err := employeeClient.replaceEmployee(ctx, employee)
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
 		}
+		respondWithUpdatedEmployee(ctx, employeeDetails)
 	}
 }
 
-func (service *EmployeeService) updateEmployee(ctx *gin.Context, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
-	txid := ctx.Request.Header.Get(constants.TransactionID)
+func (service *EmployeeService) replaceEmployee(ctx *gin.Context, employee models.Employee) (models.Employee, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "replaceEmployee")()
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return models.Employee{}, err
+	}
 
-	utils.Logger.Info(fmt.Sprintf("calling db layer for to check if employee exists, txid : %v", txid))
-	_, err := service.repo.GetEmployeeByID(ctx, employee.ID)
+	utils.Log(ctx).Info("calling db layer for to check if employee exists")
+	current, err := service.repo.GetEmployeeByID(ctx, employee.ID)
 	if err != nil {
 		return models.Employee{}, err
 	}
+	if err := checkIfMatch(ctx, current.Version); err != nil {
+		return models.Employee{}, err
+	}
+
+	// The update only applies to the version the client has seen
+	employee.Version = current.Version
+	return service.repo.ReplaceEmployee(ctx, employee)
+}
+
+// Changes some fields of an existing employee with a JSON Merge Patch (RFC 7396) or a
+// JSON Patch (RFC 6902), depending on the content type. A field set to null is cleared.
+func PatchEmployee() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		utils.Log(ctx).Info("received request for patching employee details")
+		employeeDetails, err := employeeClient.patchEmployee(ctx, ctx.Param("id"))
+		if err != nil {
+			utils.RespondWithError(ctx, err.Code, err.Message)
+			return
+		}
+		respondWithUpdatedEmployee(ctx, employeeDetails)
+	}
+}
+
+func (service *EmployeeService) patchEmployee(ctx *gin.Context, employeeId string) (models.Employee, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "patchEmployee")()
+	txid := ctx.Request.Header.Get(constants.TransactionID)
+	if err := authorize(ctx, policy.WriteEmployees); err != nil {
+		return models.Employee{}, err
+	}
 
-	employeeDetails, err := service.repo.UpdateEmployee(ctx, employee)
+	utils.Log(ctx).Info("calling db layer for to get the employee to patch")
+	current, err := service.repo.GetEmployeeByID(ctx, employeeId)
 	if err != nil {
 		return models.Employee{}, err
 	}
-	return employeeDetails, nil
+	if err := checkIfMatch(ctx, current.Version); err != nil {
+		return models.Employee{}, err
+	}
+
+	// The patch applies to the employee as read, which is then written back whole, so the
+	// write only succeeds if nobody changed the employee in between
+	patched, patchErr := middleware.ApplyEmployeePatch(ctx, current)
+	if patchErr != nil {
+		return models.Employee{}, &employeeerror.EmployeeError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: patchErr.Error(),
+			Trace:   txid,
+		}
+	}
+	return service.repo.ReplaceEmployee(ctx, patched)
+}
+
+// respondWithUpdatedEmployee answers a successful update with the new ETag of the employee
+func respondWithUpdatedEmployee(ctx *gin.Context, employeeDetails models.Employee) {
+	ctx.Header(constants.ETag, utils.ETag(employeeDetails.Version))
+	ctx.JSON(http.StatusOK, map[string]string{
+		"employee_id":   fmt.Sprintf("%v", employeeDetails.ID),
+		"employee_name": employeeDetails.Name,
+		"position":      employeeDetails.Position,
+	})
 }
 
 // List Employee
 func ListEmployees() func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
 		_ = middleware.GetTransactionID(ctx)
-		txid := ctx.Request.Header.Get(constants.TransactionID)
-
-		utils.Logger.Info(fmt.Sprintf("received request for list employees details, txid : %v", txid))
 
-		// Get page and pagesize from the query parameters
-		page, _ := strconv.Atoi(ctx.Query("page"))
+		utils.Log(ctx).Info("received request for list employees details")
 
-		pagesize, _ := strconv.Atoi(ctx.Query("pagesize"))
+		// The query has already been validated by ValidateListEmployeesRequest
+		query, _ := middleware.ParseEmployeeQuery(ctx)
 
-		employeeDetails, err := employeeClient.listEmployees(ctx, page, pagesize)
+		employeeDetails, err := employeeClient.listEmployees(ctx, query)
 		if err != nil {
 			utils.RespondWithError(ctx, err.Code, err.Message)
 			return
@@ -196,13 +298,26 @@ func ListEmployees() func(ctx *gin.Context) {
 	}
 }
 
-func (service *EmployeeService) listEmployees(ctx *gin.Context, page, pagesize int) ([]models.Employee, *employeeerror.EmployeeError) {
-	txid := ctx.Request.Header.Get(constants.TransactionID)
+func (service *EmployeeService) listEmployees(ctx *gin.Context, query models.EmployeeQuery) (models.EmployeeList, *employeeerror.EmployeeError) {
+	defer tracing.Trace(ctx, "listEmployees")()
 
-	utils.Logger.Info(fmt.Sprintf("calling db layer for to check if employee exists, txid : %v", txid))
-	employeeDetails, err := service.repo.ListEmployee(ctx, page, pagesize)
+	if err := authorize(ctx, policy.ReadEmployees); err != nil {
+		return models.EmployeeList{}, err
+	}
+	if err := checkSalaryQuery(ctx, query); err != nil {
+		return models.EmployeeList{}, err
+	}
+	if err := checkDeletedQuery(ctx, query); err != nil {
+		return models.EmployeeList{}, err
+	}
+
+	utils.Log(ctx).Info("calling db layer for to check if employee exists")
+	employeeDetails, err := service.repo.ListEmployee(ctx, query)
 	if err != nil {
-		return []models.Employee{}, err
+		return models.EmployeeList{}, err
+	}
+	if err := service.redactEmployeeList(ctx, employeeDetails.Employees); err != nil {
+		return models.EmployeeList{}, err
 	}
 
 	return employeeDetails, nil
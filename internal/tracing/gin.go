@@ -0,0 +1,28 @@
+package tracing
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace starts a child span of the current span of the request, which becomes the parent of the
+// spans started until the returned func ends it, e.g.
+//
+//	defer tracing.Trace(ctx, "createEmployee")()
+//
+// A request without a span, when tracing is disabled, is not traced. The outcome of the request
+// is recorded on its server span.
+func Trace(ctx *gin.Context, name string, attributes ...attribute.KeyValue) func() {
+	parent := ctx.Request.Context()
+	if !trace.SpanContextFromContext(parent).IsValid() {
+		return func() {}
+	}
+
+	traced, span := Tracer().Start(parent, name, trace.WithAttributes(attributes...))
+	ctx.Request = ctx.Request.WithContext(traced)
+	return func() {
+		span.End()
+		ctx.Request = ctx.Request.WithContext(parent)
+	}
+}
@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	sqlStringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumericLiteral = regexp.MustCompile(`([^\w$.])-?\d+(?:\.\d+)?\b`)
+	sqlWhitespace     = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeSQL returns the query text recorded on the span of a statement: its literals are
+// replaced with ?, so that no value written inline reaches the traces, and its whitespace is
+// collapsed. Bind parameters such as $1 are kept, their values are never recorded.
+func SanitizeSQL(query string) string {
+	query = sqlStringLiteral.ReplaceAllString(query, "?")
+	query = sqlWhitespace.ReplaceAllString(query, " ")
+	query = sqlNumericLiteral.ReplaceAllString(query, "${1}?")
+	return strings.TrimSpace(query)
+}
+
+// SQLOperation returns the first keyword of a statement, e.g. SELECT, which names its span
+func SQLOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/utils"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+
+	// scope of the spans, as the instrumentation library in OTLP
+	scopeName = "assignment"
+	// service.name of the spans when tracing.service_name is not set
+	defaultServiceName = "employee-database"
+)
+
+var (
+	mu       sync.RWMutex
+	provider *sdktrace.TracerProvider
+	// file the file exporter writes to, closed once the provider is shut down
+	output io.Closer
+)
+
+// Enabled reports whether spans are recorded, which Init decides from the configuration
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return provider != nil
+}
+
+// Tracer returns the tracer the spans of the application are started with, which records
+// nothing until Init installs the tracer provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(scopeName)
+}
+
+// Init starts exporting the sampled spans with the exporter of the configuration, in batches in
+// the background, and installs the W3C Trace Context propagator. Nothing is recorded when tracing
+// is disabled.
+func Init(cfg config.Tracing) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	if cfg.Exporter == "" {
+		cfg.Exporter = ExporterOTLP
+	}
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	var err error
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		endpoint := strings.TrimSuffix(cfg.OTLPEndpoint, "/")
+		if endpoint == "" {
+			return fmt.Errorf("tracing.otlp_endpoint is required by the %s exporter", ExporterOTLP)
+		}
+		exporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"))
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		if cfg.File == "" {
+			return fmt.Errorf("tracing.file is required by the %s exporter", ExporterFile)
+		}
+		file, openErr := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if openErr != nil {
+			return openErr
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return fmt.Errorf("unknown tracing exporter %q, expected %s, %s or %s", cfg.Exporter, ExporterOTLP, ExporterStdout, ExporterFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		// A caller's traceparent decides for the traces it starts
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(clampRatio(cfg.SampleRatio)))),
+		sdktrace.WithIDGenerator(idGenerator{}),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	mu.Lock()
+	provider, output = tp, closer
+	mu.Unlock()
+	utils.Logger.Info("Exporting traces", zap.String("exporter", cfg.Exporter))
+	return nil
+}
+
+// Shutdown exports the spans still queued and stops recording new ones
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	tp, closer := provider, output
+	provider, output = nil, nil
+	mu.Unlock()
+	if tp == nil {
+		return nil
+	}
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	if err := tp.Shutdown(ctx); err != nil {
+		return err
+	}
+	if closer != nil {
+		return closer.Close()
+	}
+	return nil
+}
+
+type traceIDKey struct{}
+
+// ContextWithTraceID returns a context whose first span, when it has no parent, starts a trace
+// with the given ID rather than a random one
+func ContextWithTraceID(ctx context.Context, traceID trace.TraceID) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// idGenerator generates random IDs, but for the trace of a context given one by ContextWithTraceID
+type idGenerator struct{}
+
+func (idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	traceID, _ := ctx.Value(traceIDKey{}).(trace.TraceID)
+	for !traceID.IsValid() {
+		_, _ = rand.Read(traceID[:])
+	}
+	return traceID, idGenerator{}.NewSpanID(ctx, traceID)
+}
+
+func (idGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	var spanID trace.SpanID
+	for !spanID.IsValid() {
+		_, _ = rand.Read(spanID[:])
+	}
+	return spanID
+}
+
+// clampRatio keeps a sample ratio within [0, 1]
+func clampRatio(ratio float64) float64 {
+	return math.Max(0, math.Min(1, ratio))
+}
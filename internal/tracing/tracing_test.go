@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"assignment/internal/config"
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exportedSpan is the part of a span written by the file exporter that the tests look at
+type exportedSpan struct {
+	Name        string
+	SpanContext struct{ TraceID, SpanID string }
+	Parent      struct{ SpanID string }
+	SpanKind    int
+	Attributes  []struct {
+		Key   string
+		Value struct{ Value any }
+	}
+	Status   struct{ Code string }
+	Resource []struct {
+		Key   string
+		Value struct{ Value any }
+	}
+}
+
+// readSpans returns the spans written by the file exporter, one per line
+func readSpans(t *testing.T, path string) []exportedSpan {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var spans []exportedSpan
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span exportedSpan
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// remoteContext returns a context holding the span of a caller's traceparent header
+func remoteContext(traceparent string) context.Context {
+	return propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": traceparent})
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	assert.Equal(t, `SELECT id FROM employees WHERE tenant_id=$1 AND name = ? AND salary > ? LIMIT ?`,
+		SanitizeSQL("SELECT id FROM employees\n\t\tWHERE tenant_id=$1 AND name = 'O''Brien' AND salary > 1000.50 LIMIT 10"))
+	assert.Equal(t, "INSERT", SQLOperation("  insert INTO employees VALUES ($1)"))
+}
+
+func TestSpans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	assert.NoError(t, Init(config.Tracing{Enabled: true, Exporter: ExporterFile, File: path, SampleRatio: 1, ServiceName: "test"}))
+	assert.True(t, Enabled())
+
+	ctx, server := Tracer().Start(remoteContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"),
+		"GET /v1/employees", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := Tracer().Start(ctx, "listEmployees", trace.WithAttributes(attribute.String("transaction.id", "txid"), attribute.Int("rows", 2)))
+	child.SetStatus(codes.Error, "failed")
+	child.End()
+	server.End()
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", child.SpanContext().TraceID().String())
+	assert.NoError(t, Shutdown(context.Background()))
+	assert.False(t, Enabled())
+
+	// The spans are exported once ended, and once only
+	spans := readSpans(t, path)
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "listEmployees", spans[0].Name)
+	assert.Equal(t, server.SpanContext().SpanID().String(), spans[0].Parent.SpanID)
+	assert.Equal(t, "Error", spans[0].Status.Code)
+	assert.Equal(t, "rows", spans[0].Attributes[1].Key)
+	assert.Equal(t, 2.0, spans[0].Attributes[1].Value.Value)
+	assert.Equal(t, "test", spans[0].Resource[0].Value.Value)
+	assert.Equal(t, "GET /v1/employees", spans[1].Name)
+	assert.Equal(t, "00f067aa0ba902b7", spans[1].Parent.SpanID)
+	assert.Equal(t, int(trace.SpanKindServer), spans[1].SpanKind)
+
+	// Once shut down, nothing is recorded
+	_, span := Tracer().Start(context.Background(), "job")
+	assert.False(t, span.IsRecording())
+}
+
+func TestSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	assert.NoError(t, Init(config.Tracing{Enabled: true, Exporter: ExporterFile, File: path, SampleRatio: 0}))
+	defer Shutdown(context.Background())
+
+	// A new trace is dropped, a caller's sampled trace is kept
+	_, span := Tracer().Start(context.Background(), "job")
+	assert.False(t, span.SpanContext().IsSampled())
+
+	_, span = Tracer().Start(remoteContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), "request")
+	assert.True(t, span.SpanContext().IsSampled())
+
+	// A trace may be started under a given ID, which the ratio samples like any other
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	_, span = Tracer().Start(ContextWithTraceID(context.Background(), traceID), "request")
+	assert.Equal(t, traceID, span.SpanContext().TraceID())
+	assert.False(t, span.SpanContext().IsSampled())
+
+	// The W3C Trace Context propagator is installed
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag returns the entity tag of a record at the given version
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ETagMatches reports whether an If-Match or If-None-Match header value lists the entity tag
+// of the given version, or is *. If-Match compares strongly, so a weak W/ tag never matches it,
+// while If-None-Match compares weakly and ignores the W/ prefix.
+func ETagMatches(header string, version int, weak bool) bool {
+	etag := ETag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
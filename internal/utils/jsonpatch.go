@@ -0,0 +1,345 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Documents handled here are decoded with DecodeJSON, so objects are map[string]interface{},
+// arrays are []interface{} and numbers are json.Number, which keeps decimal amounts exact.
+
+// DecodeJSON decodes a JSON value, keeping numbers as json.Number
+func DecodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to a document. A null member of the patch
+// removes the member of the document, an object is merged recursively and any other value
+// replaces the member.
+func MergePatch(document interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	target, ok := document.(map[string]interface{})
+	if !ok {
+		target = map[string]interface{}{}
+	} else {
+		merged := make(map[string]interface{}, len(target))
+		for name, value := range target {
+			merged[name] = value
+		}
+		target = merged
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(target, name)
+			continue
+		}
+		target[name] = MergePatch(target[name], value)
+	}
+	return target
+}
+
+// JSONPatchOperation is one operation of a JSON Patch (RFC 6902)
+type JSONPatchOperation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+	// Value is empty when the operation has no value, and "null" for a null value
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ParseJSONPatch reads a JSON Patch document and checks that each of its operations is complete
+func ParseJSONPatch(data []byte) ([]JSONPatchOperation, error) {
+	var operations []JSONPatchOperation
+	if err := json.Unmarshal(data, &operations); err != nil {
+		return nil, errors.New("a JSON Patch must be an array of operations")
+	}
+	for i, operation := range operations {
+		switch operation.Op {
+		case "add", "replace", "test":
+			if len(operation.Value) == 0 {
+				return nil, fmt.Errorf("operation %d (%s) is missing its value", i, operation.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(operation.From); err != nil {
+				return nil, fmt.Errorf("operation %d (%s) has an invalid from, %v", i, operation.Op, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %d has an unknown op %q", i, operation.Op)
+		}
+		if _, err := parsePointer(operation.Path); err != nil {
+			return nil, fmt.Errorf("operation %d (%s) has an invalid path, %v", i, operation.Op, err)
+		}
+	}
+	return operations, nil
+}
+
+// ApplyJSONPatch applies the operations of a JSON Patch (RFC 6902) in order and returns the
+// patched document. The patch is all or nothing, the document is left unchanged on error.
+func ApplyJSONPatch(document interface{}, operations []JSONPatchOperation) (interface{}, error) {
+	document = copyJSON(document)
+	for i, operation := range operations {
+		var err error
+		document, err = applyOperation(document, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s) failed, %v", i, operation.Op, operation.Path, err)
+		}
+	}
+	return document, nil
+}
+
+func applyOperation(document interface{}, operation JSONPatchOperation) (interface{}, error) {
+	path, _ := parsePointer(operation.Path)
+	var value interface{}
+	if len(operation.Value) > 0 {
+		var err error
+		if value, err = DecodeJSON(operation.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		return addValue(document, path, value)
+	case "remove":
+		document, _, err := removeValue(document, path)
+		return document, err
+	case "replace":
+		document, _, err := removeValue(document, path)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, value)
+	case "move":
+		from, _ := parsePointer(operation.From)
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, errors.New("a value cannot be moved into itself")
+		}
+		document, moved, err := removeValue(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, moved)
+	case "copy":
+		from, _ := parsePointer(operation.From)
+		copied, err := getValue(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, copyJSON(copied))
+	case "test":
+		current, err := getValue(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalJSON(current, value) {
+			return nil, errors.New("the value differs")
+		}
+		return document, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", operation.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q does not start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func getValue(document interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := document.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			document = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			document = container[index]
+		default:
+			return nil, fmt.Errorf("%q is not inside an object or array", token)
+		}
+	}
+	return document, nil
+}
+
+// addValue sets the member named by path, or inserts into an array, and returns the document.
+// An empty path replaces the whole document.
+func addValue(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := getValue(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return document, nil
+	case []interface{}:
+		index := len(container)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(container)); err != nil {
+				return nil, err
+			}
+		}
+		container = append(container, nil)
+		copy(container[index+1:], container[index:])
+		container[index] = value
+		return setValue(document, path[:len(path)-1], container)
+	}
+	return nil, fmt.Errorf("%q is not inside an object or array", token)
+}
+
+// removeValue removes the member named by path and returns the document and the removed value
+func removeValue(document interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, document, nil
+	}
+	parent, err := getValue(document, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		removed, ok := container[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q does not exist", token)
+		}
+		delete(container, token)
+		return document, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		removed := container[index]
+		container = append(container[:index:index], container[index+1:]...)
+		document, err = setValue(document, path[:len(path)-1], container)
+		return document, removed, err
+	}
+	return nil, nil, fmt.Errorf("%q is not inside an object or array", token)
+}
+
+// setValue replaces the value at path, used for arrays whose slice header changes
+func setValue(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := getValue(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+	case []interface{}:
+		index, _ := arrayIndex(token, len(container)-1)
+		container[index] = value
+	}
+	return document, nil
+}
+
+// arrayIndex reads an array index token, which must not be greater than max
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("index %d is out of range", index)
+	}
+	return index, nil
+}
+
+func copyJSON(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for name, member := range value {
+			copied[name] = copyJSON(member)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, element := range value {
+			copied[i] = copyJSON(element)
+		}
+		return copied
+	}
+	return value
+}
+
+// equalJSON compares two JSON values, numbers by their value so that 1.50 equals 1.5
+func equalJSON(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okX := new(big.Rat).SetString(a.String())
+		y, okY := new(big.Rat).SetString(b.String())
+		return okX && okY && x.Cmp(y) == 0
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, member := range a {
+			other, ok := b[name]
+			if !ok || !equalJSON(member, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, data string) interface{} {
+	value, err := DecodeJSON([]byte(data))
+	assert.NoError(t, err)
+	return value
+}
+
+func encode(t *testing.T, value interface{}) string {
+	data, err := json.Marshal(value)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestMergePatch(t *testing.T) {
+	// The example of RFC 7396, section 3
+	document := decode(t, `{"title": "Goodbye!", "author": {"givenName": "John", "familyName": "Doe"}, "tags": ["example", "sample"], "content": "This will be unchanged"}`)
+	patch := decode(t, `{"title": "Hello!", "phoneNumber": "+01-123-456-7890", "author": {"familyName": null}, "tags": ["example"]}`)
+
+	patched := MergePatch(document, patch)
+	assert.JSONEq(t, `{"title": "Hello!", "author": {"givenName": "John"}, "tags": ["example"], "content": "This will be unchanged", "phoneNumber": "+01-123-456-7890"}`, encode(t, patched))
+	// The document itself is left alone
+	assert.JSONEq(t, `{"givenName": "John", "familyName": "Doe"}`, encode(t, document.(map[string]interface{})["author"]))
+
+	// Numbers keep their exact text
+	assert.Equal(t, `{"amount":1234.50}`, encode(t, MergePatch(decode(t, `{"amount": 1}`), decode(t, `{"amount": 1234.50}`))))
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	document := decode(t, `{"name": "John", "salary": {"amount": 100.50, "currency": "USD"}, "tags": ["a", "c"], "manager_id": "1"}`)
+
+	operations, err := ParseJSONPatch([]byte(`[
+		{"op": "test", "path": "/salary/amount", "value": 100.5},
+		{"op": "replace", "path": "/salary/amount", "value": 200},
+		{"op": "replace", "path": "/manager_id", "value": null},
+		{"op": "add", "path": "/tags/1", "value": "b"},
+		{"op": "add", "path": "/tags/-", "value": "d"},
+		{"op": "copy", "from": "/name", "path": "/alias"},
+		{"op": "move", "from": "/alias", "path": "/a~1b"},
+		{"op": "remove", "path": "/tags/0"}
+	]`))
+	assert.NoError(t, err)
+	patched, err := ApplyJSONPatch(document, operations)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "John", "salary": {"amount": 200, "currency": "USD"}, "tags": ["b", "c", "d"], "manager_id": null, "a/b": "John"}`, encode(t, patched))
+
+	// A failing operation leaves the document as it was
+	operations, _ = ParseJSONPatch([]byte(`[{"op": "remove", "path": "/name"}, {"op": "test", "path": "/manager_id", "value": "2"}]`))
+	_, err = ApplyJSONPatch(document, operations)
+	assert.EqualError(t, err, `operation 1 (test /manager_id) failed, the value differs`)
+	assert.Equal(t, "John", document.(map[string]interface{})["name"])
+
+	for _, patch := range []string{
+		`{"op": "remove", "path": "/name"}`,
+		`[{"op": "add", "path": "/name"}]`,
+		`[{"op": "rename", "path": "/name"}]`,
+		`[{"op": "remove", "path": "name"}]`,
+		`[{"op": "move", "from": "name", "path": "/other"}]`,
+	} {
+		_, err := ParseJSONPatch([]byte(patch))
+		assert.Error(t, err, patch)
+	}
+
+	for _, patch := range []string{
+		`[{"op": "replace", "path": "/missing", "value": 1}]`,
+		`[{"op": "add", "path": "/tags/5", "value": "x"}]`,
+		`[{"op": "remove", "path": "/tags/01"}]`,
+		`[{"op": "move", "from": "/salary", "path": "/salary/amount"}]`,
+	} {
+		operations, err := ParseJSONPatch([]byte(patch))
+		assert.NoError(t, err, patch)
+		_, err = ApplyJSONPatch(document, operations)
+		assert.Error(t, err, patch)
+	}
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"assignment/internal/config"
+	"assignment/internal/constants"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger is the logger of the application, Log returns the logger of a request. It discards
+// every entry until InitLogClient or InitLogger is called.
+var Logger = zap.NewNop()
+
+// Redacted replaces the value of a redacted field
+const Redacted = "[REDACTED]"
+
+// defaultRedactFields are masked when logging.redact_fields is not set
+var defaultRedactFields = []string{"name", "first_name", "last_name", "salary"}
+
+// InitLogClient installs a development logger, until InitLogger configures the logger of the
+// application
+func InitLogClient() {
+	logger, _ := zap.NewDevelopment(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newRedactingCore(core, defaultRedactFields)
+	}))
+	Logger = logger
+}
+
+// InitLogger installs the logger of the logging configuration, which masks the redacted fields
+func InitLogger(cfg config.Logging) error {
+	level := zapcore.InfoLevel
+	if cfg.Level != "" {
+		var err error
+		if level, err = zapcore.ParseLevel(cfg.Level); err != nil {
+			return fmt.Errorf("invalid logging.level %q", cfg.Level)
+		}
+	}
+
+	zapConfig := zap.NewProductionConfig()
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	// Every request is logged, none is sampled away
+	zapConfig.Sampling = nil
+	switch cfg.Encoding {
+	case "json", "":
+		zapConfig.Encoding = "json"
+		zapConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	case "console":
+		zapConfig.Encoding = "console"
+		zapConfig.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	default:
+		return fmt.Errorf("invalid logging.encoding %q, expected json or console", cfg.Encoding)
+	}
+
+	redactFields := cfg.RedactFields
+	if redactFields == nil {
+		redactFields = defaultRedactFields
+	}
+	logger, err := zapConfig.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newRedactingCore(core, redactFields)
+	}))
+	if err != nil {
+		return err
+	}
+	Logger = logger
+	return nil
+}
+
+// Log returns the logger of the request of ctx, which adds the transaction-id, the route, the
+// actor and the employee of the request to every entry, and Logger outside of a request
+func Log(ctx context.Context) *zap.Logger {
+	if c, ok := ctx.(*gin.Context); ok {
+		if logger, ok := c.Get(constants.LoggerKey); ok {
+			return logger.(*zap.Logger)
+		}
+		if c.Request != nil {
+			return Logger.With(zap.String("txid", c.Request.Header.Get(constants.TransactionID)))
+		}
+	}
+	return Logger
+}
+
+// AddLogFields adds fields to the entries logged for the request from now on
+func AddLogFields(ctx *gin.Context, fields ...zap.Field) {
+	ctx.Set(constants.LoggerKey, Log(ctx).With(fields...))
+}
+
+// redactingCore masks the value of the redacted fields before they are encoded, whether logged
+// on their own or within a record logged with zap.Any, so that names and salaries are never
+// written in cleartext. Messages are written as they are and must not hold such values.
+type redactingCore struct {
+	zapcore.Core
+	fields map[string]bool
+}
+
+func newRedactingCore(core zapcore.Core, fields []string) zapcore.Core {
+	redacted := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		redacted[strings.ToLower(field)] = true
+	}
+	return &redactingCore{Core: core, fields: redacted}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redact(fields)), fields: c.fields}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.redact(fields))
+}
+
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch {
+		case c.fields[strings.ToLower(field.Key)]:
+			redacted[i] = zap.String(field.Key, Redacted)
+		case field.Type == zapcore.ReflectType:
+			redacted[i] = zap.Any(field.Key, c.redactValue(field.Interface))
+		default:
+			redacted[i] = field
+		}
+	}
+	return redacted
+}
+
+// redactValue returns the JSON form of a value logged with zap.Any, the form it is encoded in,
+// with the redacted members masked at any depth
+func (c *redactingCore) redactValue(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return value
+	}
+	return c.mask(decoded)
+}
+
+func (c *redactingCore) mask(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, member := range v {
+			if c.fields[strings.ToLower(key)] {
+				v[key] = Redacted
+			} else {
+				v[key] = c.mask(member)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = c.mask(item)
+		}
+	}
+	return value
+}
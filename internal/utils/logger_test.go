@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"assignment/internal/config"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type loggedEmployee struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Salary  float64         `json:"salary"`
+	Reports []loggedReport  `json:"reports"`
+	Manager *loggedEmployee `json:"manager,omitempty"`
+}
+
+type loggedReport struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// bufferLogger returns a JSON logger writing to a buffer, which masks the default redacted fields
+func bufferLogger() (*zap.Logger, *bytes.Buffer) {
+	buffer := &bytes.Buffer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}), zapcore.AddSync(buffer), zapcore.DebugLevel)
+	return zap.New(newRedactingCore(core, defaultRedactFields)), buffer
+}
+
+func TestRedaction(t *testing.T) {
+	logger, buffer := bufferLogger()
+
+	logger.With(zap.String("Name", "John Doe")).Info("created", zap.Float64("salary", 1000), zap.String("employee_id", "7"))
+	assert.JSONEq(t, `{"msg": "created", "Name": "[REDACTED]", "salary": "[REDACTED]", "employee_id": "7"}`, buffer.String())
+
+	buffer.Reset()
+	logger.Info("updated", zap.Any("employee", loggedEmployee{
+		ID:      "7",
+		Name:    "John Doe",
+		Salary:  1000.5,
+		Reports: []loggedReport{{ID: "8", Name: "Jane Roe"}},
+		Manager: &loggedEmployee{ID: "1", Name: "Mary Major", Salary: 2000},
+	}))
+	assert.JSONEq(t, `{"msg": "updated", "employee": {
+		"id": "7", "name": "[REDACTED]", "salary": "[REDACTED]",
+		"reports": [{"id": "8", "name": "[REDACTED]"}],
+		"manager": {"id": "1", "name": "[REDACTED]", "salary": "[REDACTED]", "reports": null}
+	}}`, buffer.String())
+	assert.NotContains(t, buffer.String(), "Doe")
+}
+
+func TestInitLogger(t *testing.T) {
+	defer func(logger *zap.Logger) { Logger = logger }(Logger)
+
+	assert.NoError(t, InitLogger(config.Logging{Level: "warn", Encoding: "console"}))
+	assert.False(t, Logger.Core().Enabled(zapcore.InfoLevel))
+	assert.True(t, Logger.Core().Enabled(zapcore.WarnLevel))
+
+	assert.EqualError(t, InitLogger(config.Logging{Level: "loud"}), `invalid logging.level "loud"`)
+	assert.EqualError(t, InitLogger(config.Logging{Encoding: "xml"}), `invalid logging.encoding "xml", expected json or console`)
+}
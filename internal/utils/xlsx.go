@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xlsxMaxRows is the number of rows a sheet holds in Excel
+const xlsxMaxRows = 1048576
+
+// XLSXNumber is a cell value written as a number rather than as text, e.g. a decimal amount
+type XLSXNumber string
+
+// XLSXWriter streams rows into an XLSX workbook. Each row goes straight to the zip stream
+// with its text inlined, so memory use does not depend on the number of rows. Rows past the
+// row limit of Excel continue on a new sheet, which starts with the header again.
+type XLSXWriter struct {
+	zip    *zip.Writer
+	sheet  *bufio.Writer
+	header []string
+	sheets int
+	rows   int
+}
+
+// NewXLSXWriter starts a workbook on w whose sheets start with the given header row.
+// Close must be called to complete the workbook.
+func NewXLSXWriter(w io.Writer, header []string) *XLSXWriter {
+	return &XLSXWriter{zip: zip.NewWriter(w), header: header}
+}
+
+// WriteRow appends a row. A cell is written as a number for an XLSXNumber or an integer, as
+// text for a string, and left empty for nil.
+func (x *XLSXWriter) WriteRow(cells ...interface{}) error {
+	if x.sheet == nil || x.rows == xlsxMaxRows {
+		if err := x.startSheet(); err != nil {
+			return err
+		}
+	}
+	return x.writeRow(cells)
+}
+
+func (x *XLSXWriter) writeRow(cells []interface{}) error {
+	x.rows++
+	x.sheet.WriteString("<row>")
+	for _, cell := range cells {
+		switch value := cell.(type) {
+		case nil:
+			x.sheet.WriteString("<c/>")
+		case XLSXNumber:
+			fmt.Fprintf(x.sheet, "<c><v>%s</v></c>", value)
+		case int:
+			fmt.Fprintf(x.sheet, "<c><v>%s</v></c>", strconv.Itoa(value))
+		case int64:
+			fmt.Fprintf(x.sheet, "<c><v>%s</v></c>", strconv.FormatInt(value, 10))
+		case string:
+			x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(x.sheet, []byte(value)); err != nil {
+				return err
+			}
+			x.sheet.WriteString("</t></is></c>")
+		default:
+			return fmt.Errorf("cannot write %T to an XLSX cell", cell)
+		}
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+// startSheet ends the current sheet, if any, and starts the next one with the header row
+func (x *XLSXWriter) startSheet() error {
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+	x.sheets++
+	part, err := x.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", x.sheets))
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(part)
+	x.rows = 0
+	x.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	header := make([]interface{}, len(x.header))
+	for i, name := range x.header {
+		header[i] = name
+	}
+	return x.writeRow(header)
+}
+
+func (x *XLSXWriter) endSheet() error {
+	if x.sheet == nil {
+		return nil
+	}
+	x.sheet.WriteString("</sheetData></worksheet>")
+	err := x.sheet.Flush()
+	x.sheet = nil
+	return err
+}
+
+// Close ends the last sheet and writes the parts of the workbook that list the sheets.
+// It does not close the underlying writer.
+func (x *XLSXWriter) Close() error {
+	// An empty workbook still has a sheet with the header
+	if x.sheets == 0 {
+		if err := x.startSheet(); err != nil {
+			return err
+		}
+	}
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+
+	var overrides, sheets, relationships strings.Builder
+	for i := 1; i <= x.sheets; i++ {
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+		fmt.Fprintf(&sheets, `<sheet name="Sheet%d" sheetId="%d" r:id="rId%d"/>`, i, i, i)
+		fmt.Fprintf(&relationships, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			overrides.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			relationships.String() + `</Relationships>`},
+	}
+	for _, part := range parts {
+		w, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, xml.Header+part.content); err != nil {
+			return err
+		}
+	}
+	return x.zip.Close()
+}
@@ -3,35 +3,148 @@ package main
 import (
 	"assignment/internal/config"
 	"assignment/internal/db"
+	"assignment/internal/middleware"
 	"assignment/internal/server"
 	"assignment/internal/service"
+	"assignment/internal/tracing"
 	"assignment/internal/utils"
-	"log"
+	"context"
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"go.uber.org/zap"
 )
 
+// defaults are the settings each configuration layer overrides, built in so that the binary
+// runs from any directory
+//
+//go:embed config/defaults.toml
+var defaults []byte
+
 func main() {
 
 	// Initializing the Log client
 	utils.InitLogClient()
 
-	// Initializing the GlobalConfig
-	err := config.InitGlobalConfig()
-	if err != nil {
-		log.Fatalf("Unable to initialize global config")
+	// Initializing the GlobalConfig from the defaults, the configuration files, the environment
+	// and the flags, e.g. `go run main.go --config prod.toml --server.address :9090`
+	args, err := config.InitGlobalConfig(defaults, os.Args[1:])
+	var invalid *config.InvalidConfigError
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return
+	case errors.As(err, &invalid):
+		utils.Logger.Fatal("Invalid configuration", zap.Strings("problems", invalid.Problems))
+	case err != nil:
+		utils.Logger.Fatal("Unable to initialize global config", zap.Error(err))
+	}
+
+	// Logging at the level and in the encoding of the configuration
+	if err := utils.InitLogger(config.GetConfig().Logging); err != nil {
+		utils.Logger.Fatal("Unable to initialize logging", zap.Error(err))
+	}
+
+	// Running the schema migrations on demand, e.g. `go run main.go migrate up`
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrations(args[1:]); err != nil {
+			utils.Logger.Fatal("Unable to migrate the database", zap.Error(err))
+		}
+		return
 	}
 
-	// Establishing the connection to DB.
-	postgres, err := db.New()
+	// Loading the keys bearer tokens are verified with
+	if err := middleware.InitAuthentication(); err != nil {
+		utils.Logger.Fatal("Unable to initialize authentication", zap.Error(err))
+	}
+
+	// Exporting the spans of the requests when tracing is enabled
+	if err := tracing.Init(config.GetConfig().Tracing); err != nil {
+		utils.Logger.Fatal("Unable to initialize tracing", zap.Error(err))
+	}
+
+	// Establishing the connection to DB, or the in-memory store when configured.
+	repo, err := db.New()
 	if err != nil {
-		log.Fatal("Unable to connect to DB : ", err)
+		utils.Logger.Fatal("Unable to connect to DB", zap.Error(err))
 	}
 
 	// Initializing the client for employee records service
-	//service.NewEmployeeService(postgres)
-	_ = service.NewEmployeeService(postgres)
+	_ = service.NewEmployeeService(repo)
+	// Services may call the API with an X-API-Key in place of a bearer token
+	middleware.SetAPIKeyAuthenticator(service.AuthenticateAPIKey)
+
+	// Purging the employees deleted for longer than the retention period in the background
+	go service.RunEmployeePurge(context.Background())
+
+	// Applying the scheduled salary changes once they are due in the background
+	go service.RunSalaryScheduler(context.Background())
+
+	// Removing the expired responses of idempotency keys in the background
+	go service.RunIdempotencyKeyPurge(context.Background())
 
 	// Starting the server
 	server.Start()
 }
+
+// runMigrations handles `migrate up`, `migrate down [steps]` and `migrate status`
+func runMigrations(args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migration(s) applied\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migration(s) rolled back\n", rolledBack)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+	return nil
+}